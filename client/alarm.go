@@ -0,0 +1,93 @@
+package storclient
+
+// FailureAlarmOpts is configuration of failure rate alarm (see StorClientOpts.FailureAlarm)
+//
+// failure rate is computed over last Window finished downloads (skipped files aren't counted),
+// alarm is raised when rate exceeds Threshold and cleared when rate drops to ClearThreshold or below
+// (hysteresis - alarm doesn't flap around threshold)
+type FailureAlarmOpts struct {
+	// number of last downloads in sliding window
+	// default (0) is 100
+	Window int
+	// failure rate (0-1) which raise alarm
+	Threshold float64
+	// failure rate (0-1) which clear raised alarm
+	// default (0) is half of Threshold
+	ClearThreshold float64
+	// min number of downloads in window before alarm can be raised
+	// default (0) means full Window
+	MinSamples int
+	// callback called on raise (firing=true) and clear (firing=false) of alarm with current failure rate,
+	// it's called from stats goroutine - long running callback should hand over work
+	OnAlarm FailureAlarmFunc
+}
+
+// FailureAlarmFunc is callback of failure rate alarm
+type FailureAlarmFunc func(firing bool, failureRate float64)
+
+// DefaultFailureAlarmWindow is default size of failure alarm sliding window
+const DefaultFailureAlarmWindow = 100
+
+// failureAlarm is sliding window of download results, it isn't safe for concurrent use
+type failureAlarm struct {
+	opts   FailureAlarmOpts
+	window []bool
+	next   int
+	filled int
+	failed int
+	firing bool
+}
+
+func newFailureAlarm(opts FailureAlarmOpts) *failureAlarm {
+	if opts.Window <= 0 {
+		opts.Window = DefaultFailureAlarmWindow
+	}
+	if opts.ClearThreshold <= 0 {
+		opts.ClearThreshold = opts.Threshold / 2
+	}
+	if opts.MinSamples <= 0 || opts.MinSamples > opts.Window {
+		opts.MinSamples = opts.Window
+	}
+
+	return &failureAlarm{opts: opts, window: make([]bool, opts.Window)}
+}
+
+// update add result of download to window and call OnAlarm on change of alarm state
+func (alarm *failureAlarm) update(status DownloadStatus) {
+	if alarm == nil || status == DOWN_SKIP {
+		return
+	}
+
+	if alarm.filled == len(alarm.window) {
+		if alarm.window[alarm.next] {
+			alarm.failed--
+		}
+	} else {
+		alarm.filled++
+	}
+
+	failed := status == DOWN_FAIL
+	alarm.window[alarm.next] = failed
+	if failed {
+		alarm.failed++
+	}
+	alarm.next = (alarm.next + 1) % len(alarm.window)
+
+	if alarm.filled < alarm.opts.MinSamples {
+		return
+	}
+
+	rate := float64(alarm.failed) / float64(alarm.filled)
+	switch {
+	case !alarm.firing && rate > alarm.opts.Threshold:
+		alarm.firing = true
+	case alarm.firing && rate <= alarm.opts.ClearThreshold:
+		alarm.firing = false
+	default:
+		return
+	}
+
+	if alarm.opts.OnAlarm != nil {
+		alarm.opts.OnAlarm(alarm.firing, rate)
+	}
+}
@@ -0,0 +1,55 @@
+package storclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureAlarm(t *testing.T) {
+	type call struct {
+		firing bool
+		rate   float64
+	}
+	var calls []call
+
+	alarm := newFailureAlarm(FailureAlarmOpts{
+		Window:    4,
+		Threshold: 0.5,
+		OnAlarm: func(firing bool, rate float64) {
+			calls = append(calls, call{firing, rate})
+		},
+	})
+
+	feed := func(statuses ...DownloadStatus) {
+		for _, status := range statuses {
+			alarm.update(status)
+		}
+	}
+
+	feed(DOWN_FAIL, DOWN_FAIL, DOWN_FAIL)
+	assert.Empty(t, calls, "window isn't full")
+
+	feed(DOWN_SKIP, DOWN_SKIP)
+	assert.Empty(t, calls, "skipped files aren't counted")
+
+	feed(DOWN_OK)
+	assert.Equal(t, []call{{true, 0.75}}, calls, "alarm raised")
+
+	feed(DOWN_OK)
+	assert.Len(t, calls, 1, "0.5 is above clear threshold (hysteresis)")
+
+	feed(DOWN_OK)
+	assert.Equal(t, []call{{true, 0.75}, {false, 0.25}}, calls, "alarm cleared")
+
+	feed(DOWN_FAIL, DOWN_FAIL)
+	assert.Len(t, calls, 2, "0.5 doesn't exceed threshold")
+
+	feed(DOWN_FAIL)
+	assert.Equal(t, []call{{true, 0.75}, {false, 0.25}, {true, 0.75}}, calls, "alarm raised again")
+}
+
+func TestFailureAlarmNil(t *testing.T) {
+	var alarm *failureAlarm
+	assert.NotPanics(t, func() { alarm.update(DOWN_FAIL) })
+}
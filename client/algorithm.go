@@ -0,0 +1,60 @@
+package storclient
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+)
+
+// HashAlgorithm is algorithm of object keys (hashes) on stor
+type HashAlgorithm string
+
+const (
+	SHA256 HashAlgorithm = "sha256"
+	SHA1   HashAlgorithm = "sha1"
+	MD5    HashAlgorithm = "md5"
+	SHA512 HashAlgorithm = "sha512"
+)
+
+// HashAlgorithms is list of all supported algorithms
+var HashAlgorithms = []HashAlgorithm{SHA256, SHA1, MD5, SHA512}
+
+var hashConstructors = map[HashAlgorithm]func() hash.Hash{
+	SHA256: sha256.New,
+	SHA1:   sha1.New,
+	MD5:    md5.New,
+	SHA512: sha512.New,
+}
+
+// Valid return true for supported algorithm
+func (algorithm HashAlgorithm) Valid() bool {
+	_, ok := hashConstructors[algorithm]
+	return ok
+}
+
+// New return new hasher of algorithm (sha256 for unsupported algorithm)
+func (algorithm HashAlgorithm) New() hash.Hash {
+	if constructor, ok := hashConstructors[algorithm]; ok {
+		return constructor()
+	}
+
+	return sha256.New()
+}
+
+// Size return size of hash in bytes
+func (algorithm HashAlgorithm) Size() int {
+	return algorithm.New().Size()
+}
+
+// hashAlgorithmBySize return algorithm with hash of given size (all supported algorithms have different sizes)
+func hashAlgorithmBySize(size int) (HashAlgorithm, bool) {
+	for _, algorithm := range HashAlgorithms {
+		if algorithm.Size() == size {
+			return algorithm, true
+		}
+	}
+
+	return "", false
+}
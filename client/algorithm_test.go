@@ -0,0 +1,67 @@
+package storclient
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/url"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashAlgorithmBySize(t *testing.T) {
+	for _, algorithm := range HashAlgorithms {
+		found, ok := hashAlgorithmBySize(algorithm.Size())
+		assert.True(t, ok, string(algorithm))
+		assert.Equal(t, algorithm, found)
+	}
+
+	_, ok := hashAlgorithmBySize(7)
+	assert.False(t, ok)
+}
+
+func TestParseHashAlgorithm(t *testing.T) {
+	sum := sha1.Sum([]byte("sample"))
+	expected := hex.EncodeToString(sum[:])
+
+	for _, s := range []string{expected, "sha1:" + expected, "SHA1-" + expected} {
+		hash, err := ParseHashAlgorithm(s, SHA1)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, hash.String(), s)
+		}
+	}
+
+	for _, s := range []string{"sha256:" + expected, expected + "00"} {
+		_, err := ParseHashAlgorithm(s, SHA1)
+		if assert.Error(t, err, s) {
+			assert.Equal(t, ErrInvalidHash, errors.Cause(err), s)
+		}
+	}
+
+	_, err := ParseHashAlgorithm(expected, "crc32")
+	assert.Error(t, err)
+}
+
+func TestDownloadVerifySha1(t *testing.T) {
+	sum := sha1.Sum([]byte("sample"))
+	sha, err := ParseHashAlgorithm(hex.EncodeToString(sum[:]), SHA1)
+	assert.NoError(t, err)
+
+	size, err := downloadFileToDevnull(&bodyClientMock{body: "sample"}, "http://blabla", sha)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len("sample")), size)
+
+	_, err = downloadFileToDevnull(&bodyClientMock{body: "corrupted"}, "http://blabla", sha)
+	assert.Error(t, err)
+}
+
+func TestDownloadRejectOtherAlgorithm(t *testing.T) {
+	_, err := New(url.URL{}, "", StorClientOpts{HashAlgorithm: "crc32"})
+	assert.Error(t, err)
+
+	client, err := New(url.URL{}, "", StorClientOpts{HashAlgorithm: SHA1})
+	assert.NoError(t, err)
+
+	assert.Equal(t, ErrInvalidHash, errors.Cause(client.Download(emptyHash)), "sha256 isn't accepted by sha1 client")
+}
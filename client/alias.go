@@ -0,0 +1,118 @@
+package storclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNoAlias is returned by AliasResolver if sha has no alternate identifier
+var ErrNoAlias = errors.New("no alias of sha")
+
+// AliasResolver translate sha which isn't on stor (404) to alternate identifier
+// (e.g. md5/sha1 of same content or new canonical sha), download is retried under alias
+// and content is verified against alias (see StorClientOpts.AliasResolver)
+//
+// Resolve is called from worker goroutines, so it must be safe for concurrent use
+type AliasResolver interface {
+	Resolve(ctx context.Context, sha hashutil.Hash) (hashutil.Hash, error)
+}
+
+// AliasResolverFunc is adapter of function to AliasResolver
+type AliasResolverFunc func(ctx context.Context, sha hashutil.Hash) (hashutil.Hash, error)
+
+// Resolve call f(ctx, sha)
+func (f AliasResolverFunc) Resolve(ctx context.Context, sha hashutil.Hash) (hashutil.Hash, error) {
+	return f(ctx, sha)
+}
+
+// HTTPAliasResolver is mapping endpoint of aliases
+//
+// request is GET <URL>/<sha>, status 200 with alias in body (hex - algorithm by length,
+// or with algorithm prefix e.g. md5:<hex>) means alias, 404 means no alias (ErrNoAlias)
+type HTTPAliasResolver struct {
+	URL string
+	// default (nil) means http.DefaultClient
+	Client *http.Client
+}
+
+// Resolve ask mapping endpoint for alias of sha
+func (resolver HTTPAliasResolver) Resolve(ctx context.Context, sha hashutil.Hash) (hashutil.Hash, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(resolver.URL, "/")+"/"+sha.String(), nil)
+	if err != nil {
+		return hashutil.Hash{}, err
+	}
+	req = req.WithContext(ctx)
+
+	client := resolver.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return hashutil.Hash{}, errors.Wrap(err, "Alias lookup fail")
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return hashutil.Hash{}, errors.Wrap(err, "Alias lookup fail")
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return parseAlias(string(bytes.TrimSpace(body)))
+	case http.StatusNotFound:
+		return hashutil.Hash{}, ErrNoAlias
+	default:
+		return hashutil.Hash{}, errors.Errorf("Alias lookup of %s fail: %s %s", sha, resp.Status, bytes.TrimSpace(body))
+	}
+}
+
+// parseAlias parse alias with algorithm prefix, or hex alias of algorithm by its length
+func parseAlias(s string) (hashutil.Hash, error) {
+	if prefix, _, ok := splitAlgorithmPrefix(s); ok {
+		return ParseHashAlgorithm(s, HashAlgorithm(prefix))
+	}
+
+	algorithm, ok := hashAlgorithmBySize(hex.DecodedLen(len(s)))
+	if !ok {
+		return hashutil.Hash{}, errors.Wrapf(ErrInvalidHash, "alias %q has unknown length", s)
+	}
+
+	return ParseHashAlgorithm(s, algorithm)
+}
+
+// resolveAlias switch identity of download to alias of sha if failed attempt was 404 from stor,
+// return true if download should be retried under alias
+func (client *StorClient) resolveAlias(fields log.Fields, err error, trySource bool, sha hashutil.Hash, identity *hashutil.Hash) bool {
+	if client.AliasResolver == nil || trySource || !identity.Equal(sha) {
+		return false
+	}
+
+	if e, ok := err.(StatusError); !ok || e.StatusCode != http.StatusNotFound {
+		return false
+	}
+
+	alias, err := client.AliasResolver.Resolve(client.ctx, sha)
+	if err != nil {
+		if err != ErrNoAlias {
+			log.WithFields(fields).Warningf("Alias of %s fail: %s", sha, err)
+		}
+		return false
+	}
+
+	log.WithFields(fields).Debugf("%s isn't on stor, retry as alias %s", sha, alias)
+	*identity = alias
+
+	return true
+}
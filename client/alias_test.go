@@ -0,0 +1,109 @@
+package storclient_test
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func md5Of(content string) hashutil.Hash {
+	sum := md5.Sum([]byte(content))
+	hash, _ := hashutil.BytesToHash(md5.New(), sum[:])
+	return hash
+}
+
+func TestAliasResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimPrefix(r.URL.Path, "/") == md5Of("legacy").String() {
+			w.Write([]byte("legacy"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	storUrl, _ := url.Parse(server.URL)
+
+	tmpDir, err := ioutil.TempDir("", "alias")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	resolver := storclient.AliasResolverFunc(func(ctx context.Context, sha hashutil.Hash) (hashutil.Hash, error) {
+		if sha.Equal(sha256Of("legacy")) {
+			return md5Of("legacy"), nil
+		}
+		return hashutil.Hash{}, storclient.ErrNoAlias
+	})
+
+	client, err := storclient.New(*storUrl, tmpDir, storclient.StorClientOpts{AliasResolver: resolver, RetryAttempts: 3})
+	assert.NoError(t, err)
+	results := client.Results()
+
+	client.Start()
+	assert.NoError(t, client.Download(sha256Of("legacy")))
+	assert.NoError(t, client.Download(sha256Of("missing")))
+	go client.Wait()
+
+	byStatus := make(map[storclient.DownloadStatus]storclient.DownloadResult)
+	for result := range results {
+		byStatus[result.Status] = result
+	}
+
+	ok := byStatus[storclient.DOWN_OK]
+	assert.True(t, ok.Sha.Equal(sha256Of("legacy")))
+	assert.True(t, ok.Alias.Equal(md5Of("legacy")))
+	content, err := ioutil.ReadFile(filepath.Join(tmpDir, sha256Of("legacy").String()))
+	assert.NoError(t, err)
+	assert.Equal(t, "legacy", string(content))
+
+	failed := byStatus[storclient.DOWN_FAIL]
+	assert.True(t, failed.Sha.Equal(sha256Of("missing")))
+	assert.True(t, failed.Alias.IsEmpty())
+}
+
+func TestHTTPAliasResolver(t *testing.T) {
+	sum := sha1.Sum([]byte("legacy"))
+	sha1Hash, _ := hashutil.BytesToHash(sha1.New(), sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, "/aliases/") {
+		case sha256Of("md5").String():
+			w.Write([]byte("md5:" + md5Of("legacy").String() + "\n"))
+		case sha256Of("sha1").String():
+			w.Write([]byte(sha1Hash.String()))
+		case sha256Of("broken").String():
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	resolver := storclient.HTTPAliasResolver{URL: server.URL + "/aliases/"}
+
+	alias, err := resolver.Resolve(context.Background(), sha256Of("md5"))
+	assert.NoError(t, err)
+	assert.True(t, alias.Equal(md5Of("legacy")))
+
+	alias, err = resolver.Resolve(context.Background(), sha256Of("sha1"))
+	assert.NoError(t, err)
+	assert.True(t, alias.Equal(sha1Hash))
+
+	_, err = resolver.Resolve(context.Background(), sha256Of("unknown"))
+	assert.Equal(t, storclient.ErrNoAlias, err)
+
+	_, err = resolver.Resolve(context.Background(), sha256Of("broken"))
+	assert.Error(t, err)
+	assert.NotEqual(t, storclient.ErrNoAlias, err)
+}
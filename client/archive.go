@@ -0,0 +1,129 @@
+package storclient
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ArchiveFormat is format of archive output (see StorClientOpts.ArchiveWriter)
+type ArchiveFormat string
+
+const (
+	// ArchiveTar - uncompressed tar (default)
+	ArchiveTar ArchiveFormat = "tar"
+	// ArchiveTarGzip - gzip compressed tar
+	ArchiveTarGzip ArchiveFormat = "tar.gz"
+	// ArchiveZip - zip with deflated entries
+	ArchiveZip ArchiveFormat = "zip"
+)
+
+// archiveWriter append downloaded files to one archive, it's safe for concurrent use
+type archiveWriter struct {
+	lock    sync.Mutex
+	tar     *tar.Writer
+	gzip    *gzip.Writer
+	zip     *zip.Writer
+	entries map[string]bool
+}
+
+func newArchiveWriter(w io.Writer, format ArchiveFormat) (*archiveWriter, error) {
+	archive := &archiveWriter{entries: make(map[string]bool)}
+
+	switch format {
+	case ArchiveTar, "":
+		archive.tar = tar.NewWriter(w)
+	case ArchiveTarGzip:
+		archive.gzip = gzip.NewWriter(w)
+		archive.tar = tar.NewWriter(archive.gzip)
+	case ArchiveZip:
+		archive.zip = zip.NewWriter(w)
+	default:
+		return nil, errors.Errorf("unsupported archive format %q", format)
+	}
+
+	return archive, nil
+}
+
+// contains return true if entry is already in archive
+func (archive *archiveWriter) contains(name string) bool {
+	archive.lock.Lock()
+	defer archive.lock.Unlock()
+
+	return archive.entries[name]
+}
+
+// add append file as entry name to archive and remove file
+func (archive *archiveWriter) add(name, path string) (err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "Open of %s for archive fail", path)
+	}
+	defer func() {
+		file.Close()
+		if err == nil {
+			err = errors.Wrapf(os.Remove(path), "Remove of archived %s fail", path)
+		}
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	archive.lock.Lock()
+	defer archive.lock.Unlock()
+
+	if archive.entries[name] {
+		return nil
+	}
+
+	var out io.Writer
+	if archive.zip != nil {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		header.SetModTime(info.ModTime())
+		header.SetMode(0644)
+		if out, err = archive.zip.CreateHeader(header); err != nil {
+			return errors.Wrapf(err, "Write of archive entry %s fail", name)
+		}
+	} else {
+		header := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime().Truncate(time.Second)}
+		if err = archive.tar.WriteHeader(header); err != nil {
+			return errors.Wrapf(err, "Write of archive entry %s fail", name)
+		}
+		out = archive.tar
+	}
+
+	if _, err = io.Copy(out, file); err != nil {
+		return errors.Wrapf(err, "Write of archive entry %s fail", name)
+	}
+	archive.entries[name] = true
+
+	return nil
+}
+
+// close finish archive (underlying writer isn't closed)
+func (archive *archiveWriter) close() error {
+	archive.lock.Lock()
+	defer archive.lock.Unlock()
+
+	if archive.zip != nil {
+		return archive.zip.Close()
+	}
+
+	if err := archive.tar.Close(); err != nil {
+		return err
+	}
+
+	if archive.gzip != nil {
+		return archive.gzip.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package storclient_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveOutput(t *testing.T) {
+	contents := map[string]string{}
+	for _, content := range []string{"first", "second", "third"} {
+		contents[sha256Of(content).String()] = content
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if content, ok := contents[path.Base(r.URL.Path)]; ok {
+			w.Write([]byte(content))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	download := func(format storclient.ArchiveFormat) *bytes.Buffer {
+		tmpDir, err := ioutil.TempDir("", "archive")
+		assert.NoError(t, err)
+		defer os.RemoveAll(tmpDir)
+
+		out := &bytes.Buffer{}
+		client, err := storclient.New(*serverURL, tmpDir, storclient.StorClientOpts{Max: 2, RetryAttempts: 1, Suffix: ".dat", ArchiveWriter: out, ArchiveFormat: format})
+		assert.NoError(t, err)
+
+		client.Start()
+		for sha := range contents {
+			assert.NoError(t, client.Download(sha256Of(contents[sha])))
+		}
+		assert.NoError(t, client.Download(sha256Of("first")))
+		assert.NoError(t, client.Download(sha256Of("missing")))
+		total := client.Wait()
+		assert.Equal(t, 3, total.Count+total.Skip-1)
+		assert.Equal(t, 1, total.Failed())
+
+		files, err := filepath.Glob(filepath.Join(tmpDir, "*.dat"))
+		assert.NoError(t, err)
+		assert.Empty(t, files, "downloaded files are moved to archive")
+
+		return out
+	}
+
+	expected := map[string]string{}
+	for sha, content := range contents {
+		expected[sha+".dat"] = content
+	}
+
+	readTar := func(r io.Reader) map[string]string {
+		entries := map[string]string{}
+		tr := tar.NewReader(r)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			content, err := ioutil.ReadAll(tr)
+			assert.NoError(t, err)
+			entries[header.Name] = string(content)
+		}
+		return entries
+	}
+
+	out := download("")
+	assert.Equal(t, expected, readTar(out), "tar")
+
+	out = download(storclient.ArchiveTarGzip)
+	gz, err := gzip.NewReader(out)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, readTar(gz), "tar.gz")
+
+	out = download(storclient.ArchiveZip)
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	assert.NoError(t, err)
+	entries := map[string]string{}
+	for _, file := range zr.File {
+		rc, err := file.Open()
+		assert.NoError(t, err)
+		content, err := ioutil.ReadAll(rc)
+		assert.NoError(t, err)
+		rc.Close()
+		entries[file.Name] = string(content)
+	}
+	assert.Equal(t, expected, entries, "zip")
+
+	_, err = storclient.New(*serverURL, os.TempDir(), storclient.StorClientOpts{ArchiveWriter: &bytes.Buffer{}, ArchiveFormat: "rar"})
+	assert.Error(t, err)
+	_, err = storclient.New(*serverURL, os.TempDir(), storclient.StorClientOpts{ArchiveWriter: &bytes.Buffer{}, Devnull: true})
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package storclient
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Auth is authentication of requests to stor - exactly one method must be set
+//
+// credentials are sent to hosts of storage url, mirrors and tenants only (never to S3 or other hosts)
+type Auth struct {
+	// basic authentication
+	Username string
+	Password string
+	// static bearer token (Authorization: Bearer <token>)
+	BearerToken string
+	// arbitrary API key header e.g. X-Api-Key
+	APIKeyHeader string
+	APIKey       string
+}
+
+func (auth *Auth) validate() error {
+	methods := 0
+	if auth.Username != "" || auth.Password != "" {
+		methods++
+	}
+	if auth.BearerToken != "" {
+		methods++
+	}
+	if auth.APIKeyHeader != "" || auth.APIKey != "" {
+		if auth.APIKeyHeader == "" || auth.APIKey == "" {
+			return errors.New("both API key header and API key must be set")
+		}
+		methods++
+	}
+
+	if methods != 1 {
+		return errors.Errorf("exactly one authentication method must be set (%d are set)", methods)
+	}
+
+	return nil
+}
+
+func (auth *Auth) apply(req *http.Request) {
+	switch {
+	case auth.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+auth.BearerToken)
+	case auth.APIKeyHeader != "":
+		req.Header.Set(auth.APIKeyHeader, auth.APIKey)
+	default:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+}
+
+// authTransport add authentication to requests to allowed hosts
+type authTransport struct {
+	next http.RoundTripper
+	auth *Auth
+	lock sync.RWMutex
+	// hosts of storage url, mirrors and tenants
+	hosts map[string]bool
+}
+
+func newAuthTransport(next http.RoundTripper, auth *Auth, urls ...url.URL) *authTransport {
+	t := &authTransport{next: next, auth: auth, hosts: make(map[string]bool)}
+	for _, u := range urls {
+		t.allow(u)
+	}
+
+	return t
+}
+
+// allow send credentials to host of u (e.g. storage url of tenant)
+func (t *authTransport) allow(u url.URL) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.hosts[u.Host] = true
+}
+
+func (t *authTransport) allowed(host string) bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.hosts[host]
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.allowed(req.URL.Host) || req.Header.Get("Authorization") != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	// RoundTripper must not modify request
+	authReq := req.Clone(req.Context())
+	t.auth.apply(authReq)
+
+	return t.next.RoundTrip(authReq)
+}
@@ -0,0 +1,97 @@
+package storclient_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuth(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer server.Close()
+
+	s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"), "credentials aren't sent to S3")
+		http.NotFound(w, r)
+	}))
+	defer s3.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	s3URL, _ := url.Parse(s3.URL)
+
+	download := func(auth *storclient.Auth) http.Header {
+		got = nil
+		client, err := storclient.New(*serverURL, os.TempDir(), storclient.StorClientOpts{Devnull: true, RetryAttempts: 2, S3URL: s3URL, Auth: auth})
+		assert.NoError(t, err)
+
+		client.Start()
+		assert.NoError(t, client.Download(sha256Of("")))
+		assert.Equal(t, 1, client.Wait().Count)
+
+		return got
+	}
+
+	header := download(&storclient.Auth{Username: "user", Password: "secret"})
+	assert.Equal(t, "Basic dXNlcjpzZWNyZXQ=", header.Get("Authorization"))
+
+	header = download(&storclient.Auth{BearerToken: "token"})
+	assert.Equal(t, "Bearer token", header.Get("Authorization"))
+
+	header = download(&storclient.Auth{APIKeyHeader: "X-Api-Key", APIKey: "key"})
+	assert.Equal(t, "key", header.Get("X-Api-Key"))
+	assert.Empty(t, header.Get("Authorization"))
+
+	for _, invalid := range []*storclient.Auth{
+		{},
+		{BearerToken: "token", Username: "user"},
+		{APIKeyHeader: "X-Api-Key"},
+	} {
+		_, err := storclient.New(*serverURL, "", storclient.StorClientOpts{Auth: invalid})
+		assert.Error(t, err)
+	}
+}
+
+func TestAuthHosts(t *testing.T) {
+	authorization := func(got *[]string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*got = append(*got, r.Header.Get("Authorization"))
+		}))
+	}
+
+	var gotMirror, gotTenant []string
+	mirror := authorization(&gotMirror)
+	defer mirror.Close()
+	tenant := authorization(&gotTenant)
+	defer tenant.Close()
+
+	// storage url is down, so download fails over to mirror
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL, _ := url.Parse(down.URL)
+	down.Close()
+	mirrorURL, _ := url.Parse(mirror.URL)
+	tenantURL, _ := url.Parse(tenant.URL)
+
+	tenantDir, err := ioutil.TempDir("", "auth")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tenantDir)
+
+	client, err := storclient.New(*downURL, os.TempDir(), storclient.StorClientOpts{Devnull: true, RetryAttempts: 2, Mirrors: []url.URL{*mirrorURL}, Auth: &storclient.Auth{BearerToken: "token"}})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(sha256Of("")))
+	assert.NoError(t, client.WithTenant(tenantDir, *tenantURL, nil).Download(sha256Of("")))
+	assert.Equal(t, 2, client.Wait().Count)
+
+	assert.Equal(t, []string{"Bearer token"}, gotMirror)
+	assert.Equal(t, []string{"Bearer token"}, gotTenant)
+}
@@ -0,0 +1,203 @@
+package storclient
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AutoscaleOpts is configuration of autoscaling worker pool (see StorClientOpts.Autoscale)
+//
+// every Interval the pool is resized by queue depth and average duration of downloads
+// finished in the interval - pool grows (doubles, up to MaxWorkers) while jobs wait in queue
+// and latency is in TargetLatency, shrinks by one worker when latency exceeds TargetLatency
+// (more workers only overload server) or when queue is empty (down to MinWorkers)
+type AutoscaleOpts struct {
+	// workers kept running also in idle periods
+	// default (0) means pool scales down to zero and first Download starts worker
+	MinWorkers int
+	// upper bound of workers
+	// default (0) is StorClientOpts.Max
+	MaxWorkers int
+	// period of pool resizing
+	// default (0) is 1s
+	Interval time.Duration
+	// average download duration above which pool stops growing and shrinks
+	// default (0) means latency is ignored
+	TargetLatency time.Duration
+}
+
+// DefaultAutoscaleInterval is default period of pool resizing
+const DefaultAutoscaleInterval = time.Second
+
+// autoscaler collects durations of finished downloads for resizing of worker pool
+type autoscaler struct {
+	opts AutoscaleOpts
+	// idle worker which receives from shrink ends
+	shrink chan struct{}
+
+	lock     sync.Mutex
+	duration time.Duration
+	count    int
+}
+
+func newAutoscaler(opts AutoscaleOpts, max int) *autoscaler {
+	if opts.MaxWorkers <= 0 {
+		opts.MaxWorkers = max
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultAutoscaleInterval
+	}
+
+	return &autoscaler{opts: opts, shrink: make(chan struct{})}
+}
+
+// observe add download duration to current interval
+func (scaler *autoscaler) observe(stat DownStat) {
+	if scaler == nil || stat.Status != DOWN_OK {
+		return
+	}
+
+	scaler.lock.Lock()
+	defer scaler.lock.Unlock()
+
+	scaler.duration += stat.Duration
+	scaler.count++
+}
+
+// latency return average duration of downloads finished since last call (0 without downloads)
+func (scaler *autoscaler) latency() time.Duration {
+	scaler.lock.Lock()
+	defer scaler.lock.Unlock()
+
+	var latency time.Duration
+	if scaler.count > 0 {
+		latency = scaler.duration / time.Duration(scaler.count)
+	}
+	scaler.duration, scaler.count = 0, 0
+
+	return latency
+}
+
+// target return wanted count of workers
+func (scaler *autoscaler) target(running, depth int, latency time.Duration) int {
+	opts := scaler.opts
+	overloaded := opts.TargetLatency > 0 && latency > opts.TargetLatency
+
+	target := running
+	switch {
+	case overloaded:
+		target--
+	case depth > 0:
+		grow := running
+		if grow < 1 {
+			grow = 1
+		}
+		if grow > depth {
+			grow = depth
+		}
+		target += grow
+	default:
+		target--
+	}
+
+	if target > opts.MaxWorkers {
+		target = opts.MaxWorkers
+	}
+	if target < opts.MinWorkers {
+		target = opts.MinWorkers
+	}
+	if target < 1 && depth > 0 {
+		// queued jobs always have worker
+		target = 1
+	}
+
+	return target
+}
+
+// autoscale resize worker pool every interval until queue is closed
+func (client *StorClient) autoscale() {
+	scaler := client.autoscaler
+	for {
+		select {
+		case <-client.queue.jobs.closed:
+			return
+		case <-client.Clock.After(scaler.opts.Interval):
+		}
+
+		client.workers.lock.Lock()
+		running := client.workers.running
+		client.workers.lock.Unlock()
+
+		depth := client.queue.jobs.len()
+		latency := scaler.latency()
+		target := scaler.target(running, depth, latency)
+
+		if target != running {
+			log.WithFields(log.Fields{
+				"workers": running,
+				"target":  target,
+				"queue":   depth,
+				"latency": latency,
+			}).Debug("autoscale")
+		}
+
+		client.resizeWorkers(target)
+	}
+}
+
+// resizeWorkers start workers up to target or ask idle workers to end,
+// busy workers are never interrupted - pool shrinks as they become idle
+func (client *StorClient) resizeWorkers(target int) {
+	client.queue.whileOpen(func() {
+		client.workers.lock.Lock()
+		defer client.workers.lock.Unlock()
+
+		for client.workers.running < target {
+			client.spawnWorker()
+		}
+	})
+
+	client.workers.lock.Lock()
+	excess := client.workers.running - target
+	client.workers.lock.Unlock()
+
+	for ; excess > 0; excess-- {
+		select {
+		case client.autoscaler.shrink <- struct{}{}:
+		default:
+			return
+		}
+	}
+}
+
+// ensureAutoscaledWorker start first worker for pushed job when pool is scaled down to zero,
+// it's called by queue under its lock like ensureWorker
+func (client *StorClient) ensureAutoscaledWorker() {
+	client.workers.lock.Lock()
+	defer client.workers.lock.Unlock()
+
+	if client.workers.running == 0 && client.autoscaler.opts.MaxWorkers > 0 {
+		client.spawnWorker()
+	}
+}
+
+// shrinkWorker return true if idle worker asked by autoscaler can end - it can't if there are
+// pending jobs (pushed concurrently with shrink, pushing doesn't start worker while this one runs),
+// last worker closes idle connections
+func (client *StorClient) shrinkWorker(jobs *priorityQueue) bool {
+	client.workers.lock.Lock()
+	defer client.workers.lock.Unlock()
+
+	if jobs.len() > 0 {
+		return false
+	}
+
+	client.workers.running--
+	if client.workers.running == 0 {
+		client.closeIdleConnections()
+	}
+
+	return true
+}
@@ -0,0 +1,113 @@
+package storclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoscaleTarget(t *testing.T) {
+	scaler := newAutoscaler(AutoscaleOpts{MinWorkers: 1, MaxWorkers: 8, TargetLatency: time.Second}, DefaultMax)
+
+	assert.Equal(t, 2, scaler.target(1, 100, 0), "grows with backlog")
+	assert.Equal(t, 8, scaler.target(6, 100, time.Millisecond), "up to max")
+	assert.Equal(t, 5, scaler.target(4, 1, time.Millisecond), "by queue depth")
+	assert.Equal(t, 3, scaler.target(4, 100, 2*time.Second), "shrinks over target latency")
+	assert.Equal(t, 3, scaler.target(4, 0, 0), "shrinks when idle")
+	assert.Equal(t, 1, scaler.target(1, 0, 0), "down to min")
+
+	scaler = newAutoscaler(AutoscaleOpts{}, DefaultMax)
+	assert.Equal(t, DefaultMax, scaler.opts.MaxWorkers)
+	assert.Equal(t, DefaultAutoscaleInterval, scaler.opts.Interval)
+	assert.Equal(t, 2, scaler.target(1, 5, time.Hour), "latency is ignored without target")
+	assert.Equal(t, 0, scaler.target(1, 0, 0), "scales to zero")
+}
+
+func TestAutoscaleOpts(t *testing.T) {
+	_, err := New(url.URL{}, os.TempDir(), StorClientOpts{Autoscale: &AutoscaleOpts{}, IdleWorkerTimeout: time.Second})
+	assert.Error(t, err)
+
+	_, err = New(url.URL{}, os.TempDir(), StorClientOpts{Autoscale: &AutoscaleOpts{MinWorkers: 5, MaxWorkers: 2}})
+	assert.Error(t, err)
+}
+
+func TestAutoscaleShrinkWithQueuedJob(t *testing.T) {
+	client, err := New(url.URL{}, os.TempDir(), StorClientOpts{Devnull: true, Autoscale: &AutoscaleOpts{Interval: time.Hour}})
+	assert.NoError(t, err)
+
+	// one idle worker with shrink pending while job is pushed and queue closed
+	client.workers.running = 1
+	client.autoscaler.shrink = make(chan struct{}, 1)
+	client.autoscaler.shrink <- struct{}{}
+	assert.NoError(t, client.queue.push(context.Background(), downloadJob{sha: emptyHash}))
+	client.queue.close()
+
+	assert.False(t, client.shrinkWorker(client.queue.jobs), "worker isn't shrunk while job is queued")
+	assert.Equal(t, 1, runningWorkers(client))
+
+	job, ok := client.nextJob(client.queue)
+	assert.True(t, ok)
+	assert.Equal(t, emptyHash, job.sha)
+
+	<-client.autoscaler.shrink
+	assert.True(t, client.shrinkWorker(client.queue.jobs), "idle worker is shrunk with empty queue")
+	assert.Equal(t, 0, runningWorkers(client))
+}
+
+func TestAutoscale(t *testing.T) {
+	contents := map[string]string{}
+	var shas []hashutil.Hash
+	for i := 0; i <= 200; i++ {
+		content := fmt.Sprintf("content%d", i)
+		sha := contentHash(content)
+		contents[sha.String()] = content
+		shas = append(shas, sha)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		_, _ = w.Write([]byte(contents[path.Base(r.URL.Path)]))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{
+		Devnull:   true,
+		Autoscale: &AutoscaleOpts{MaxWorkers: 4, Interval: 10 * time.Millisecond},
+	})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.Equal(t, 0, runningWorkers(client))
+
+	waitForWorkers := func(check func(int) bool) int {
+		deadline := time.Now().Add(5 * time.Second)
+		running := runningWorkers(client)
+		for !check(running) && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+			running = runningWorkers(client)
+		}
+		return running
+	}
+
+	for _, sha := range shas[:200] {
+		assert.NoError(t, client.Download(sha))
+	}
+
+	assert.Equal(t, 4, waitForWorkers(func(running int) bool { return running == 4 }), "pool grows up to max")
+	assert.Equal(t, 0, waitForWorkers(func(running int) bool { return running == 0 }), "pool shrinks when idle")
+
+	assert.NoError(t, client.Download(shas[200]))
+	total := client.Wait()
+
+	assert.Equal(t, 201, total.Count)
+}
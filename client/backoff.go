@@ -0,0 +1,62 @@
+package storclient
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryBackoff is strategy of delays between retry attempts (see StorClientOpts.RetryBackoff)
+type RetryBackoff int
+
+const (
+	// BackoffExponential - delay is doubled after each attempt (RetryDelay, 2*RetryDelay, 4*RetryDelay...)
+	BackoffExponential RetryBackoff = iota
+	// BackoffFixed - every delay is RetryDelay
+	BackoffFixed
+)
+
+func (backoff RetryBackoff) String() string {
+	switch backoff {
+	case BackoffFixed:
+		return "fixed"
+	default:
+		return "exponential"
+	}
+}
+
+// jitterRand is source of random jitter of retry delays shared by workers
+type jitterRand struct {
+	lock sync.Mutex
+	rnd  *rand.Rand
+}
+
+func newJitterRand(seed int64) *jitterRand {
+	return &jitterRand{rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (jitter *jitterRand) float64() float64 {
+	jitter.lock.Lock()
+	defer jitter.lock.Unlock()
+
+	return jitter.rnd.Float64()
+}
+
+// backoffDelay return delay before retry attempt (attempt >= 1) by strategy,
+// capped by maxDelay (0 means without cap)
+func backoffDelay(backoff RetryBackoff, delay, maxDelay time.Duration, attempt uint) time.Duration {
+	if backoff == BackoffExponential {
+		for i := uint(1); i < attempt; i++ {
+			if maxDelay > 0 && delay >= maxDelay {
+				break
+			}
+			delay *= 2
+		}
+	}
+
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return delay
+}
@@ -0,0 +1,59 @@
+package storclient
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		backoff  RetryBackoff
+		maxDelay time.Duration
+		attempt  uint
+		expected time.Duration
+	}{
+		{BackoffExponential, 0, 1, time.Second},
+		{BackoffExponential, 0, 4, 8 * time.Second},
+		{BackoffExponential, 5 * time.Second, 4, 5 * time.Second},
+		{BackoffExponential, 5 * time.Second, 200, 5 * time.Second},
+		{BackoffFixed, 0, 4, time.Second},
+		{BackoffFixed, 500 * time.Millisecond, 4, 500 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, backoffDelay(test.backoff, time.Second, test.maxDelay, test.attempt), "%s max %s attempt %d", test.backoff, test.maxDelay, test.attempt)
+	}
+}
+
+func TestRetryJitter(t *testing.T) {
+	client, err := New(url.URL{}, "", StorClientOpts{RetryDelay: time.Second, RetryBackoff: BackoffFixed, RetryJitter: 0.5})
+	assert.NoError(t, err)
+
+	delays := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		delay := client.retryDelay(1)
+		assert.True(t, delay > 500*time.Millisecond && delay <= time.Second, "%s is out of jitter range", delay)
+		delays[delay] = true
+	}
+	assert.True(t, len(delays) > 1, "delays are randomized")
+
+	_, err = New(url.URL{}, "", StorClientOpts{RetryJitter: 1.5})
+	assert.Error(t, err)
+}
+
+func TestRetryMaxDelay(t *testing.T) {
+	clock := &fakeClock{}
+
+	httpClient := func() httpClient { return &clientMock{statusCode: 500, status: "Something bad"} }
+	opts := StorClientOpts{RetryAttempts: 5, RetryDelay: time.Minute, RetryMaxDelay: 3 * time.Minute, Clock: clock}
+	downloadWorkersTest(t, opts, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
+		assert.Equal(t, DOWN_FAIL, stat[0].Status)
+	})
+
+	assert.Equal(t, []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}, clock.sleeps)
+}
@@ -0,0 +1,78 @@
+package storclient
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/avast/hashutil-go"
+)
+
+// ErrBackpressure is cause of BackpressureError (see TryDownload)
+var ErrBackpressure = errors.New("download queue is full")
+
+// DefaultBackpressureRetryAfter is suggested delay of next TryDownload before first download is done
+const DefaultBackpressureRetryAfter = time.Second
+
+// minBackpressureRetryAfter is lower bound of suggested delay
+const minBackpressureRetryAfter = 10 * time.Millisecond
+
+// errQueueFull is returned by non-blocking push to full queue
+var errQueueFull = errors.New("queue is full")
+
+// BackpressureError is returned by TryDownload when download queue is full
+type BackpressureError struct {
+	// count of shas waiting in queue
+	Depth int
+	// max count of shas in queue
+	Capacity int
+	// suggested delay of next TryDownload - estimated time of processing of half of queue
+	// by rate of downloads so far
+	RetryAfter time.Duration
+}
+
+func (err *BackpressureError) Error() string {
+	return fmt.Sprintf("%s (%d of %d queued, retry after %s)", ErrBackpressure, err.Depth, err.Capacity, err.RetryAfter)
+}
+
+// Cause return ErrBackpressure
+func (err *BackpressureError) Cause() error {
+	return ErrBackpressure
+}
+
+// TryDownload add sha to download queue like Download, but doesn't block if queue is full -
+// *BackpressureError is returned instead (see TotalStat.Backpressure), so producer can adapt rate of feeding
+func (client *StorClient) TryDownload(sha hashutil.Hash) error {
+	if err := client.validateHash(sha); err != nil {
+		return err
+	}
+
+	if err := client.queue.tryPush(downloadJob{sha: sha}); err != errQueueFull {
+		if err == nil {
+			client.events.emit(Event{Event: EventQueued, Sha: sha.String()})
+		}
+		return err
+	}
+
+	snapshot := client.Snapshot()
+	return &BackpressureError{
+		Depth:      snapshot.Queued,
+		Capacity:   client.queue.jobs.capacity(),
+		RetryAfter: backpressureRetryAfter(snapshot),
+	}
+}
+
+// backpressureRetryAfter return estimated time of processing of half of queue
+func backpressureRetryAfter(snapshot Snapshot) time.Duration {
+	if snapshot.Done == 0 || snapshot.Elapsed <= 0 {
+		return DefaultBackpressureRetryAfter
+	}
+
+	perJob := float64(snapshot.Elapsed) / float64(snapshot.Done)
+	retryAfter := time.Duration(perJob * float64(snapshot.Queued) / 2)
+	if retryAfter < minBackpressureRetryAfter {
+		return minBackpressureRetryAfter
+	}
+
+	return retryAfter
+}
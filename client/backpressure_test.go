@@ -0,0 +1,38 @@
+package storclient
+
+import (
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTryDownload(t *testing.T) {
+	client, err := New(url.URL{}, os.TempDir(), StorClientOpts{})
+	assert.NoError(t, err)
+	client.queue = newDownloadQueue(1)
+
+	assert.NoError(t, client.TryDownload(emptyHash))
+
+	err = client.TryDownload(contentHash("second"))
+	assert.Equal(t, ErrBackpressure, errors.Cause(err))
+	if backpressure, ok := err.(*BackpressureError); assert.True(t, ok) {
+		assert.Equal(t, 1, backpressure.Depth)
+		assert.Equal(t, 1, backpressure.Capacity)
+		assert.Equal(t, DefaultBackpressureRetryAfter, backpressure.RetryAfter, "nothing is done yet")
+	}
+	assert.Equal(t, 1, client.Snapshot().Backpressure)
+
+	client.queue.close()
+	assert.Equal(t, ErrQueueClosed, client.TryDownload(emptyHash))
+	assert.Equal(t, 1, client.queue.rejections())
+}
+
+func TestBackpressureRetryAfter(t *testing.T) {
+	assert.Equal(t, DefaultBackpressureRetryAfter, backpressureRetryAfter(Snapshot{Queued: 10}))
+	assert.Equal(t, 5*time.Second, backpressureRetryAfter(Snapshot{Queued: 10, Done: 10, Elapsed: 10 * time.Second}))
+	assert.Equal(t, minBackpressureRetryAfter, backpressureRetryAfter(Snapshot{Queued: 1, Done: 1000, Elapsed: time.Millisecond}))
+}
@@ -0,0 +1,119 @@
+package storclient
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BandwidthWindow is count of bytes transferred in one time window
+type BandwidthWindow struct {
+	Start time.Time `json:"start"`
+	Size  int64     `json:"size"`
+}
+
+// bandwidthMeter count transferred bytes per time window from start
+type bandwidthMeter struct {
+	lock   sync.Mutex
+	clock  Clock
+	start  time.Time
+	window time.Duration
+	sizes  []int64
+}
+
+func newBandwidthMeter(clock Clock, window time.Duration) *bandwidthMeter {
+	return &bandwidthMeter{clock: clock, start: clock.Now(), window: window}
+}
+
+func (m *bandwidthMeter) add(size int64) {
+	idx := int(since(m.clock, m.start) / m.window)
+	if idx < 0 {
+		idx = 0
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for len(m.sizes) <= idx {
+		m.sizes = append(m.sizes, 0)
+	}
+	m.sizes[idx] += size
+}
+
+// series return all windows from start to last window with transfer
+func (m *bandwidthMeter) series() []BandwidthWindow {
+	if m == nil {
+		return nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	series := make([]BandwidthWindow, len(m.sizes))
+	for i, size := range m.sizes {
+		series[i] = BandwidthWindow{Start: m.start.Add(time.Duration(i) * m.window), Size: size}
+	}
+
+	return series
+}
+
+// mergeBandwidth merge two series ordered by Start, sizes of same windows are summed
+func mergeBandwidth(a, b []BandwidthWindow) []BandwidthWindow {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	merged := make([]BandwidthWindow, 0, len(a)+len(b))
+	for len(a) > 0 || len(b) > 0 {
+		switch {
+		case len(b) == 0 || (len(a) > 0 && a[0].Start.Before(b[0].Start)):
+			merged = append(merged, a[0])
+			a = a[1:]
+		case len(a) == 0 || b[0].Start.Before(a[0].Start):
+			merged = append(merged, b[0])
+			b = b[1:]
+		default:
+			merged = append(merged, BandwidthWindow{Start: a[0].Start, Size: a[0].Size + b[0].Size})
+			a = a[1:]
+			b = b[1:]
+		}
+	}
+
+	return merged
+}
+
+// meteredClient count bytes of response bodies to bandwidthMeter
+type meteredClient struct {
+	httpClient
+	meter *bandwidthMeter
+}
+
+func (c *meteredClient) Get(url string) (*http.Response, error) {
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.Body = &meteredBody{ReadCloser: resp.Body, meter: c.meter}
+
+	return resp, nil
+}
+
+func (c *meteredClient) unwrap() httpClient {
+	return c.httpClient
+}
+
+type meteredBody struct {
+	io.ReadCloser
+	meter *bandwidthMeter
+}
+
+func (b *meteredBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.meter.add(int64(n))
+	}
+
+	return n, err
+}
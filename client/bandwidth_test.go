@@ -0,0 +1,68 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBandwidthMeter(t *testing.T) {
+	start := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	meter := newBandwidthMeter(clock, time.Minute)
+
+	meter.add(10)
+	clock.Sleep(30 * time.Second)
+	meter.add(5)
+	clock.Sleep(2 * time.Minute)
+	meter.add(7)
+
+	assert.Equal(t, []BandwidthWindow{
+		{Start: start, Size: 15},
+		{Start: start.Add(time.Minute), Size: 0},
+		{Start: start.Add(2 * time.Minute), Size: 7},
+	}, meter.series())
+
+	var nilMeter *bandwidthMeter
+	assert.Nil(t, nilMeter.series())
+}
+
+func TestMergeBandwidth(t *testing.T) {
+	start := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	a := []BandwidthWindow{{Start: start, Size: 1}, {Start: start.Add(time.Minute), Size: 2}}
+	b := []BandwidthWindow{{Start: start.Add(time.Minute), Size: 3}, {Start: start.Add(2 * time.Minute), Size: 4}}
+
+	assert.Equal(t, []BandwidthWindow{
+		{Start: start, Size: 1},
+		{Start: start.Add(time.Minute), Size: 5},
+		{Start: start.Add(2 * time.Minute), Size: 4},
+	}, mergeBandwidth(a, b))
+	assert.Equal(t, mergeBandwidth(a, b), mergeBandwidth(b, a))
+	assert.Nil(t, mergeBandwidth(nil, nil))
+}
+
+type bodyClientMock struct {
+	body string
+}
+
+func (c *bodyClientMock) Get(url string) (*http.Response, error) {
+	return &http.Response{StatusCode: 200, Status: "Ok", Body: ioutil.NopCloser(strings.NewReader(c.body))}, nil
+}
+
+func TestMeteredClient(t *testing.T) {
+	meter := newBandwidthMeter(&fakeClock{}, time.Minute)
+	c := &meteredClient{httpClient: &bodyClientMock{body: "0123456789"}, meter: meter}
+
+	resp, err := c.Get("http://blabla")
+	assert.NoError(t, err)
+	_, err = ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	if assert.Len(t, meter.series(), 1) {
+		assert.Equal(t, int64(10), meter.series()[0].Size)
+	}
+}
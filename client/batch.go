@@ -0,0 +1,43 @@
+package storclient
+
+import (
+	"context"
+
+	"github.com/avast/hashutil-go"
+)
+
+// Batch is named group of downloads with priority (see StorClient.NewBatch)
+//
+// jobs of batch with higher priority are scheduled before jobs with lower priority
+// (plain Download jobs have priority 0) - so new interactive batch preempts scheduling of bulk batch,
+// jobs of preempted batch stay queued and continue when there is nothing with higher priority
+type Batch struct {
+	client   *StorClient
+	name     string
+	priority int
+}
+
+// NewBatch create batch of downloads with priority (higher is sooner)
+func (client *StorClient) NewBatch(name string, priority int) *Batch {
+	return &Batch{client: client, name: name, priority: priority}
+}
+
+// Name return name of batch
+func (batch *Batch) Name() string {
+	return batch.name
+}
+
+// Priority return priority of batch
+func (batch *Batch) Priority() int {
+	return batch.priority
+}
+
+// Download add sha to download queue with priority of batch
+func (batch *Batch) Download(sha hashutil.Hash) error {
+	return batch.DownloadCtx(context.Background(), sha)
+}
+
+// DownloadCtx add sha to download queue with priority of batch (see StorClient.DownloadCtx)
+func (batch *Batch) DownloadCtx(ctx context.Context, sha hashutil.Hash) error {
+	return batch.client.push(ctx, downloadJob{sha: sha, batch: batch, priority: batch.priority})
+}
@@ -0,0 +1,71 @@
+package storclient
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchPreemption(t *testing.T) {
+	contents := map[string]string{}
+	shaOf := func(content string) hashutil.Hash {
+		sha := contentHash(content)
+		contents[sha.String()] = content
+		return sha
+	}
+	first, bulk1, bulk2, interactive := shaOf("first"), shaOf("bulk1"), shaOf("bulk2"), shaOf("interactive")
+
+	var lock sync.Mutex
+	var order []string
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content := contents[path.Base(r.URL.Path)]
+		lock.Lock()
+		order = append(order, content)
+		lock.Unlock()
+
+		if content == "first" {
+			close(started)
+			<-release
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{Max: 1, Devnull: true})
+	assert.NoError(t, err)
+	client.Start()
+
+	bulk := client.NewBatch("nightly", 0)
+	analyst := client.NewBatch("analyst", 10)
+	assert.Equal(t, "analyst", analyst.Name())
+	assert.Equal(t, 10, analyst.Priority())
+
+	assert.NoError(t, bulk.Download(first))
+	<-started
+
+	assert.NoError(t, bulk.Download(bulk1))
+	assert.NoError(t, bulk.Download(bulk2))
+	assert.NoError(t, analyst.Download(interactive))
+	close(release)
+
+	total := client.Wait()
+	assert.Equal(t, 4, total.Count)
+	assert.Equal(t, []string{"first", "interactive", "bulk1", "bulk2"}, order, "interactive batch preempts bulk batch")
+}
+
+func contentHash(content string) hashutil.Hash {
+	sum := sha256.Sum256([]byte(content))
+	hash, _ := hashutil.BytesToHash(sha256.New(), sum[:])
+	return hash
+}
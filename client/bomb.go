@@ -0,0 +1,93 @@
+package storclient
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ErrDecompressionBomb is cause of error returned if decompressed content
+// exceeds MaxDecompressedSize or archive has more files than MaxArchiveFiles
+//
+// download which fails on it isn't retried
+var ErrDecompressionBomb = errors.New("decompression bomb")
+
+// decompressionLimits guard output of decompression (and archive extraction)
+type decompressionLimits struct {
+	maxSize  int64
+	maxFiles int
+}
+
+// reader return reader which fails with ErrDecompressionBomb after maxSize bytes
+func (limits decompressionLimits) reader(r io.ReadCloser) io.ReadCloser {
+	if limits.maxSize <= 0 {
+		return r
+	}
+
+	return &limitedDecompressedBody{ReadCloser: r, remaining: limits.maxSize, limit: limits.maxSize}
+}
+
+// checkFiles return error if count of (extracted) files exceeds maxFiles
+func (limits decompressionLimits) checkFiles(count int) error {
+	if limits.maxFiles > 0 && count > limits.maxFiles {
+		return errors.Wrapf(ErrDecompressionBomb, "archive has more than %d files", limits.maxFiles)
+	}
+
+	return nil
+}
+
+type limitedDecompressedBody struct {
+	io.ReadCloser
+	remaining int64
+	limit     int64
+}
+
+func (b *limitedDecompressedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// probe if content really continues over limit
+		var probe [1]byte
+		if n, _ := b.ReadCloser.Read(probe[:]); n > 0 {
+			return 0, errors.Wrapf(ErrDecompressionBomb, "decompressed content is bigger than %d bytes", b.limit)
+		}
+
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+
+	return n, err
+}
+
+// decompressionGuardClient limit size of responses transparently decompressed by transport
+type decompressionGuardClient struct {
+	httpClient
+	limits decompressionLimits
+}
+
+func (c *decompressionGuardClient) Get(url string) (*http.Response, error) {
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.Uncompressed {
+		resp.Body = c.limits.reader(resp.Body)
+	}
+
+	return resp, nil
+}
+
+func (c *decompressionGuardClient) unwrap() httpClient {
+	return c.httpClient
+}
+
+// isDecompressionBomb return true if cause of err (or of last attempt of download) is ErrDecompressionBomb
+func isDecompressionBomb(err error) bool {
+	return errors.Cause(lastAttemptError(err)) == ErrDecompressionBomb
+}
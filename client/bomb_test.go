@@ -0,0 +1,91 @@
+package storclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecompressionLimitsReader(t *testing.T) {
+	limits := decompressionLimits{maxSize: 10}
+
+	content, err := ioutil.ReadAll(limits.reader(ioutil.NopCloser(strings.NewReader("0123456789"))))
+	assert.NoError(t, err, "content of limit size is ok")
+	assert.Equal(t, "0123456789", string(content))
+
+	_, err = ioutil.ReadAll(limits.reader(ioutil.NopCloser(strings.NewReader("0123456789A"))))
+	assert.True(t, isDecompressionBomb(err))
+
+	assert.NoError(t, limits.checkFiles(1000), "files aren't limited")
+	assert.True(t, isDecompressionBomb(decompressionLimits{maxFiles: 2}.checkFiles(3)))
+}
+
+func TestDecompressionBomb(t *testing.T) {
+	content := strings.Repeat("0", 1024*1024)
+	sum := sha256.Sum256([]byte(content))
+	sha, _ := hashutil.BytesToHash(sha256.New(), sum[:])
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	_, err := gz.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gzipped.Bytes())
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "bomb")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	serverURL, _ := url.Parse(server.URL)
+
+	t.Run("limit exceeded", func(t *testing.T) {
+		requests = 0
+		client, err := New(*serverURL, tmpDir, StorClientOpts{RetryAttempts: 3, RetryDelay: 1, MaxDecompressedSize: 1024})
+		assert.NoError(t, err)
+
+		results := client.Results()
+		client.Start()
+		go func() {
+			assert.NoError(t, client.Download(sha))
+			client.Wait()
+		}()
+
+		result := <-results
+		for range results {
+		}
+
+		assert.Equal(t, DOWN_FAIL, result.Status)
+		assert.True(t, isDecompressionBomb(result.Err), "%s", result.Err)
+		assert.Equal(t, 1, requests, "bomb isn't retried")
+
+		files, _ := ioutil.ReadDir(tmpDir)
+		assert.Empty(t, files, "temp file is removed")
+	})
+
+	t.Run("under limit", func(t *testing.T) {
+		client, err := New(*serverURL, tmpDir, StorClientOpts{RetryAttempts: 1, MaxDecompressedSize: int64(len(content))})
+		assert.NoError(t, err)
+
+		client.Start()
+		assert.NoError(t, client.Download(sha))
+		total := client.Wait()
+		assert.Equal(t, 1, total.Count)
+	})
+}
@@ -0,0 +1,135 @@
+package storclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultCircuitBreakerThreshold is count of consecutive failures which trips circuit breaker
+const DefaultCircuitBreakerThreshold = 5
+
+// DefaultCircuitBreakerCoolDown is time of open circuit breaker before probe
+const DefaultCircuitBreakerCoolDown = 30 * time.Second
+
+// ErrCircuitOpen is returned (without retry) for downloads from stor while circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker of stor is open")
+
+// CircuitBreakerOpts is configuration of circuit breaker of stor (see StorClientOpts.CircuitBreaker)
+//
+// breaker trips (opens) after Threshold consecutive transport errors or 5xx responses of stor
+// (storage url and its mirrors), then attempts on stor fail fast with ErrCircuitOpen for CoolDown.
+// After CoolDown one attempt is let through as probe - its success closes the breaker,
+// its failure opens the breaker for another CoolDown. Attempts on source (S3) aren't affected.
+type CircuitBreakerOpts struct {
+	// count of consecutive failures which trips breaker
+	// default (0) is DefaultCircuitBreakerThreshold
+	Threshold int
+	// time of open breaker before probe
+	// default (0) is DefaultCircuitBreakerCoolDown
+	CoolDown time.Duration
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker of stor, nil breaker lets every attempt through
+type circuitBreaker struct {
+	lock     sync.Mutex
+	clock    Clock
+	opts     CircuitBreakerOpts
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func newCircuitBreaker(clock Clock, opts *CircuitBreakerOpts) *circuitBreaker {
+	if opts == nil {
+		return nil
+	}
+
+	breaker := &circuitBreaker{clock: clock, opts: *opts}
+	if breaker.opts.Threshold <= 0 {
+		breaker.opts.Threshold = DefaultCircuitBreakerThreshold
+	}
+	if breaker.opts.CoolDown <= 0 {
+		breaker.opts.CoolDown = DefaultCircuitBreakerCoolDown
+	}
+
+	return breaker
+}
+
+// guard wrap attempt of download, attempt on stor (trySource is false when attempt is called)
+// fails fast if breaker is open and its result is recorded
+func (breaker *circuitBreaker) guard(trySource *bool, attempt func() error) func() error {
+	if breaker == nil {
+		return attempt
+	}
+
+	return func() error {
+		if *trySource {
+			return attempt()
+		}
+
+		if !breaker.allow() {
+			return ErrCircuitOpen
+		}
+
+		err := attempt()
+		breaker.record(err)
+
+		return err
+	}
+}
+
+// allow return false if attempt must fail fast,
+// after cool-down of open breaker only one attempt (probe) is allowed
+func (breaker *circuitBreaker) allow() bool {
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+
+	switch breaker.state {
+	case breakerOpen:
+		if since(breaker.clock, breaker.openedAt) < breaker.opts.CoolDown {
+			return false
+		}
+		breaker.state = breakerHalfOpen
+		log.Info("Circuit breaker of stor is half-open - probe")
+		return true
+	case breakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// record result of attempt allowed by breaker
+func (breaker *circuitBreaker) record(err error) {
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+
+	if !isMirrorFailure(err) {
+		if breaker.state != breakerClosed {
+			log.Info("Circuit breaker of stor is closed")
+		}
+		breaker.state = breakerClosed
+		breaker.failures = 0
+		return
+	}
+
+	breaker.failures++
+	if breaker.state == breakerHalfOpen || breaker.failures >= breaker.opts.Threshold {
+		if breaker.state != breakerOpen {
+			log.Warningf("Circuit breaker of stor is open for %s (%d consecutive failures)", breaker.opts.CoolDown, breaker.failures)
+		}
+		breaker.state = breakerOpen
+		breaker.openedAt = breaker.clock.Now()
+	}
+}
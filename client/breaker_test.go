@@ -0,0 +1,67 @@
+package storclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := &fakeClock{}
+	breaker := newCircuitBreaker(clock, &CircuitBreakerOpts{Threshold: 2, CoolDown: time.Minute})
+	unavailable := StatusError{StatusCode: 503, Status: "Service Unavailable"}
+
+	trySource := false
+	calls := 0
+	attempt := func(err error) func() error {
+		return breaker.guard(&trySource, func() error {
+			calls++
+			return err
+		})
+	}
+
+	assert.Equal(t, unavailable, attempt(unavailable)())
+	assert.NoError(t, attempt(nil)(), "success resets consecutive failures")
+	assert.Equal(t, unavailable, attempt(unavailable)())
+	assert.Equal(t, unavailable, attempt(unavailable)())
+	assert.Equal(t, 4, calls)
+
+	assert.Equal(t, ErrCircuitOpen, attempt(nil)(), "open breaker fails fast")
+	assert.Equal(t, 4, calls)
+
+	trySource = true
+	assert.NoError(t, attempt(nil)(), "source isn't guarded")
+	trySource = false
+
+	clock.Sleep(time.Minute)
+	assert.True(t, breaker.allow(), "probe after cool-down")
+	assert.False(t, breaker.allow(), "only one probe")
+	breaker.record(unavailable)
+	assert.Equal(t, ErrCircuitOpen, attempt(nil)(), "failed probe opens breaker again")
+
+	clock.Sleep(time.Minute)
+	calls = 0
+	assert.NoError(t, attempt(nil)())
+	assert.NoError(t, attempt(nil)(), "successful probe closes breaker")
+	assert.Equal(t, 2, calls)
+
+	assert.Nil(t, newCircuitBreaker(clock, nil))
+}
+
+func TestCircuitBreakerOfDownload(t *testing.T) {
+	httpClientTouch := 0
+	httpClient := func() httpClient {
+		httpClientTouch++
+		return &clientMock{statusCode: 500, status: "Something bad"}
+	}
+
+	opts := StorClientOpts{RetryAttempts: 10, Clock: &fakeClock{}, CircuitBreaker: &CircuitBreakerOpts{Threshold: 3}}
+	downloadWorkersTest(t, opts, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
+		assert.Equal(t, DOWN_FAIL, stat[0].Status)
+	})
+
+	assert.Equal(t, 3, httpClientTouch, "breaker trips after 3 failures, next attempt fails fast without retry")
+}
@@ -0,0 +1,307 @@
+package storclient
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultBundleManifest is name of manifest member of bundle
+const DefaultBundleManifest = "MANIFEST"
+
+// ErrBundle is cause of error of bundle with invalid manifest or with failed member
+var ErrBundle = errors.New("invalid bundle")
+
+// DownloadBundle add sha of bundle to download queue
+//
+// bundle is tar (optionally gzip compressed) or zip of many objects with manifest member
+// (DefaultBundleManifest) in sha256sum format - "<hash>  <member name>" per line.
+// After download each member listed in manifest is unpacked next to bundle as object of its hash
+// (same name as downloaded object, see Suffix, UpperCase and ShardDepth) and verified against its hash.
+// Result of each member (with DownloadResult.Bundle) goes to Results and ResultWriter only,
+// stats count bundle itself, which fails with ErrBundle if any member fails.
+//
+// bundle can't be downloaded with Devnull, DryRun, ArchiveWriter, EncryptionKey, ZipPassword or MetadataOnly
+func (client *StorClient) DownloadBundle(ctx context.Context, sha hashutil.Hash) error {
+	if client.Devnull || client.DryRun || client.archive != nil || client.aead != nil || client.ZipPassword != "" || client.MetadataOnly != nil {
+		return errors.New("bundle can't be downloaded with devnull, dry run, archive, encryption or metadata only")
+	}
+
+	return client.push(ctx, downloadJob{sha: sha, bundle: true})
+}
+
+// bundleMember is member of bundle listed in manifest
+type bundleMember struct {
+	sha  hashutil.Hash
+	name string
+}
+
+// bundleReader iterate over members of bundle, fn is called with content of each member
+type bundleReader func(fn func(name string, r io.Reader) error) error
+
+// openBundle return reader of tar, tar.gz or zip bundle (format by magic bytes)
+func openBundle(path string) (bundleReader, func() error, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	magic := make([]byte, 4)
+	n, _ := io.ReadFull(file, magic)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	if bytes.HasPrefix(magic[:n], []byte("PK\x03\x04")) {
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+
+		zipReader, err := zip.NewReader(file, info.Size())
+		if err != nil {
+			file.Close()
+			return nil, nil, errors.Wrapf(ErrBundle, "zip: %s", err)
+		}
+
+		return func(fn func(name string, r io.Reader) error) error {
+			for _, f := range zipReader.File {
+				if f.FileInfo().IsDir() {
+					continue
+				}
+
+				r, err := f.Open()
+				if err != nil {
+					return errors.Wrapf(ErrBundle, "zip member %s: %s", f.Name, err)
+				}
+				err = fn(f.Name, r)
+				r.Close()
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}, file.Close, nil
+	}
+
+	gzipped := bytes.HasPrefix(magic[:n], []byte{0x1f, 0x8b})
+	return func(fn func(name string, r io.Reader) error) error {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		var r io.Reader = bufio.NewReader(file)
+		if gzipped {
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return errors.Wrapf(ErrBundle, "gzip: %s", err)
+			}
+			defer gz.Close()
+			r = gz
+		}
+
+		tarReader := tar.NewReader(r)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return errors.Wrapf(ErrBundle, "tar: %s", err)
+			}
+
+			if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+				continue
+			}
+
+			if err := fn(header.Name, tarReader); err != nil {
+				return err
+			}
+		}
+	}, file.Close, nil
+}
+
+// parseBundleManifest parse manifest of bundle in sha256sum format ("<hash>  <name>", "*" binary mark is allowed)
+func parseBundleManifest(r io.Reader, algorithm HashAlgorithm) ([]bundleMember, error) {
+	members := make([]bundleMember, 0)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line, ok := manifestLine(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, errors.Wrapf(ErrBundle, "line %d of manifest isn't \"<hash>  <name>\"", n)
+		}
+
+		sha, err := ParseHashAlgorithm(fields[0], algorithm)
+		if err != nil {
+			return nil, errors.Wrapf(ErrBundle, "line %d of manifest: %s", n, err)
+		}
+
+		members = append(members, bundleMember{sha: sha, name: strings.TrimPrefix(fields[1], "*")})
+	}
+
+	return members, scanner.Err()
+}
+
+// unpackBundle unpack members of downloaded bundle to dir and send result of each member
+func (client *StorClient) unpackBundle(bundleSha hashutil.Hash, path, dir string) error {
+	each, closeBundle, err := openBundle(path)
+	if err != nil {
+		return err
+	}
+	defer closeBundle()
+
+	var members []bundleMember
+	err = each(func(name string, r io.Reader) error {
+		if name != DefaultBundleManifest || members != nil {
+			return nil
+		}
+
+		var parseErr error
+		members, parseErr = parseBundleManifest(r, client.HashAlgorithm)
+		return parseErr
+	})
+	if err != nil {
+		return err
+	}
+	if members == nil {
+		return errors.Wrapf(ErrBundle, "bundle %s has no %s", bundleSha, DefaultBundleManifest)
+	}
+
+	if err := client.decompression.checkFiles(len(members)); err != nil {
+		return err
+	}
+
+	byName := make(map[string]bundleMember, len(members))
+	for _, member := range members {
+		byName[member.name] = member
+	}
+
+	results := make(map[string]DownloadResult, len(members))
+	var remaining int64 = -1
+	if client.decompression.maxSize > 0 {
+		remaining = client.decompression.maxSize
+	}
+	err = each(func(name string, r io.Reader) error {
+		member, ok := byName[name]
+		if !ok {
+			if name != DefaultBundleManifest {
+				log.WithField("sha256", bundleSha.String()).Debugf("Member %s of bundle isn't in manifest - skip", name)
+			}
+			return nil
+		}
+		if _, done := results[name]; done {
+			return nil
+		}
+
+		if remaining >= 0 {
+			r = io.LimitReader(r, remaining+1)
+		}
+
+		result := client.unpackBundleMember(bundleSha, member, r, dir)
+		results[name] = result
+
+		if remaining >= 0 {
+			if remaining -= result.Size; remaining < 0 {
+				return errors.Wrapf(ErrDecompressionBomb, "members of bundle %s exceed %d bytes", bundleSha, client.decompression.maxSize)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, member := range members {
+		result, ok := results[member.name]
+		if !ok {
+			result = DownloadResult{Sha: member.sha, Status: DOWN_FAIL, Bundle: bundleSha, Err: errors.Wrapf(ErrBundle, "member %s isn't in bundle", member.name)}
+		}
+		if result.Status == DOWN_FAIL {
+			failed++
+		}
+
+		client.sendResult(result)
+	}
+
+	if failed > 0 {
+		return errors.Wrapf(ErrBundle, "%d of %d members of bundle %s fail", failed, len(members), bundleSha)
+	}
+
+	return nil
+}
+
+// unpackBundleMember write verified content of member to its file in dir
+func (client *StorClient) unpackBundleMember(bundleSha hashutil.Hash, member bundleMember, r io.Reader, dir string) DownloadResult {
+	result := DownloadResult{Sha: member.sha, Bundle: bundleSha, Status: DOWN_FAIL}
+
+	path := filepath.Join(dir, filepath.FromSlash(client.filename(member.sha)))
+	result.Path = path
+
+	if _, err := os.Stat(path); err == nil {
+		result.Status = DOWN_SKIP
+		return result
+	}
+
+	if err := client.dirs.ensure(filepath.Dir(path)); err != nil {
+		result.Err = err
+		return result
+	}
+
+	v, err := newVerification(client.Verifier, member.sha)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	temp := fmt.Sprintf("%s.temp", path)
+	out, err := os.Create(temp)
+	if err != nil {
+		result.Err = errors.Wrapf(err, "Create of %s fail", temp)
+		return result
+	}
+
+	result.Size, err = io.Copy(io.MultiWriter(out, v.writer()), r)
+	if errClose := out.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = v.verify(member.sha)
+	}
+	if err == nil {
+		err = client.fileAttrs.apply(temp)
+	}
+	if err == nil {
+		err = os.Rename(temp, path)
+	}
+	if err != nil {
+		os.Remove(temp)
+		result.Err = errors.Wrapf(err, "Unpack of member %s fail", member.name)
+		return result
+	}
+
+	result.Status = DOWN_OK
+	return result
+}
@@ -0,0 +1,142 @@
+package storclient_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func tarBundle(t *testing.T, members map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	w := tar.NewWriter(buf)
+	for name, content := range members {
+		assert.NoError(t, w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := w.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func zipBundle(t *testing.T, members map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for name, content := range members {
+		f, err := w.Create(name)
+		assert.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func bytesHash(content []byte) hashutil.Hash {
+	sum := sha256.Sum256(content)
+	hash, _ := hashutil.BytesToHash(sha256.New(), sum[:])
+	return hash
+}
+
+func TestDownloadBundle(t *testing.T) {
+	manifest := sha256Of("first").String() + "  samples/first.bin\n" + sha256Of("second").String() + " *second.bin\n"
+
+	good := tarBundle(t, map[string]string{
+		"MANIFEST":          manifest,
+		"samples/first.bin": "first",
+		"second.bin":        "second",
+		"README":            "not in manifest",
+	})
+	zipped := zipBundle(t, map[string]string{
+		"MANIFEST":  sha256Of("third").String() + "  third.bin\n",
+		"third.bin": "third",
+	})
+	corrupted := tarBundle(t, map[string]string{
+		"MANIFEST":   sha256Of("fourth").String() + "  fourth.bin\n" + sha256Of("fifth").String() + "  fifth.bin\n",
+		"fourth.bin": "corrupted",
+	})
+
+	bundles := map[string][]byte{
+		bytesHash(good).String():      good,
+		bytesHash(zipped).String():    zipped,
+		bytesHash(corrupted).String(): corrupted,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := bundles[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+	storUrl, _ := url.Parse(server.URL)
+
+	tmpDir, err := ioutil.TempDir("", "bundle")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	client, err := storclient.New(*storUrl, tmpDir, storclient.StorClientOpts{ShardDepth: 1})
+	assert.NoError(t, err)
+	results := client.Results()
+
+	byBundle := make(map[string][]storclient.DownloadResult)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range results {
+			key := result.Bundle.String()
+			if result.Bundle.IsEmpty() {
+				key = result.Sha.String()
+			}
+			byBundle[key] = append(byBundle[key], result)
+		}
+	}()
+
+	client.Start()
+	for _, bundle := range [][]byte{good, zipped, corrupted} {
+		assert.NoError(t, client.DownloadBundle(context.Background(), bytesHash(bundle)))
+	}
+	total := client.Wait()
+	<-done
+
+	assert.Equal(t, 2, total.Count)
+
+	// good bundle, its two members and bundle itself
+	assert.Len(t, byBundle[bytesHash(good).String()], 3)
+	for _, content := range []string{"first", "second", "third"} {
+		sha := sha256Of(content).String()
+		data, err := ioutil.ReadFile(filepath.Join(tmpDir, sha[:2], sha))
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	}
+
+	statuses := make(map[string]storclient.DownloadStatus)
+	for _, result := range byBundle[bytesHash(corrupted).String()] {
+		statuses[result.Sha.String()] = result.Status
+	}
+	assert.Equal(t, map[string]storclient.DownloadStatus{
+		bytesHash(corrupted).String(): storclient.DOWN_FAIL,
+		sha256Of("fourth").String():   storclient.DOWN_FAIL,
+		sha256Of("fifth").String():    storclient.DOWN_FAIL,
+	}, statuses)
+
+	devnull, err := storclient.New(*storUrl, tmpDir, storclient.StorClientOpts{Devnull: true})
+	assert.NoError(t, err)
+	assert.Error(t, devnull.DownloadBundle(context.Background(), bytesHash(good)))
+}
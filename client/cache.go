@@ -0,0 +1,230 @@
+package storclient
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// CacheOpts is configuration of local content cache (see StorClientOpts.Cache)
+//
+// cache is directory of verified objects (<dir>/<sha>) shared between runs and processes,
+// sha found in cache is hard-linked (or copied) to download directory without network request
+// and each downloaded file is hard-linked (or copied) to cache; when cache exceeds MaxSize,
+// least recently used objects are evicted - downloaded files should be treated as read-only,
+// because hard-linked file shares content with cache
+type CacheOpts struct {
+	Dir string
+	// max size of cache in bytes
+	// default (0) means without limit
+	MaxSize int64
+}
+
+// cacheTempSuffix is suffix of files which are being added to cache
+const cacheTempSuffix = ".cachetmp"
+
+// contentCache is read-through cache of objects, size is approximate if cache is shared by processes
+type contentCache struct {
+	dir     string
+	maxSize int64
+
+	lock sync.Mutex
+	size int64
+}
+
+func newContentCache(opts CacheOpts) (*contentCache, error) {
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	cache := &contentCache{dir: opts.Dir, maxSize: opts.MaxSize}
+	entries, err := cache.entries()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		cache.size += entry.Size()
+	}
+
+	return cache, nil
+}
+
+func (cache *contentCache) path(sha hashutil.Hash) string {
+	return filepath.Join(cache.dir, sha.String())
+}
+
+// get place cached object to path (only check presence if devnull) with attrs,
+// returns size and true on cache hit; access time of hit is updated for LRU
+func (cache *contentCache) get(sha hashutil.Hash, path string, devnull bool, attrs fileAttrs) (int64, bool) {
+	cached := cache.path(sha)
+	info, err := os.Stat(cached)
+	if err != nil {
+		return 0, false
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(cached, now, now); err != nil {
+		// entry is evicted concurrently
+		return 0, false
+	}
+
+	if !devnull {
+		if err := linkOrCopy(cached, path, attrs); err != nil {
+			log.WithField("sha256", sha.String()).Warningf("Read of cache fail: %s", err)
+			return 0, false
+		}
+	}
+
+	return info.Size(), true
+}
+
+// put add downloaded file to cache and evict least recently used objects over max size
+func (cache *contentCache) put(sha hashutil.Hash, path string) {
+	cached := cache.path(sha)
+	if _, err := os.Stat(cached); err == nil {
+		return
+	}
+
+	if err := linkOrCopy(path, cached, fileAttrs{}); err != nil {
+		log.WithField("sha256", sha.String()).Warningf("Write to cache fail: %s", err)
+		return
+	}
+
+	info, err := os.Stat(cached)
+	if err != nil {
+		return
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.size += info.Size()
+	if cache.maxSize > 0 && cache.size > cache.maxSize {
+		cache.evict()
+	}
+}
+
+// evict remove least recently used objects until cache fits max size, caller must hold lock
+func (cache *contentCache) evict() {
+	entries, err := cache.entries()
+	if err != nil {
+		log.Warningf("Eviction of cache fail: %s", err)
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+
+	cache.size = 0
+	for _, entry := range entries {
+		cache.size += entry.Size()
+	}
+
+	for _, entry := range entries {
+		if cache.size <= cache.maxSize {
+			break
+		}
+
+		if err := os.Remove(filepath.Join(cache.dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			log.Warningf("Eviction of %s fail: %s", entry.Name(), err)
+			continue
+		}
+		cache.size -= entry.Size()
+	}
+}
+
+// entries return cached objects (without files being added)
+func (cache *contentCache) entries() ([]os.FileInfo, error) {
+	infos, err := ioutil.ReadDir(cache.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := infos[:0]
+	for _, info := range infos {
+		if info.Mode().IsRegular() && !strings.HasSuffix(info.Name(), cacheTempSuffix) {
+			entries = append(entries, info)
+		}
+	}
+
+	return entries, nil
+}
+
+// linkOrCopy hard-link src to dst (copy if link isn't possible e.g. across filesystems),
+// dst appears atomically; with attrs dst is always copy (attrs of src aren't changed)
+func linkOrCopy(src, dst string, attrs fileAttrs) error {
+	tmp := dst + cacheTempSuffix
+	_ = os.Remove(tmp)
+
+	if !attrs.empty() || os.Link(src, tmp) != nil {
+		if err := copyFile(src, tmp); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+
+	if err := attrs.apply(tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+func copyFile(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := out.Close(); err == nil {
+			err = errClose
+		}
+	}()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// sendCached send result of download taken from cache
+func (client *StorClient) sendCached(downloadedFilesStat chan<- DownStat, id int, seq int64, sha hashutil.Hash, path string, size int64, duration time.Duration, err error) {
+	if client.Devnull || client.archive != nil {
+		path = ""
+	}
+
+	logger := log.WithFields(log.Fields{
+		"worker": id,
+		"sha256": sha.String(),
+	})
+
+	if err != nil {
+		logger.Errorf("Error download %s from cache: %s", sha, err)
+		client.sendFailedDownload(downloadedFilesStat, DownloadResult{seq: seq, worker: id, Sha: sha, Path: path, Status: DOWN_FAIL, Err: err})
+		return
+	}
+
+	logger.Debugf("Downloaded %s from cache", sha)
+	client.addToIndex(sha)
+	client.postDownload(sha, path, size)
+	client.sendStat(downloadedFilesStat, DownloadResult{seq: seq, worker: id, Sha: sha, Path: path, Size: size, Duration: duration, Status: DOWN_OK, Cached: true})
+}
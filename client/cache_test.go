@@ -0,0 +1,107 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	sha := contentHash("popular")
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte("popular"))
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	cacheDir := filepath.Join(tmpDir, "cache")
+	serverURL, _ := url.Parse(server.URL)
+
+	download := func(dir string) DownloadResult {
+		assert.NoError(t, os.MkdirAll(dir, 0755))
+		client, err := New(*serverURL, dir, StorClientOpts{Max: 1, Cache: &CacheOpts{Dir: cacheDir}})
+		assert.NoError(t, err)
+
+		results := client.Results()
+		client.Start()
+		assert.NoError(t, client.Download(sha))
+
+		var result DownloadResult
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for r := range results {
+				result = r
+			}
+		}()
+
+		client.Wait()
+		<-done
+
+		return result
+	}
+
+	first := download(filepath.Join(tmpDir, "first"))
+	assert.Equal(t, DOWN_OK, first.Status)
+	assert.False(t, first.Cached)
+	assert.True(t, fileExists(filepath.Join(cacheDir, sha.String())), "downloaded file is added to cache")
+
+	second := download(filepath.Join(tmpDir, "second"))
+	assert.Equal(t, DOWN_OK, second.Status)
+	assert.True(t, second.Cached)
+	assert.Equal(t, int64(len("popular")), second.Size)
+	assert.Equal(t, 1, requests, "second download is served by cache")
+
+	content, err := ioutil.ReadFile(filepath.Join(tmpDir, "second", sha.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, "popular", string(content))
+}
+
+func TestCacheEviction(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	cache, err := newContentCache(CacheOpts{Dir: filepath.Join(tmpDir, "cache"), MaxSize: 10})
+	assert.NoError(t, err)
+
+	old, recent, newest := contentHash("old"), contentHash("recent"), contentHash("newest")
+	for i, sha := range []string{"old", "recent"} {
+		path := filepath.Join(tmpDir, sha)
+		assert.NoError(t, ioutil.WriteFile(path, []byte("0123"), 0644))
+		cache.put(contentHash(sha), path)
+
+		mtime := time.Now().Add(time.Duration(i-10) * time.Minute)
+		assert.NoError(t, os.Chtimes(cache.path(contentHash(sha)), mtime, mtime))
+	}
+
+	_, ok := cache.get(old, filepath.Join(tmpDir, "old.copy"), true, fileAttrs{})
+	assert.True(t, ok, "hit makes old entry recently used")
+
+	path := filepath.Join(tmpDir, "newest")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("0123"), 0644))
+	cache.put(newest, path)
+
+	assert.True(t, fileExists(cache.path(old)))
+	assert.False(t, fileExists(cache.path(recent)), "least recently used entry is evicted")
+	assert.True(t, fileExists(cache.path(newest)))
+	assert.Equal(t, int64(8), cache.size)
+}
+
+func TestCacheOpts(t *testing.T) {
+	_, err := New(url.URL{}, os.TempDir(), StorClientOpts{DryRun: true, Cache: &CacheOpts{Dir: os.TempDir()}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,183 @@
+package storclient
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChaosOpts configure fault injection to fetch path
+//
+// it's intended for testing of failure handling only, never use it in production
+type ChaosOpts struct {
+	// probability (0..1) of fault injection per request
+	Rate float64
+	// max artificial latency of request (random between 0 and Latency)
+	// 0 means latency isn't injected
+	Latency time.Duration
+	// response 5xx without touching the server
+	ServerError bool
+	// response body is cut before end
+	TruncateBody bool
+	// one byte of response body is changed
+	CorruptBody bool
+	// seed of random generator
+	// 0 means time based seed
+	Seed int64
+}
+
+type chaosFault int
+
+const (
+	chaosLatency chaosFault = iota
+	chaosServerError
+	chaosTruncateBody
+	chaosCorruptBody
+)
+
+type chaosRand struct {
+	lock sync.Mutex
+	rnd  *rand.Rand
+}
+
+func newChaosRand(seed int64) *chaosRand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &chaosRand{rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (r *chaosRand) Float64() float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.rnd.Float64()
+}
+
+func (r *chaosRand) Int63n(n int64) int64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.rnd.Int63n(n)
+}
+
+type chaosClient struct {
+	httpClient
+	opts  *ChaosOpts
+	rnd   *chaosRand
+	clock Clock
+}
+
+// fault return randomly selected fault (by Rate) from enabled faults
+func (c *chaosClient) fault() (chaosFault, bool) {
+	faults := make([]chaosFault, 0, 4)
+	if c.opts.Latency > 0 {
+		faults = append(faults, chaosLatency)
+	}
+	if c.opts.ServerError {
+		faults = append(faults, chaosServerError)
+	}
+	if c.opts.TruncateBody {
+		faults = append(faults, chaosTruncateBody)
+	}
+	if c.opts.CorruptBody {
+		faults = append(faults, chaosCorruptBody)
+	}
+
+	if len(faults) == 0 || c.rnd.Float64() >= c.opts.Rate {
+		return 0, false
+	}
+
+	return faults[c.rnd.Int63n(int64(len(faults)))], true
+}
+
+func (c *chaosClient) Get(url string) (*http.Response, error) {
+	fault, inject := c.fault()
+	if !inject {
+		return c.httpClient.Get(url)
+	}
+
+	switch fault {
+	case chaosLatency:
+		c.clock.Sleep(time.Duration(c.rnd.Int63n(int64(c.opts.Latency))))
+	case chaosServerError:
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Status:     "503 Service Unavailable (chaos)",
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}, nil
+	}
+
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return resp, err
+	}
+
+	switch fault {
+	case chaosTruncateBody:
+		var limit int64
+		if resp.ContentLength > 0 {
+			limit = c.rnd.Int63n(resp.ContentLength)
+		}
+		resp.Body = &truncatedBody{ReadCloser: resp.Body, limit: limit}
+	case chaosCorruptBody:
+		resp.Body = &corruptedBody{ReadCloser: resp.Body}
+	}
+
+	return resp, nil
+}
+
+func (c *chaosClient) unwrap() httpClient {
+	return c.httpClient
+}
+
+// truncatedBody return io.ErrUnexpectedEOF after limit bytes
+type truncatedBody struct {
+	io.ReadCloser
+	limit int64
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.limit <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	if int64(len(p)) > b.limit {
+		p = p[:b.limit]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.limit -= int64(n)
+
+	return n, err
+}
+
+// corruptedBody flip bits of first byte (or add one byte to empty body)
+type corruptedBody struct {
+	io.ReadCloser
+	corrupted bool
+}
+
+func (b *corruptedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.corrupted || len(p) == 0 {
+		return n, err
+	}
+
+	if n > 0 {
+		p[0] ^= 0xff
+		b.corrupted = true
+	} else if err == io.EOF {
+		p[0] = 0xff
+		b.corrupted = true
+		return 1, nil
+	}
+
+	return n, err
+}
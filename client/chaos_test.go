@@ -0,0 +1,47 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChaosClient(t *testing.T) {
+	mock := &clientMock{statusCode: 200, status: "Ok"}
+
+	t.Run("without fault", func(t *testing.T) {
+		c := &chaosClient{httpClient: mock, opts: &ChaosOpts{Rate: 1}, rnd: newChaosRand(1), clock: realClock{}}
+		_, err := downloadFileToDevnull(c, "http://blabla", emptyHash)
+		assert.NoError(t, err)
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		c := &chaosClient{httpClient: mock, opts: &ChaosOpts{Rate: 1, ServerError: true}, rnd: newChaosRand(1), clock: realClock{}}
+		_, err := downloadFileToDevnull(c, "http://blabla", emptyHash)
+		if assert.Error(t, err) {
+			assert.Equal(t, 503, err.(StatusError).StatusCode)
+		}
+	})
+
+	t.Run("truncated body", func(t *testing.T) {
+		c := &chaosClient{httpClient: mock, opts: &ChaosOpts{Rate: 1, TruncateBody: true}, rnd: newChaosRand(1), clock: realClock{}}
+		_, err := downloadFileToDevnull(c, "http://blabla", emptyHash)
+		assert.Error(t, err)
+	})
+
+	t.Run("corrupted body", func(t *testing.T) {
+		c := &chaosClient{httpClient: mock, opts: &ChaosOpts{Rate: 1, CorruptBody: true}, rnd: newChaosRand(1), clock: realClock{}}
+		resp, err := c.Get("http://blabla")
+		assert.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0xff}, body)
+	})
+
+	t.Run("zero rate", func(t *testing.T) {
+		c := &chaosClient{httpClient: mock, opts: &ChaosOpts{Rate: 0, ServerError: true}, rnd: newChaosRand(1), clock: realClock{}}
+		_, err := downloadFileToDevnull(c, "http://blabla", emptyHash)
+		assert.NoError(t, err)
+	})
+}
@@ -30,3 +30,19 @@ func TestNewInfinityTimeout(t *testing.T) {
 	expectedTimeout, _ := time.ParseDuration("0s")
 	assert.Equal(t, client.Timeout, expectedTimeout)
 }
+
+func TestTotalStatMerge(t *testing.T) {
+	a := storclient.TotalStat{Size: 10, Duration: time.Second, Count: 1, Skip: 2, Metadata: 1}
+	b := storclient.TotalStat{Size: 20, Duration: 2 * time.Second, Count: 3, Metadata: 2}
+	c := storclient.TotalStat{Size: 5, Skip: 1}
+
+	merged := a.Merge(b)
+	assert.Equal(t, int64(30), merged.Size)
+	assert.Equal(t, 3*time.Second, merged.Duration)
+	assert.Equal(t, 4, merged.Count)
+	assert.Equal(t, 2, merged.Skip)
+	assert.Equal(t, 3, merged.Metadata)
+
+	assert.Equal(t, a.Merge(b).Merge(c), a.Merge(b.Merge(c)), "merge is associative")
+	assert.Equal(t, a, a.Merge(storclient.TotalStat{}), "empty TotalStat is neutral")
+}
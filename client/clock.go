@@ -0,0 +1,31 @@
+package storclient
+
+import "time"
+
+// Clock is source of time used by client (durations, retry delays, timers)
+//
+// default is real (wall) clock, custom implementation can be injected
+// via StorClientOpts.Clock e.g. for deterministic tests without real sleeps
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func since(clock Clock, t time.Time) time.Duration {
+	return clock.Now().Sub(t)
+}
@@ -0,0 +1,62 @@
+package storclient
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock never sleeps, only moves time forward
+type fakeClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+	c.sleeps = append(c.sleeps, d)
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.Sleep(d)
+
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+
+func TestRetryDelayViaClock(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)}
+
+	httpClient := func() httpClient { return &clientMock{statusCode: 500, status: "Internal server error"} }
+	retries := make([]uint, 0)
+	onRetry := func(sha hashutil.Hash, attempt uint, err error, nextDelay time.Duration) {
+		assert.True(t, sha.Equal(emptyHash))
+		assert.Error(t, err)
+		assert.Equal(t, time.Second<<(attempt-1), nextDelay)
+		retries = append(retries, attempt)
+	}
+
+	opts := StorClientOpts{RetryAttempts: 4, RetryDelay: time.Second, Clock: clock, OnRetry: onRetry}
+	downloadWorkersTest(t, opts, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
+		assert.Equal(t, DOWN_FAIL, stat[0].Status)
+	})
+
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, clock.sleeps)
+	assert.Equal(t, []uint{1, 2, 3}, retries)
+}
@@ -0,0 +1,60 @@
+package storclient
+
+import "sync"
+
+// CompletionFunc is called with result of each download (or upload) - see StorClientOpts.OnComplete
+type CompletionFunc func(result DownloadResult)
+
+// orderedCompletion call callback with results in submission order,
+// results completed out of order are buffered until all previous results are done
+//
+// jobs which weren't queued (push fails) are skipped
+type orderedCompletion struct {
+	lock     sync.Mutex
+	next     int64
+	pending  map[int64]*DownloadResult
+	callback CompletionFunc
+}
+
+func newOrderedCompletion(callback CompletionFunc) *orderedCompletion {
+	return &orderedCompletion{pending: make(map[int64]*DownloadResult), callback: callback}
+}
+
+// done add result of job seq and call callback with all results which are in order
+func (o *orderedCompletion) done(seq int64, result DownloadResult) {
+	o.add(seq, &result)
+}
+
+// skip mark job seq as never queued
+func (o *orderedCompletion) skip(seq int64) {
+	o.add(seq, nil)
+}
+
+func (o *orderedCompletion) add(seq int64, result *DownloadResult) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.pending[seq] = result
+	for {
+		result, ok := o.pending[o.next]
+		if !ok {
+			return
+		}
+
+		delete(o.pending, o.next)
+		o.next++
+		if result != nil {
+			o.callback(*result)
+		}
+	}
+}
+
+// complete call OnComplete callback with result (in submission order if OrderedCompletion is set)
+func (client *StorClient) complete(result DownloadResult) {
+	switch {
+	case client.ordered != nil:
+		client.ordered.done(result.seq, result)
+	case client.OnComplete != nil:
+		client.OnComplete(result)
+	}
+}
@@ -0,0 +1,83 @@
+package storclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedCompletion(t *testing.T) {
+	contents := map[string]string{}
+	var shas []hashutil.Hash
+	for i := 0; i < 8; i++ {
+		content := fmt.Sprintf("content %d", i)
+		sha := contentHash(content)
+		contents[sha.String()] = content
+		shas = append(shas, sha)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content := contents[path.Base(r.URL.Path)]
+		// earlier submitted files complete later
+		i, _ := strconv.Atoi(content[len("content "):])
+		time.Sleep(time.Duration(8-i) * 5 * time.Millisecond)
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	var completed []hashutil.Hash
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{
+		Max:               4,
+		Devnull:           true,
+		OrderedCompletion: true,
+		OnComplete: func(result DownloadResult) {
+			completed = append(completed, result.Sha)
+		},
+	})
+	assert.NoError(t, err)
+
+	client.Start()
+	for _, sha := range shas {
+		assert.NoError(t, client.Download(sha))
+	}
+	total := client.Wait()
+
+	assert.Equal(t, 8, total.Count)
+	assert.Equal(t, shas, completed, "completion in submission order")
+}
+
+func TestOrderedCompletionSkip(t *testing.T) {
+	var completed []int64
+	ordered := newOrderedCompletion(func(result DownloadResult) {
+		completed = append(completed, result.seq)
+	})
+
+	ordered.done(2, DownloadResult{seq: 2})
+	ordered.done(0, DownloadResult{seq: 0})
+	assert.Equal(t, []int64{0}, completed)
+
+	ordered.skip(1)
+	assert.Equal(t, []int64{0, 2}, completed, "dropped job doesn't block next results")
+
+	var dropped []int64
+	queue := newDownloadQueue(0)
+	queue.onDrop = func(seq int64) {
+		dropped = append(dropped, seq)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, queue.push(ctx, downloadJob{}))
+	assert.Error(t, queue.push(ctx, downloadJob{}))
+	assert.Equal(t, []int64{0, 1}, dropped, "job which isn't queued is dropped")
+}
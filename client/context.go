@@ -0,0 +1,123 @@
+package storclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/avast/hashutil-go"
+)
+
+// StartCtx start stor downloading process bound to ctx
+//
+// cancel of ctx aborts in-flight requests and all queued downloads fail
+func (client *StorClient) StartCtx(ctx context.Context) {
+	// context created by New is replaced (nothing is bound to it before start)
+	client.cancel()
+	client.ctx, client.cancel = context.WithCancel(ctx)
+
+	client.Start()
+}
+
+// DownloadCtx add sha to download queue
+//
+// ctx bounds waiting for free place in (full) queue, ctx.Err() is returned if ctx is done before sha is queued;
+// cancel of ctx after that aborts queued or in-flight download of sha (it fails with ctx.Err())
+func (client *StorClient) DownloadCtx(ctx context.Context, sha hashutil.Hash) error {
+	return client.push(ctx, downloadJob{sha: sha})
+}
+
+// WaitCtx wait to all downloads like Wait
+//
+// if ctx is done before all downloads end, in-flight downloads are aborted,
+// rest of queue fails and ctx.Err() is returned with stats of processed downloads
+func (client *StorClient) WaitCtx(ctx context.Context) (TotalStat, error) {
+	done := make(chan TotalStat, 1)
+	go func() {
+		done <- client.Wait()
+	}()
+
+	select {
+	case total := <-done:
+		return total, nil
+	case <-ctx.Done():
+		client.cancel()
+		return <-done, ctx.Err()
+	}
+}
+
+// mergeContext return context which is done when parent or ctx is done,
+// cancel must be called to release it
+func mergeContext(parent, ctx context.Context) (context.Context, context.CancelFunc) {
+	merged, cancel := context.WithCancel(parent)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-merged.Done():
+		}
+	}()
+
+	return merged, cancel
+}
+
+// setRequestContext bind requests made by (wrapped) contextClient to ctx, returns false if isn't possible
+func setRequestContext(httpClient httpClient, ctx context.Context) bool {
+	c := findContextClient(httpClient)
+	if c == nil {
+		return false
+	}
+
+	c.ctx = ctx
+
+	return true
+}
+
+// contextClient is httpClient with requests bound to context
+type contextClient struct {
+	*http.Client
+	ctx    context.Context
+	header http.Header
+	// verifier of downloaded content, nil means HashVerifier
+	verifier VerifierFactory
+	// Get sends POST with body (batch request, see setRequestBody), so wrappers
+	// of response (throttling, metering etc.) are applied as to GET
+	body []byte
+}
+
+func (c *contextClient) Get(url string) (*http.Response, error) {
+	method, body := http.MethodGet, io.Reader(nil)
+	if c.body != nil {
+		method, body = http.MethodPost, bytes.NewReader(c.body)
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+
+	return c.Do(req)
+}
+
+// Head issue HEAD request with context and headers of client
+func (c *contextClient) Head(url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+
+	return c.Do(req)
+}
+
+func (c *contextClient) unwrap() httpClient {
+	return c.Client
+}
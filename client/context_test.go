@@ -0,0 +1,146 @@
+package storclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitCtxCancel(t *testing.T) {
+	// server hangs until request is aborted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "", storclient.StorClientOpts{Devnull: true, Max: 1})
+	assert.NoError(t, err)
+
+	client.StartCtx(context.Background())
+	for _, sha := range []hashutil.Hash{sha256Of("first"), sha256Of("second")} {
+		assert.NoError(t, client.DownloadCtx(context.Background(), sha))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	startTime := time.Now()
+	total, err := client.WaitCtx(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.WithinDuration(t, startTime, time.Now(), 5*time.Second)
+	assert.Equal(t, 2, total.Failed())
+}
+
+func TestDownloadCtxCancelInFlight(t *testing.T) {
+	var requests int32
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+sha256Of("first").String() {
+			w.Write([]byte("second"))
+			return
+		}
+		// first hangs until request is aborted
+		atomic.AddInt32(&requests, 1)
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "", storclient.StorClientOpts{Devnull: true, Max: 1, RetryAttempts: 3})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client.Start()
+	assert.NoError(t, client.DownloadCtx(ctx, sha256Of("first")))
+	assert.NoError(t, client.Download(sha256Of("second")))
+
+	<-started
+	cancel()
+
+	total := client.Wait()
+	assert.Equal(t, 1, total.Failed(), "canceled download fails")
+	assert.Equal(t, 1, total.Count, "other downloads continue")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "canceled download isn't retried")
+}
+
+func TestDownloadCtxFullQueue(t *testing.T) {
+	client, err := storclient.New(url.URL{}, "", storclient.StorClientOpts{})
+	assert.NoError(t, err)
+
+	// client isn't started, so queue is full after 1024 shas
+	for i := 0; i < 1024; i++ {
+		assert.NoError(t, client.Download(sha256Of("sample")))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, client.DownloadCtx(ctx, sha256Of("sample")))
+}
+
+func TestStop(t *testing.T) {
+	// server hangs until request is aborted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "", storclient.StorClientOpts{Devnull: true, Max: 1})
+	assert.NoError(t, err)
+
+	client.Start()
+	for _, sha := range []hashutil.Hash{sha256Of("first"), sha256Of("second"), sha256Of("third")} {
+		assert.NoError(t, client.Download(sha))
+	}
+
+	startTime := time.Now()
+	total := client.Stop(false)
+	assert.WithinDuration(t, startTime, time.Now(), 5*time.Second)
+	assert.Equal(t, 3, total.Failed())
+	assert.Equal(t, 3, total.Abandoned)
+	assert.Equal(t, storclient.ErrQueueClosed, client.Download(sha256Of("fourth")))
+}
+
+func TestStopDrain(t *testing.T) {
+	objects := make(map[string]string)
+	shas := make([]hashutil.Hash, 0)
+	for _, content := range []string{"first", "second", "third"} {
+		objects[sha256Of(content).String()] = content
+		shas = append(shas, sha256Of(content))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(objects[strings.TrimPrefix(r.URL.Path, "/")]))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "", storclient.StorClientOpts{Devnull: true, Max: 1})
+	assert.NoError(t, err)
+
+	client.Start()
+	for _, sha := range shas {
+		assert.NoError(t, client.Download(sha))
+	}
+
+	total := client.Stop(true)
+	assert.True(t, total.Status(), "queued downloads are finished")
+	assert.Equal(t, 3, total.Count)
+	assert.Equal(t, 0, total.Abandoned)
+	assert.Equal(t, 3, total.Workers[0].Count, "all downloads are done by one worker")
+	assert.Equal(t, storclient.ErrQueueClosed, client.Download(sha256Of("fourth")))
+}
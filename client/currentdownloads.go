@@ -6,6 +6,23 @@ import (
 	"github.com/avast/hashutil-go"
 )
 
+// DedupSet is set of shas which are downloading now - sha in set isn't downloaded by other worker
+// (see StorClientOpts.DedupSet), implementation must be safe for concurrent use
+//
+// shared implementation (e.g. backed by Redis) can coordinate dedup across multiple client processes,
+// it should handle errors of store itself (ContainsOrAdd returning true means download anyway)
+type DedupSet interface {
+	// ContainsOrAdd add hash to set, returns true if hash was added (isn't downloading now)
+	ContainsOrAdd(hash hashutil.Hash) bool
+	// Del remove hash from set at end of download
+	Del(hash hashutil.Hash)
+}
+
+// NewMemoryDedupSet return in-memory DedupSet of one process (default)
+func NewMemoryDedupSet() DedupSet {
+	return &currentDownloads{}
+}
+
 type currentDownloads struct {
 	lock    sync.RWMutex
 	hashmap map[string]interface{}
@@ -2,6 +2,11 @@ package storclient
 
 import (
 	"crypto/md5"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
 	"testing"
 
 	"github.com/avast/hashutil-go"
@@ -19,3 +24,43 @@ func TestCurrentDownloads(t *testing.T) {
 	assert.True(t, cur.ContainsOrAdd(hash))
 	assert.False(t, cur.ContainsOrAdd(hash))
 }
+
+// sharedDedupSet simulates set shared with other process which downloads busy shas
+type sharedDedupSet struct {
+	DedupSet
+	busy map[string]bool
+}
+
+func (set sharedDedupSet) ContainsOrAdd(hash hashutil.Hash) bool {
+	if set.busy[hash.String()] {
+		return false
+	}
+
+	return set.DedupSet.ContainsOrAdd(hash)
+}
+
+func TestCustomDedupSet(t *testing.T) {
+	free, busy := contentHash("free"), contentHash("busy")
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, path.Base(r.URL.Path))
+		_, _ = w.Write([]byte("free"))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	set := sharedDedupSet{DedupSet: NewMemoryDedupSet(), busy: map[string]bool{busy.String(): true}}
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{Max: 1, Devnull: true, DedupSet: set})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(free))
+	assert.NoError(t, client.Download(busy))
+	total := client.Wait()
+
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, 1, total.Skip, "sha downloading in other process is skipped")
+	assert.Equal(t, []string{free.String()}, requests)
+	assert.True(t, set.ContainsOrAdd(free), "sha is deleted from set after download")
+}
@@ -0,0 +1,70 @@
+package storclient
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/avast/hashutil-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// deadLetterQueue keeps shas of downloads which failed after all retries
+//
+// queue is bounded (oldest shas are dropped), every sha is also written
+// to writer (if is set) as one hex sha per line - same format as input of stor-client cli
+type deadLetterQueue struct {
+	lock     sync.Mutex
+	writer   io.Writer
+	capacity int
+	shas     []hashutil.Hash
+	dropped  int
+}
+
+func (q *deadLetterQueue) add(sha hashutil.Hash) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.writer != nil {
+		if _, err := fmt.Fprintln(q.writer, sha); err != nil {
+			log.WithField("sha256", sha.String()).Warningf("Write to dead letter fail: %s", err)
+		}
+	}
+
+	if q.capacity <= 0 {
+		return
+	}
+
+	if len(q.shas) >= q.capacity {
+		q.shas = q.shas[1:]
+		q.dropped++
+	}
+	q.shas = append(q.shas, sha)
+}
+
+// DeadLetters return shas of downloads which failed after all retries
+// (at most DeadLetterCapacity of last ones) and count of dropped (older) shas
+func (client *StorClient) DeadLetters() ([]hashutil.Hash, int) {
+	client.deadLetters.lock.Lock()
+	defer client.deadLetters.lock.Unlock()
+
+	return append([]hashutil.Hash(nil), client.deadLetters.shas...), client.deadLetters.dropped
+}
+
+// WriteDeadLetters export dead letters (see DeadLetters) as one hex sha per line
+func (client *StorClient) WriteDeadLetters(w io.Writer) error {
+	shas, _ := client.DeadLetters()
+	for _, sha := range shas {
+		if _, err := fmt.Fprintln(w, sha); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sendFailedDownload add sha to dead letters and send result of failed download
+func (client *StorClient) sendFailedDownload(downloadedFilesStat chan<- DownStat, result DownloadResult) {
+	client.deadLetters.add(result.Sha)
+	client.sendStat(downloadedFilesStat, result)
+}
@@ -0,0 +1,57 @@
+package storclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetterQueue(t *testing.T) {
+	var buf bytes.Buffer
+	queue := deadLetterQueue{writer: &buf, capacity: 2}
+
+	shas := make([]hashutil.Hash, 3)
+	for i, content := range []string{"a", "b", "c"} {
+		sum := sha256.Sum256([]byte(content))
+		shas[i], _ = hashutil.BytesToHash(sha256.New(), sum[:])
+		queue.add(shas[i])
+	}
+
+	assert.Equal(t, shas[1:], queue.shas, "queue is bounded")
+	assert.Equal(t, 1, queue.dropped)
+	assert.Equal(t, shas[0].String()+"\n"+shas[1].String()+"\n"+shas[2].String()+"\n", buf.String(), "writer gets all shas")
+
+	unbounded := deadLetterQueue{capacity: -1}
+	unbounded.add(shas[0])
+	assert.Empty(t, unbounded.shas, "without keeping")
+}
+
+func TestDeadLetters(t *testing.T) {
+	var buf bytes.Buffer
+	httpClient := func() httpClient { return &clientMock{statusCode: 404, status: "Not found"} }
+	downloadWorkersTest(t, StorClientOpts{DeadLetterWriter: &buf}, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
+		assert.Equal(t, DOWN_FAIL, stat[0].Status)
+	})
+	assert.Equal(t, emptyHash.String()+"\n", buf.String())
+
+	client, err := New(url.URL{}, "", StorClientOpts{})
+	assert.NoError(t, err)
+	client.deadLetters.add(emptyHash)
+
+	shas, dropped := client.DeadLetters()
+	assert.Equal(t, []hashutil.Hash{emptyHash}, shas)
+	assert.Equal(t, 0, dropped)
+
+	var exported strings.Builder
+	assert.NoError(t, client.WriteDeadLetters(&exported))
+
+	parsed, err := ParseHash(strings.TrimSpace(exported.String()))
+	assert.NoError(t, err, "export is re-consumable")
+	assert.True(t, parsed.Equal(emptyHash))
+}
@@ -0,0 +1,33 @@
+package storclient
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WaitDeadline wait to all downloads like Wait, but at most until deadline (by Clock)
+//
+// once deadline passes, in-flight downloads are aborted and rest of queue fails
+// (counted in TotalStat.Abandoned), context.DeadlineExceeded is returned with stats of processed downloads
+func (client *StorClient) WaitDeadline(deadline time.Time) (TotalStat, error) {
+	done := make(chan TotalStat, 1)
+	go func() {
+		done <- client.Wait()
+	}()
+
+	select {
+	case total := <-done:
+		return total, nil
+	case <-client.Clock.After(deadline.Sub(client.Clock.Now())):
+		log.Warningf("Deadline %s passed - abort downloads", deadline.Format(time.RFC3339))
+		client.cancel()
+		return <-done, context.DeadlineExceeded
+	}
+}
+
+// abandoned return true if failed result is caused by abort of client (see WaitCtx, WaitDeadline)
+func (client *StorClient) abandoned(result DownloadResult) bool {
+	return result.Status == DOWN_FAIL && client.ctx.Err() != nil
+}
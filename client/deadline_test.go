@@ -0,0 +1,56 @@
+package storclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitDeadline(t *testing.T) {
+	// server hangs until request is aborted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "", storclient.StorClientOpts{Devnull: true, Max: 1})
+	assert.NoError(t, err)
+
+	client.Start()
+	for _, sha := range []hashutil.Hash{sha256Of("first"), sha256Of("second"), sha256Of("third")} {
+		assert.NoError(t, client.Download(sha))
+	}
+
+	startTime := time.Now()
+	total, err := client.WaitDeadline(startTime.Add(100 * time.Millisecond))
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.WithinDuration(t, startTime, time.Now(), 5*time.Second)
+	assert.Equal(t, 3, total.Failed())
+	assert.Equal(t, 3, total.Abandoned, "in-flight and queued downloads are abandoned")
+	assert.False(t, total.Status())
+}
+
+func TestWaitDeadlineFinished(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "", storclient.StorClientOpts{Devnull: true})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(sha256Of("")))
+
+	total, err := client.WaitDeadline(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, total.Status())
+	assert.Equal(t, 0, total.Abandoned)
+}
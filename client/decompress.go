@@ -0,0 +1,118 @@
+package storclient
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Decoder return reader of decoded content of r (see StorClientOpts.Decoders)
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+// built-in decoders of content encodings
+var defaultDecoders = map[string]Decoder{
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": func(r io.Reader) (io.ReadCloser, error) {
+		return zlib.NewReader(r)
+	},
+}
+
+// decompressTransport negotiate compression of responses (Accept-Encoding) and decode responses
+// by Content-Encoding, so hash is verified on decoded content
+//
+// ranged requests (resume) ask for identity encoding, because offset is offset in decoded content
+type decompressTransport struct {
+	next           http.RoundTripper
+	decoders       map[string]Decoder
+	acceptEncoding string
+}
+
+func newDecompressTransport(next http.RoundTripper, decoders map[string]Decoder) *decompressTransport {
+	all := make(map[string]Decoder, len(defaultDecoders)+len(decoders))
+	for encoding, decoder := range defaultDecoders {
+		all[encoding] = decoder
+	}
+	for encoding, decoder := range decoders {
+		all[strings.ToLower(encoding)] = decoder
+	}
+
+	encodings := make([]string, 0, len(all))
+	for encoding := range all {
+		encodings = append(encodings, encoding)
+	}
+	sort.Strings(encodings)
+
+	return &decompressTransport{next: next, decoders: all, acceptEncoding: strings.Join(encodings, ", ")}
+}
+
+func (t *decompressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req = req.Clone(req.Context())
+		if req.Header.Get("Range") != "" {
+			req.Header.Set("Accept-Encoding", "identity")
+		} else {
+			req.Header.Set("Accept-Encoding", t.acceptEncoding)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" || req.Method == http.MethodHead {
+		return resp, nil
+	}
+
+	decoder, ok := t.decoders[encoding]
+	if !ok {
+		resp.Body.Close()
+		return nil, errors.Errorf("unsupported Content-Encoding %q of %s", encoding, req.URL)
+	}
+
+	if resp.StatusCode == http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, errors.Errorf("partial response of %s is encoded by %s and can't be resumed", req.URL, encoding)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	decoded, err := decoder(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, errors.Wrapf(err, "Decoding of %s response of %s fail", encoding, req.URL)
+	}
+
+	resp.Body = &decodedBody{ReadCloser: decoded, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return resp, nil
+}
+
+// decodedBody close decoder and raw body of response
+type decodedBody struct {
+	io.ReadCloser
+	raw io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if rawErr := b.raw.Close(); err == nil {
+		err = rawErr
+	}
+
+	return err
+}
@@ -0,0 +1,109 @@
+package storclient_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// upperDecoder is toy content encoding (content is sent in upper case)
+func upperDecoder(r io.Reader) (io.ReadCloser, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(bytes.ToLower(content))), nil
+}
+
+func TestDecompress(t *testing.T) {
+	content := strings.Repeat("compressed content ", 100)
+
+	var acceptEncodings []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncodings = append(acceptEncodings, r.Header.Get("Accept-Encoding"))
+		switch {
+		case r.Header.Get("Range") != "":
+			http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+		case strings.Contains(r.Header.Get("Accept-Encoding"), "upper"):
+			w.Header().Set("Content-Encoding", "upper")
+			_, _ = w.Write([]byte(strings.ToUpper(content)))
+		default:
+			// compressed even if client doesn't ask for it
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			_, _ = gz.Write([]byte(content))
+			_ = gz.Close()
+		}
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "decompress")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	serverURL, _ := url.Parse(server.URL)
+	download := func(opts storclient.StorClientOpts) storclient.TotalStat {
+		opts.RetryAttempts = 1
+		client, err := storclient.New(*serverURL, tmpDir, opts)
+		assert.NoError(t, err)
+
+		client.Start()
+		assert.NoError(t, client.Download(sha256Of(content)))
+		return client.Wait()
+	}
+	target := filepath.Join(tmpDir, sha256Of(content).String())
+
+	acceptEncodings = nil
+	assert.Equal(t, 1, download(storclient.StorClientOpts{Decompress: true}).Count)
+	assert.Equal(t, []string{"deflate, gzip"}, acceptEncodings)
+	downloaded, err := ioutil.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(downloaded))
+	assert.NoError(t, os.Remove(target))
+
+	acceptEncodings = nil
+	assert.Equal(t, 1, download(storclient.StorClientOpts{Decompress: true, Decoders: map[string]storclient.Decoder{"upper": upperDecoder}}).Count)
+	assert.Equal(t, []string{"deflate, gzip, upper"}, acceptEncodings, "custom decoder is negotiated")
+	assert.NoError(t, os.Remove(target))
+
+	// resume of decoded temp file asks for identity encoding
+	assert.NoError(t, ioutil.WriteFile(target+".temp", []byte(content[:100]), 0644))
+	acceptEncodings = nil
+	assert.Equal(t, 1, download(storclient.StorClientOpts{Decompress: true}).Count)
+	assert.Equal(t, []string{"identity"}, acceptEncodings)
+	downloaded, err = ioutil.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(downloaded))
+}
+
+func TestDecompressBomb(t *testing.T) {
+	content := strings.Repeat("0", 10000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		_, _ = gz.Write([]byte(content))
+		_ = gz.Close()
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, os.TempDir(), storclient.StorClientOpts{Devnull: true, Decompress: true, MaxDecompressedSize: 1000})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(sha256Of(content)))
+	assert.Equal(t, 1, client.Wait().Failed(), "decoded content is limited")
+}
@@ -0,0 +1,65 @@
+package storclient
+
+import (
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxConcurrentMkdir is count of concurrent directory creations if StorClientOpts.MaxConcurrentMkdir isn't set
+const DefaultMaxConcurrentMkdir = 4
+
+// dirCache create directories of downloaded files (e.g. of sharded layout) only once
+// and bounds count of concurrent MkdirAll calls (mkdir storms on NFS are expensive)
+//
+// created directories must not be removed while client is running
+type dirCache struct {
+	lock sync.Mutex
+	dirs map[string]*dirCreation
+	sem  chan struct{}
+}
+
+// dirCreation is (pending or finished) creation of one directory
+type dirCreation struct {
+	done chan struct{}
+	err  error
+}
+
+func newDirCache(maxConcurrent int) *dirCache {
+	if maxConcurrent <= 0 {
+		maxConcurrent = DefaultMaxConcurrentMkdir
+	}
+
+	return &dirCache{dirs: make(map[string]*dirCreation), sem: make(chan struct{}, maxConcurrent)}
+}
+
+// ensure create dir (with parents) if it wasn't created yet,
+// concurrent callers of same dir wait to one creation, failed creation is tried again by next call
+func (cache *dirCache) ensure(dir string) error {
+	cache.lock.Lock()
+	if creation, ok := cache.dirs[dir]; ok {
+		cache.lock.Unlock()
+		<-creation.done
+		return creation.err
+	}
+
+	creation := &dirCreation{done: make(chan struct{})}
+	cache.dirs[dir] = creation
+	cache.lock.Unlock()
+
+	cache.sem <- struct{}{}
+	creation.err = os.MkdirAll(dir, 0755)
+	<-cache.sem
+
+	if creation.err != nil {
+		creation.err = errors.Wrapf(creation.err, "Create of directory %s fail", dir)
+
+		cache.lock.Lock()
+		delete(cache.dirs, dir)
+		cache.lock.Unlock()
+	}
+	close(creation.done)
+
+	return creation.err
+}
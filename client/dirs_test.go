@@ -0,0 +1,52 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirCache(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "dirs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	cache := newDirCache(2)
+
+	dir := filepath.Join(tmpDir, "a", "b")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, cache.ensure(dir))
+		}()
+	}
+	wg.Wait()
+	assert.DirExists(t, dir)
+	assert.Len(t, cache.dirs, 1)
+
+	// file in place of directory
+	blocked := filepath.Join(tmpDir, "blocked")
+	assert.NoError(t, ioutil.WriteFile(blocked, nil, 0644))
+	assert.Error(t, cache.ensure(filepath.Join(blocked, "c")))
+
+	assert.NoError(t, os.Remove(blocked))
+	assert.NoError(t, cache.ensure(filepath.Join(blocked, "c")), "failed creation isn't cached")
+	assert.DirExists(t, filepath.Join(blocked, "c"))
+}
+
+func TestDownloadToSubdirectory(t *testing.T) {
+	httpClient := func() httpClient { return &clientMock{statusCode: 200, status: "OK"} }
+
+	downloadWorkersTest(t, StorClientOpts{Suffix: "/sample"}, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
+		assert.Equal(t, DOWN_OK, stat[0].Status)
+		assert.FileExists(t, filepath.Join(tempdir.Canonpath(), emptyHash.String(), "sample"))
+	})
+}
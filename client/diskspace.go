@@ -0,0 +1,118 @@
+package storclient
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrInsufficientSpace is cause of error of download which would leave less than StorClientOpts.MinFreeSpace
+// on filesystem of download dir, it isn't retried
+var ErrInsufficientSpace = errors.New("insufficient free disk space")
+
+// DefaultFreeSpaceInterval is period of re-check of free space of paused download (see StorClientOpts.WaitForFreeSpace)
+const DefaultFreeSpaceInterval = 10 * time.Second
+
+// diskGuard keeps min free space on filesystem of downloaded files
+type diskGuard struct {
+	min   int64
+	wait  bool
+	clock Clock
+	// free return available bytes of filesystem of path (for unprivileged user)
+	free func(path string) (int64, error)
+}
+
+func newDiskGuard(clock Clock, min int64, wait bool) *diskGuard {
+	if min <= 0 {
+		return nil
+	}
+
+	return &diskGuard{min: min, wait: wait, clock: clock, free: freeSpace}
+}
+
+// check return ErrInsufficientSpace if free space of filesystem of dir without size is under min
+func (guard *diskGuard) check(dir string, size int64) error {
+	free, err := guard.free(dir)
+	if err != nil {
+		return errors.Wrapf(err, "Check of free space of %s fail", dir)
+	}
+
+	if free-size < guard.min {
+		return errors.Wrapf(ErrInsufficientSpace, "%d bytes free on %s, %d bytes needed and %d bytes kept", free, dir, size, guard.min)
+	}
+
+	return nil
+}
+
+// await check free space of dir before download, if wait is set,
+// download is paused (checked every DefaultFreeSpaceInterval) until space is freed or ctx is canceled
+func (guard *diskGuard) await(ctx context.Context, dir string) error {
+	if guard == nil {
+		return nil
+	}
+
+	paused := false
+	for {
+		err := guard.check(dir, 0)
+		if errors.Cause(err) != ErrInsufficientSpace || !guard.wait {
+			if paused && err == nil {
+				log.Infof("Free space on %s is available - download resumed", dir)
+			}
+			return err
+		}
+
+		if !paused {
+			log.Warningf("%s - download paused until space is freed", err)
+			paused = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-guard.clock.After(DefaultFreeSpaceInterval):
+		}
+	}
+}
+
+// diskGuardClient fails response whose Content-Length doesn't fit to free space of dir
+type diskGuardClient struct {
+	httpClient
+	guard *diskGuard
+	dir   string
+}
+
+func (c *diskGuardClient) Get(url string) (*http.Response, error) {
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return resp, err
+	}
+
+	if (resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent) && resp.ContentLength > 0 {
+		if err := c.guard.check(c.dir, resp.ContentLength); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+func (c *diskGuardClient) unwrap() httpClient {
+	return c.httpClient
+}
+
+// guardDisk wrap httpClient by check of Content-Length against free space of dir
+func (client *StorClient) guardDisk(httpClient httpClient, dir string) httpClient {
+	if client.disk == nil {
+		return httpClient
+	}
+
+	return &diskGuardClient{httpClient: httpClient, guard: client.disk, dir: dir}
+}
+
+func isInsufficientSpace(err error) bool {
+	return errors.Cause(lastAttemptError(err)) == ErrInsufficientSpace
+}
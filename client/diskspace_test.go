@@ -0,0 +1,100 @@
+package storclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskGuardCheck(t *testing.T) {
+	guard := newDiskGuard(&fakeClock{}, 100, false)
+	guard.free = func(string) (int64, error) { return 150, nil }
+
+	assert.NoError(t, guard.check("/data", 0))
+	assert.NoError(t, guard.check("/data", 50))
+	assert.True(t, isInsufficientSpace(guard.check("/data", 51)))
+
+	guard.free = func(string) (int64, error) { return 50, nil }
+	assert.Equal(t, ErrInsufficientSpace, errors.Cause(guard.await(context.Background(), "/data")), "without wait")
+
+	assert.Nil(t, newDiskGuard(&fakeClock{}, 0, true))
+	var disabled *diskGuard
+	assert.NoError(t, disabled.await(context.Background(), "/data"))
+
+	tmpDir, err := ioutil.TempDir("", "diskspace")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	free, err := freeSpace(tmpDir)
+	assert.NoError(t, err)
+	assert.True(t, free > 0)
+}
+
+func TestDiskGuardWait(t *testing.T) {
+	clock := &fakeClock{}
+	guard := newDiskGuard(clock, 100, true)
+	checks := 0
+	guard.free = func(string) (int64, error) {
+		checks++
+		if checks < 3 {
+			return 10, nil
+		}
+		return 1000, nil
+	}
+
+	assert.NoError(t, guard.await(context.Background(), "/data"))
+	assert.Equal(t, 3, checks)
+	assert.Equal(t, []time.Duration{DefaultFreeSpaceInterval, DefaultFreeSpaceInterval}, clock.sleeps)
+
+	guard.free = func(string) (int64, error) { return 10, nil }
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, guard.await(ctx, "/data"))
+}
+
+func TestDiskGuardContentLength(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 100))
+	}))
+	defer server.Close()
+
+	guard := newDiskGuard(&fakeClock{}, 100, false)
+	guard.free = func(string) (int64, error) { return 150, nil }
+
+	c := &diskGuardClient{httpClient: &http.Client{}, guard: guard, dir: "/data"}
+	_, err := c.Get(server.URL)
+	assert.True(t, isInsufficientSpace(err))
+
+	guard.free = func(string) (int64, error) { return 200, nil }
+	resp, err := c.Get(server.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+}
+
+func TestDownloadWithoutFreeSpace(t *testing.T) {
+	httpClientTouch := 0
+	httpClient := func() httpClient {
+		httpClientTouch++
+		return &clientMock{statusCode: 200, status: "OK"}
+	}
+
+	opts := StorClientOpts{MinFreeSpace: 1 << 62}
+	downloadWorkersTest(t, opts, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
+		assert.Equal(t, DOWN_FAIL, stat[0].Status)
+	})
+	assert.Equal(t, 0, httpClientTouch)
+
+	_, err := New(url.URL{}, "", StorClientOpts{WaitForFreeSpace: true})
+	assert.Error(t, err)
+}
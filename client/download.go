@@ -1,19 +1,19 @@
 package storclient
 
 import (
-	"bytes"
-	"crypto/sha256"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/JaSei/pathutil-go"
 	"github.com/avast/hashutil-go"
-	"github.com/avast/retry-go"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
@@ -22,152 +22,356 @@ type httpClient interface {
 	Get(url string) (*http.Response, error)
 }
 
+// wrappedHTTPClient is httpClient which adds some behaviour to other httpClient
+type wrappedHTTPClient interface {
+	unwrap() httpClient
+}
+
 //type logFieldsError interface {
 //	Error() string
 //	LogFields() log.Fields
 //}
 
-type downloadError struct {
-	sha        hashutil.Hash
-	statusCode int
-	status     string
-}
-
 type successDownload struct {
 	size         int64
 	lastModified time.Time
 }
 
-func (err downloadError) Error() string {
-	return fmt.Sprintf("Download of %s fail %d (%s)", err.sha, err.statusCode, err.status)
+// shaMismatchError is returned if downloaded content doesn't match expected sha
+type shaMismatchError struct {
+	expected   hashutil.Hash
+	downloaded hashutil.Hash
+	reason     string
+}
+
+func (err shaMismatchError) Error() string {
+	if err.reason != "" {
+		return fmt.Sprintf("Downloaded content of %s is wrong: %s", err.expected, err.reason)
+	}
+
+	return fmt.Sprintf("Downloaded sha (%s) is not equal with expected sha (%s)", err.downloaded, err.expected)
 }
 
-//func (err downloadError) LogFields() log.Fields {
+//func (err StatusError) LogFields() log.Fields {
 //	return log.Fields{
-//		"sha256":     err.sha.String(),
-//		"statusCode": err.statusCode,
-//		"status":     err.status,
+//		"sha256":     err.Sha.String(),
+//		"statusCode": err.StatusCode,
+//		"status":     err.Status,
 //	}
 //}
 
-func (client *StorClient) downloadWorker(id int, httpClientFunc func() httpClient, shasForDownload <-chan hashutil.Hash, downloadedFilesStat chan<- DownStat) {
+func (client *StorClient) downloadWorker(id int, httpClientFunc func() httpClient, jobs *downloadQueue, downloadedFilesStat chan<- DownStat) {
 	defer client.wg.Done()
 
 	log.WithField("worker", id).Debugln("Start download worker...")
 
-	for sha := range shasForDownload {
-		if sha.Equal(workerEnd) {
-			log.WithField("worker", id).Debugln("worker end")
+	defer log.WithField("worker", id).Debugln("worker end")
+
+	workerBucket := newTokenBucket(client.Clock, client.MaxBytesPerSecPerWorker)
+
+	slot := client.schedule.slot()
+	defer slot.release()
+
+	for {
+		slot.wait()
+		client.pause.wait(client.ctx)
+		job, ok := client.nextJob(jobs)
+		if !ok {
 			return
 		}
+		// worker could wait for job before pause
+		client.pause.wait(client.ctx)
+
+		if job.uploadPath != "" {
+			client.uploadWorkerJob(id, job.seq, job.uploadPath, downloadedFilesStat)
+
+			continue
+		}
 
-		filename := sha.String()
-		if client.UpperCase {
-			filename = strings.ToUpper(sha.String())
+		if client.multiGet != nil {
+			client.prefetch(id, jobs.jobs, job, httpClientFunc, workerBucket)
 		}
 
-		filename += client.Suffix
+		sha := job.sha
 
-		filepath, err := pathutil.New(client.downloadDir, filename)
+		tenant, clientFunc := client.root, httpClientFunc
+		if job.tenant != nil {
+			tenant, clientFunc = job.tenant, job.tenant.httpClientFunc
+		}
+
+		if err := job.err(client.ctx); err != nil {
+			client.sendFailedDownload(downloadedFilesStat, DownloadResult{seq: job.seq, worker: id, Sha: sha, Status: DOWN_FAIL, Err: err})
+
+			continue
+		}
+
+		filename := client.filename(sha)
+
+		filepath, err := pathutil.New(tenant.downloadDir, filename)
 		if err != nil {
 			log.Errorf("path problem: %s", err)
 
-			downloadedFilesStat <- DownStat{Status: DOWN_FAIL}
+			client.sendFailedDownload(downloadedFilesStat, DownloadResult{seq: job.seq, worker: id, Sha: sha, Status: DOWN_FAIL, Err: err})
+
+			continue
+		}
+
+		if client.archive != nil && client.archive.contains(filename) {
+			log.WithFields(log.Fields{
+				"worker": id,
+				"sha256": sha.String(),
+			}).Debugf("File %s is archived - skip download", filename)
+
+			client.sendStat(downloadedFilesStat, DownloadResult{seq: job.seq, worker: id, Sha: sha, Status: DOWN_SKIP})
 
 			continue
 		}
 
-		if filepath.Exists() {
+		if !client.DryRun && !client.Overwrite && client.archive == nil && filepath.Exists() && client.existingValid(id, sha, filepath.Canonpath()) {
 			log.WithFields(log.Fields{
 				"worker": id,
 				"sha256": sha.String(),
 			}).Debugf("File %s exists - skip download", filepath)
 
-			downloadedFilesStat <- DownStat{Status: DOWN_SKIP}
+			client.sendStat(downloadedFilesStat, DownloadResult{seq: job.seq, worker: id, Sha: sha, Path: filepath.String(), Status: DOWN_SKIP})
 
 			continue
 		}
 
-		if !client.currentDownloads.ContainsOrAdd(sha) {
+		if !client.DryRun && !client.Overwrite && client.DownloadIndex != nil && client.DownloadIndex.Contains(sha) {
+			log.WithFields(log.Fields{
+				"worker": id,
+				"sha256": sha.String(),
+			}).Debug("Sha is in download index - skip download")
+
+			client.sendStat(downloadedFilesStat, DownloadResult{seq: job.seq, worker: id, Sha: sha, Status: DOWN_SKIP})
+
+			continue
+		}
+
+		if path.Dir(filename) != "." && !client.DryRun {
+			if err := client.dirs.ensure(filepath.Parent().Canonpath()); err != nil {
+				log.WithFields(log.Fields{
+					"worker": id,
+					"sha256": sha.String(),
+				}).Error(err)
+
+				client.sendFailedDownload(downloadedFilesStat, DownloadResult{seq: job.seq, worker: id, Sha: sha, Status: DOWN_FAIL, Err: err})
+
+				continue
+			}
+		}
+
+		if !client.Devnull && !client.DryRun {
+			if err := client.disk.await(client.ctx, filepath.Parent().Canonpath()); err != nil {
+				log.WithFields(log.Fields{
+					"worker": id,
+					"sha256": sha.String(),
+				}).Error(err)
+
+				client.sendFailedDownload(downloadedFilesStat, DownloadResult{seq: job.seq, worker: id, Sha: sha, Status: DOWN_FAIL, Err: err})
+
+				continue
+			}
+		}
+
+		if !tenant.currentDownloads.ContainsOrAdd(sha) {
 			log.WithFields(log.Fields{
 				"worker": id,
 				"sha256": sha.String(),
 			}).Debug("File is now downloading in other worker - skip download")
 
-			downloadedFilesStat <- DownStat{Status: DOWN_SKIP}
+			client.sendStat(downloadedFilesStat, DownloadResult{seq: job.seq, worker: id, Sha: sha, Path: filepath.String(), Status: DOWN_SKIP})
 
 			continue
 		}
 
-		startTime := time.Now()
+		client.events.emit(Event{Event: EventStarted, Sha: sha.String()})
+		client.progress.started(sha.String())
+
+		startTime := client.Clock.Now()
+
+		if client.cache != nil && !client.Overwrite {
+			if size, ok := client.cache.get(sha, filepath.Canonpath(), client.Devnull, client.fileAttrs); ok {
+				var err error
+				if client.archive != nil {
+					err = client.archive.add(filename, filepath.Canonpath())
+				}
+				tenant.currentDownloads.Del(sha)
+
+				client.sendCached(downloadedFilesStat, id, job.seq, sha, filepath.String(), size, since(client.Clock, startTime), err)
 
-		tryS3 := false
-		if client.S3URL != nil {
-			tryS3 = true
+				continue
+			}
+		}
+
+		trySource := client.source != nil
+		mirror := tenant.mirrors.cursor()
+		staging := client.stagingOf(tenant)
+		// client of each chunk of parallel download (see StorClientOpts.ParallelChunks)
+		newChunkClient := func() httpClient {
+			chunkClient := client.throttle(clientFunc(), workerBucket)
+			client.limitRequestTime(chunkClient, startTime)
+			return chunkClient
 		}
 
 		var size int64
-		err = retry.Do(
-			func() error {
+		var capturedHeader http.Header
+		var metadata *Metadata
+		// content of last attempt isn't verified (see StorClientOpts.InsecureMirrors)
+		var unverified bool
+		// sha or its alias (see StorClientOpts.AliasResolver)
+		identity := sha
+		// content from batch response is used by first attempt (see StorClientOpts.MultiGet)
+		part, staged := client.multiGet.take(sha)
+		attempts := 0
+		err = client.RetryEngine.Do(
+			sha,
+			client.breaker.guard(&trySource, client.traceAttempt(sha, &attempts, &size, func(trace func(httpClient) httpClient) error {
+				attempts++
+				if client.MaxElapsedTime > 0 && since(client.Clock, startTime) >= client.MaxElapsedTime {
+					return ErrMaxElapsedTime
+				}
+
+				if staged {
+					staged = false
+					var err error
+					size, err = client.installStaged(part, sha, filepath.Canonpath())
+					return err
+				}
+
 				var err error
 
-				var u string
-				if tryS3 {
-					var urlErr error
-					u, urlErr = client.createS3URL(sha)
-					if urlErr != nil {
-						log.WithFields(log.Fields{
-							"worker": id,
-							"sha256": sha.String(),
-						}).Warningf("S3 template fail: %s", urlErr)
-					} else {
-						log.WithFields(log.Fields{
-							"worker": id,
-							"sha256": sha.String(),
-						}).Debugf("Use S3 url %s", u)
+				u := client.attemptURL(log.Fields{"worker": id, "sha256": sha.String()}, identity, mirror.url(), trySource)
+
+				httpClient := client.throttle(clientFunc(), workerBucket)
+				if job.cancelable() {
+					ctx, cancel := mergeContext(client.ctx, job.ctx)
+					defer cancel()
+					setRequestContext(httpClient, ctx)
+				}
+				if !client.Devnull && !client.DryRun {
+					httpClient = client.guardDisk(httpClient, filepath.Parent().Canonpath())
+				}
+				client.limitRequestTime(httpClient, startTime)
+				httpClient = &progressClient{httpClient: httpClient, progress: &client.progress, sha: sha.String()}
+				httpClient = trace(httpClient)
+				httpClient, unverified = client.trustedMirrors.skipVerification(httpClient, u)
+
+				if len(client.CaptureHeaders) > 0 {
+					capture := &headerCaptureClient{httpClient: httpClient, names: client.CaptureHeaders}
+					defer func() {
+						capturedHeader = capture.header
+					}()
+					httpClient = capture
+				}
+
+				attemptStartTime := client.Clock.Now()
+				if client.MetadataOnly != nil {
+					m, err := headMetadata(httpClient, u, identity)
+					if err != nil {
+						client.mirrors.record(u, 0, since(client.Clock, attemptStartTime), err)
+						return err
+					}
+					if client.MetadataOnly(sha, m) {
+						metadata = &m
+						client.mirrors.record(u, 0, since(client.Clock, attemptStartTime), nil)
+						return nil
 					}
 				}
-				if u == "" {
-					u = client.createStorURL(sha)
-					log.WithFields(log.Fields{
-						"worker": id,
-						"sha256": sha.String(),
-					}).Debugf("Use Stor url %s", u)
+
+				if client.SignatureVerifier != nil && !client.DryRun {
+					if err := client.verifySignature(httpClient, u, identity); err != nil {
+						client.mirrors.record(u, 0, since(client.Clock, attemptStartTime), err)
+						return err
+					}
 				}
 
-				if client.Devnull {
-					size, err = downloadFileToDevnull(httpClientFunc(), u, sha)
-				} else {
-					size, err = downloadFileViaTempFile(httpClientFunc(), filepath, u, sha)
+				switch {
+				case client.DryRun:
+					size, err = headFile(httpClient, u, identity)
+				case client.Devnull:
+					size, err = downloadFileToDevnull(httpClient, u, identity)
+				case client.ZipPassword != "":
+					size, err = downloadFileZipped(httpClient, filepath, u, identity, client.ZipPassword, client.ZipEncryption, client.fileAttrs, staging)
+				case client.aead != nil:
+					size, err = downloadFileEncrypted(httpClient, filepath, u, identity, client.aead, client.fileAttrs, staging)
+				default:
+					if m, ok := client.parallelMetadata(httpClient, u, identity, trySource); ok {
+						size, err = downloadFileParallel(httpClient, newChunkClient, filepath, u, identity, m, client.ParallelChunks, client.checkTemp(identity), client.fileAttrs, staging)
+					} else {
+						size, err = downloadFileViaTempFileWith(httpClient, filepath, u, identity, client.checkTemp(identity), client.fileAttrs, staging)
+					}
+					if err == nil {
+						err = verifyFile(filepath.Canonpath(), size, identity, client.VerifyAfterRename)
+					}
 				}
+				client.mirrors.record(u, size, since(client.Clock, attemptStartTime), err)
 
 				return err
-			},
-			retry.OnRetry(func(n uint, err error) {
+			})),
+			func(err error) bool {
 				log.WithFields(log.Fields{
 					"worker": id,
 					"sha256": sha.String(),
-				}).Debugf("Retry #%d: %s", n, err)
-			}),
-			retry.RetryIf(func(err error) bool {
-				switch e := err.(type) {
-				case downloadError:
-					if (downloadError)(e).statusCode == 404 && tryS3 {
-						tryS3 = false
-					} else if (downloadError)(e).statusCode == 404 {
-						return false
-					}
-				}
+				}).Debugf("Attempt fail: %s", err)
 
-				return true
-			}),
-			retry.Delay(client.RetryDelay),
-			retry.Attempts(client.RetryAttempts),
-			retry.Units(1),
+				if job.err(client.ctx) != nil {
+					return false
+				}
+				mirror.failover(err)
+				if client.resolveAlias(log.Fields{"worker": id, "sha256": sha.String()}, err, trySource, sha, &identity) {
+					return true
+				}
+				return retryable(err, &trySource)
+			},
 		)
 
-		downloadDuration := time.Since(startTime)
-		client.currentDownloads.Del(sha)
+		if staged {
+			part.discard()
+		}
+
+		var alias hashutil.Hash
+		if !identity.Equal(sha) {
+			alias = identity
+		}
+
+		retries := 0
+		if attempts > 1 {
+			retries = attempts - 1
+		}
+
+		if err == nil && metadata != nil {
+			tenant.currentDownloads.Del(sha)
+
+			log.WithFields(log.Fields{
+				"worker": id,
+				"sha256": sha.String(),
+			}).Debugf("Metadata of %s recorded", sha)
+			client.sendStat(downloadedFilesStat, DownloadResult{seq: job.seq, worker: id, Sha: sha, Size: metadata.Size, Duration: since(client.Clock, startTime), Status: DOWN_METADATA, retries: retries, Header: capturedHeader, Metadata: metadata.Header, Alias: alias})
+
+			continue
+		}
+
+		if err == nil && job.bundle {
+			err = client.unpackBundle(sha, filepath.Canonpath(), tenant.downloadDir)
+		}
+
+		if err == nil && client.cache != nil && !client.Devnull {
+			client.cache.put(sha, filepath.Canonpath())
+		}
+
+		if err == nil && client.archive != nil {
+			err = client.archive.add(filename, filepath.Canonpath())
+		}
+
+		downloadDuration := since(client.Clock, startTime)
+		tenant.currentDownloads.Del(sha)
+
+		resultPath := filepath.String()
+		if client.Devnull || client.DryRun || client.archive != nil {
+			resultPath = ""
+		}
 
 		if err != nil {
 			log.WithFields(log.Fields{
@@ -175,39 +379,165 @@ func (client *StorClient) downloadWorker(id int, httpClientFunc func() httpClien
 				"sha256": sha.String(),
 				"error":  err,
 			}).Errorf("Error download %s: %s\n", sha, err)
-			downloadedFilesStat <- DownStat{Status: DOWN_FAIL}
+			client.sendFailedDownload(downloadedFilesStat, DownloadResult{seq: job.seq, worker: id, Sha: sha, Path: resultPath, Status: DOWN_FAIL, Err: err, Header: capturedHeader, retries: retries, Alias: alias})
 		} else {
 			log.WithFields(log.Fields{
 				"worker": id,
 				"sha256": sha.String(),
 			}).Debugf("Downloaded %s", sha)
-			downloadedFilesStat <- DownStat{Size: size, Duration: downloadDuration, Status: DOWN_OK}
+			client.addToIndex(sha)
+			client.postDownload(sha, resultPath, size)
+			client.sendStat(downloadedFilesStat, DownloadResult{seq: job.seq, worker: id, Sha: sha, Path: resultPath, Size: size, Duration: downloadDuration, Status: DOWN_OK, Header: capturedHeader, retries: retries, Exists: client.DryRun, Alias: alias, Unverified: unverified && !client.DryRun})
+		}
+	}
+}
+
+// attemptURL return url of download attempt - url on source (S3) if trySource (and url is ok), url on stor otherwise
+func (client *StorClient) attemptURL(fields log.Fields, sha hashutil.Hash, storageUrl url.URL, trySource bool) string {
+	if trySource {
+		u, err := client.source.ObjectURL(sha)
+		if err == nil {
+			log.WithFields(fields).Debugf("Use source url %s", u)
+			return u
+		}
+		log.WithFields(fields).Warningf("Source url fail: %s", err)
+	}
+
+	u := createStorURL(storageUrl, sha)
+	log.WithFields(fields).Debugf("Use Stor url %s", u)
+
+	return u
+}
+
+// retryable return true if failed attempt should be retried,
+// 404 from source turns off trySource (next attempt is fallback to stor)
+func retryable(err error, trySource *bool) bool {
+	if err == ErrMaxElapsedTime || err == ErrCircuitOpen || isDecompressionBomb(err) || isSignatureError(err) || isInsufficientSpace(err) {
+		return false
+	}
+
+	if e, ok := err.(StatusError); ok && e.StatusCode == 404 {
+		if !*trySource {
+			return false
 		}
+		*trySource = false
+	}
+
+	return true
+}
+
+// existingValid report if existing file of sha can be skipped,
+// with VerifyExisting it's re-hashed and wrong (or unreadable) file is downloaded again
+func (client *StorClient) existingValid(id int, sha hashutil.Hash, path string) bool {
+	if !client.VerifyExisting {
+		return true
+	}
+
+	ok, err := verifyExisting(path, sha)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"worker": id,
+			"sha256": sha.String(),
+		}).Warn(err)
+	} else if !ok {
+		log.WithFields(log.Fields{
+			"worker": id,
+			"sha256": sha.String(),
+		}).Warnf("Existing file %s is wrong - download again", path)
+	}
+
+	return ok
+}
+
+// limitRequestTime shorten timeout of http client to remaining time of MaxElapsedTime
+func (client *StorClient) limitRequestTime(httpClient httpClient, startTime time.Time) {
+	if client.MaxElapsedTime <= 0 {
+		return
+	}
+
+	for {
+		wrapped, ok := httpClient.(wrappedHTTPClient)
+		if !ok {
+			break
+		}
+		httpClient = wrapped.unwrap()
+	}
+
+	hc, ok := httpClient.(*http.Client)
+	if !ok {
+		return
+	}
+
+	remaining := client.MaxElapsedTime - since(client.Clock, startTime)
+	if hc.Timeout == 0 || remaining < hc.Timeout {
+		hc.Timeout = remaining
 	}
 }
 
-func (client *StorClient) newHTTPClient() httpClient {
-	tr := &http.Transport{
-		MaxIdleConns:    client.Max,
-		IdleConnTimeout: client.Timeout,
+// sendStat emit progress event, record result to journal, send result (see Results)
+// and send stat to stats processing
+func (client *StorClient) sendStat(downloadedFilesStat chan<- DownStat, result DownloadResult) {
+	client.events.emitResult(result.Sha.String(), result)
+
+	if client.Journal != nil {
+		if journalErr := client.Journal.Record(client.Clock.Now(), result); journalErr != nil {
+			log.WithField("sha256", result.Sha.String()).Warning(journalErr)
+		}
 	}
 
-	return &http.Client{Transport: tr}
+	client.sendResult(result)
+	client.complete(result)
+	downloadedFilesStat <- DownStat{Size: result.Size, Duration: result.Duration, Status: result.Status, sha: result.Sha.String(), unverified: result.Unverified, abandoned: client.abandoned(result), worker: result.worker, retries: result.retries}
+}
+
+// httpClientFunc return http client used by workers
+func (client *StorClient) httpClientFunc() httpClient {
+	return client.buildHTTPClient(nil)
 }
 
-func (client *StorClient) createS3URL(sha hashutil.Hash) (string, error) {
-	var pathBytes bytes.Buffer
-	shaStr := sha.String()
-	params := struct{ Sha, FirstShaByte, SecondShaByte, ThirdShaByte string }{shaStr, shaStr[0:2], shaStr[2:4], shaStr[4:6]}
-	if err := client.s3template.Execute(&pathBytes, params); err != nil {
-		return "", err
+// buildHTTPClient return http client with given request headers and RequestTimeout
+// (with decompression limit, bandwidth metering and fault injection if is enabled)
+func (client *StorClient) buildHTTPClient(header http.Header) httpClient {
+	stdClient := client.newStdHTTPClient()
+	if client.RequestTimeout > 0 {
+		stdClient.Timeout = client.RequestTimeout
 	}
 
-	return fmt.Sprintf("%s/%s", client.S3URL.String(), pathBytes.String()), nil
+	var httpClient httpClient = &contextClient{Client: stdClient, ctx: client.ctx, header: header, verifier: client.Verifier}
+
+	if client.decompression.maxSize > 0 {
+		httpClient = &decompressionGuardClient{httpClient: httpClient, limits: client.decompression}
+	}
+
+	if client.bandwidth != nil {
+		httpClient = &meteredClient{httpClient: httpClient, meter: client.bandwidth}
+	}
+
+	if client.Chaos != nil {
+		httpClient = &chaosClient{httpClient: httpClient, opts: client.Chaos, rnd: client.chaosRand, clock: client.Clock}
+	}
+
+	return httpClient
+}
+
+// newStdHTTPClient return http client with transport (connection pool) shared by all workers and tenants
+// (or with replay/recording of requests)
+func (client *StorClient) newStdHTTPClient() *http.Client {
+	if client.HTTPClient != nil {
+		httpClient := *client.HTTPClient
+		httpClient.Transport = client.roundTripper
+		return &httpClient
+	}
+
+	return &http.Client{Transport: client.roundTripper}
 }
 
 func (client *StorClient) createStorURL(sha hashutil.Hash) string {
-	storage := (client.storageUrl).String()
+	return createStorURL(client.storageUrl, sha)
+}
+
+func createStorURL(storageUrl url.URL, sha hashutil.Hash) string {
+	storage := storageUrl.String()
 	storage = strings.TrimRight(storage, "/")
 	return fmt.Sprintf("%s/%s", storage, sha)
 }
@@ -217,35 +547,57 @@ func downloadFileToDevnull(httpClient httpClient, url string, expectedSha hashut
 	return succ.size, err
 }
 
+// downloadFileViaTempFile download to <sha>.temp file next to filepath and rename it to filepath
+//
+// existing temp file (from failed attempt or crashed run) is resumed by Range request with If-Range
+// (ETag or Last-Modified of response which started temp file is stored next to it), so changed object
+// is downloaded from start; temp file is kept on (network) failure for next attempt and removed only if content is wrong
 func downloadFileViaTempFile(httpClient httpClient, filepath pathutil.Path, url string, expectedSha hashutil.Hash) (size int64, err error) {
-	temppath, err := pathutil.NewTempFile(pathutil.TempOpt{Dir: filepath.Parent().Canonpath(), Prefix: fmt.Sprintf("%s_*.temp", expectedSha)})
+	return downloadFileViaTempFileWith(httpClient, filepath, url, expectedSha, nil, fileAttrs{}, tempFiles{})
+}
+
+// downloadFileViaTempFileWith download via temp file like downloadFileViaTempFile,
+// if checkTemp is set, it verifies temp file before rename instead of in-process hash check;
+// attrs are applied to verified temp file before rename, temp file is named and placed by staging
+func downloadFileViaTempFileWith(httpClient httpClient, filepath pathutil.Path, url string, expectedSha hashutil.Hash, checkTemp func(path string) error, attrs fileAttrs, staging tempFiles) (size int64, err error) {
+	temppath, err := staging.path(filepath, expectedSha, "")
 	if err != nil {
-		return 0, errors.Wrap(err, "Construct of new temp file fail")
+		return 0, errors.Wrap(err, "Construct of temp file path fail")
 	}
 
-	// cleanup tempfile if this function fail (err is set)
+	// cleanup tempfile if its content is wrong (resume isn't possible)
 	defer func() {
-		if err != nil {
+		if _, ok := err.(shaMismatchError); (ok || isDecompressionBomb(err)) && temppath.Exists() {
+			removeValidator(temppath.Canonpath())
 			if remErr := temppath.Remove(); remErr != nil {
 				err = errors.Wrapf(remErr, "Cleanup tempfile %s fail", temppath)
 			}
 		}
 	}()
 
-	if temppath.Exists() {
-		if err := temppath.Remove(); err != nil {
-			return 0, errors.Wrapf(err, "Cleanup old (exists) tempfile %s fail", temppath)
+	succ, err := downloadFile(httpClient, temppath, url, expectedSha, checkTemp == nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if checkTemp != nil {
+		if err := checkTemp(temppath.Canonpath()); err != nil {
+			if errors.Is(err, ErrChecksumMismatch) {
+				return 0, shaMismatchError{expected: expectedSha, reason: err.Error()}
+			}
+			// temp file is complete, next attempt only checks it again
+			return 0, errors.Wrapf(err, "Check of tempfile %s fail", temppath)
 		}
 	}
 
-	succ, err := downloadFile(httpClient, temppath, url, expectedSha)
-	if err != nil {
+	if err := attrs.apply(temppath.Canonpath()); err != nil {
 		return 0, err
 	}
 
 	if _, err := temppath.Rename(filepath.Canonpath()); err != nil {
 		return 0, errors.Wrapf(err, "Rename temp %s to final path %s fail", temppath, filepath)
 	}
+	removeValidator(temppath.Canonpath())
 
 	if err = os.Chtimes(filepath.Canonpath(), succ.lastModified, succ.lastModified); err != nil {
 		return 0, errors.Wrapf(err, "Chtimes(%s, %s) fail", filepath.Canonpath(), succ.lastModified.String())
@@ -254,19 +606,163 @@ func downloadFileViaTempFile(httpClient httpClient, filepath pathutil.Path, url
 	return succ.size, nil
 }
 
-func downloadFile(httpClient httpClient, path pathutil.Path, url string, expectedSha hashutil.Hash) (succ successDownload, err error) {
-	out, err := path.OpenWriter()
+// downloadFile download to path, existing content of path is resumed (see downloadFileViaTempFile)
+//
+// hash of content isn't checked if verifyHash is false (content is verified by caller)
+func downloadFile(httpClient httpClient, path pathutil.Path, url string, expectedSha hashutil.Hash, verifyHash bool) (succ successDownload, err error) {
+	out, err := os.OpenFile(path.Canonpath(), os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
-		return successDownload{}, errors.Wrapf(err, "OpenWriter to tempfile %s fail", path)
+		return successDownload{}, errors.Wrapf(err, "Open of tempfile %s fail", path)
 	}
 
 	defer func() {
-		if errClose := out.Close(); errClose != nil {
-			err = errors.Wrapf(err, "Close %s fail", path)
+		if errClose := out.Close(); errClose != nil && err == nil {
+			err = errors.Wrapf(errClose, "Close %s fail", path)
+		}
+	}()
+
+	var v *verification
+	if verifyHash {
+		if v, err = newVerificationOf(httpClient, expectedSha); err != nil {
+			return successDownload{}, err
+		}
+	}
+
+	// verify already downloaded content - file offset is at the end after it
+	offset, err := io.Copy(v.writer(), out)
+	if err != nil {
+		return successDownload{}, errors.Wrapf(err, "Read of tempfile %s fail", path)
+	}
+
+	if offset > 0 && !setRequestHeader(httpClient, "Range", fmt.Sprintf("bytes=%d-", offset)) {
+		offset = 0
+	}
+	if validator := readValidator(path.Canonpath()); offset > 0 && validator != "" {
+		setRequestHeader(httpClient, "If-Range", validator)
+	}
+
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return successDownload{}, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil && err == nil {
+			err = errClose
 		}
 	}()
 
-	return downloadFileToWriter(httpClient, url, out, expectedSha)
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		log.Debugf("Resume download of %s from offset %d", expectedSha, offset)
+	case resp.StatusCode == http.StatusOK:
+		// download from start (server doesn't support ranges, object changed or there is nothing to resume)
+		if offset > 0 {
+			if err := restartFile(out); err != nil {
+				return successDownload{}, errors.Wrapf(err, "Truncate of tempfile %s fail", path)
+			}
+			v.restart()
+			offset = 0
+		}
+		if err := writeValidator(path.Canonpath(), rangeValidator(resp)); err != nil {
+			return successDownload{}, errors.Wrapf(err, "Write of range validator of tempfile %s fail", path)
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 && unsatisfiedRangeSize(resp) == offset:
+		// temp file is complete (e.g. its check failed in previous attempt)
+		if err := v.verify(expectedSha); err != nil {
+			return successDownload{}, err
+		}
+		lastModified, err := getLastModifiedTime(resp)
+		if err != nil {
+			return successDownload{}, err
+		}
+		return successDownload{size: offset, lastModified: lastModified}, nil
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// temp file is longer than object
+		return successDownload{}, shaMismatchError{expected: expectedSha, reason: fmt.Sprintf("tempfile has %d bytes and range isn't satisfiable", offset)}
+	default:
+		return successDownload{}, StatusError{Sha: expectedSha, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	succ, err = copyAndVerify(resp, errWriter{w: out}, v, expectedSha)
+	if err != nil {
+		return successDownload{}, err
+	}
+	succ.size += offset
+
+	return succ, nil
+}
+
+// unsatisfiedRangeSize return size of object from Content-Range (bytes */<size>) of 416 response,
+// -1 if it isn't known
+func unsatisfiedRangeSize(resp *http.Response) int64 {
+	var size int64
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes */%d", &size); err != nil {
+		return -1
+	}
+
+	return size
+}
+
+// restartFile truncate file and set offset to start
+func restartFile(file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err := file.Seek(0, io.SeekStart)
+	return err
+}
+
+// setRequestBody make (wrapped) contextClient send POST with body instead of GET,
+// returns false if isn't possible
+func setRequestBody(httpClient httpClient, body []byte) bool {
+	c := findContextClient(httpClient)
+	if c == nil {
+		return false
+	}
+
+	c.body = body
+
+	return true
+}
+
+// setRequestHeader set header of requests made by (wrapped) contextClient, returns false if isn't possible
+func setRequestHeader(httpClient httpClient, key, value string) bool {
+	c := findContextClient(httpClient)
+	if c == nil {
+		return false
+	}
+
+	header := make(http.Header)
+	for k, v := range c.header {
+		header[k] = v
+	}
+	header.Set(key, value)
+	c.header = header
+
+	return true
+}
+
+// findContextClient return contextClient wrapped by httpClient (nil if there isn't any)
+func findContextClient(httpClient httpClient) *contextClient {
+	for {
+		if c, ok := httpClient.(*contextClient); ok {
+			return c
+		}
+
+		wrapped, ok := httpClient.(wrappedHTTPClient)
+		if !ok {
+			return nil
+		}
+		httpClient = wrapped.unwrap()
+	}
+}
+
+// headFile check existence of sha on url by HEAD request, size is Content-Length (-1 if is unknown)
+func headFile(httpClient httpClient, url string, expectedSha hashutil.Hash) (size int64, err error) {
+	metadata, err := headMetadata(httpClient, url, expectedSha)
+
+	return metadata.Size, err
 }
 
 func downloadFileToWriter(httpClient httpClient, url string, out io.Writer, expectedSha hashutil.Hash) (succ successDownload, err error) {
@@ -281,29 +777,53 @@ func downloadFileToWriter(httpClient httpClient, url string, out io.Writer, expe
 	}()
 
 	if resp.StatusCode != 200 {
-		return successDownload{}, downloadError{sha: expectedSha, statusCode: resp.StatusCode, status: resp.Status}
+		return successDownload{}, StatusError{Sha: expectedSha, StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
-	lastModified, err := getLastModifiedTime(resp)
+	v, err := newVerificationOf(httpClient, expectedSha)
 	if err != nil {
 		return successDownload{}, err
 	}
 
-	hasher := sha256.New()
-	multi := io.MultiWriter(out, hasher)
+	return copyAndVerify(resp, out, v, expectedSha)
+}
 
-	size, err := io.Copy(multi, resp.Body)
+// newHasherOf return hasher of algorithm of expected hash
+func newHasherOf(expectedSha hashutil.Hash) (hash.Hash, error) {
+	algorithm, ok := hashAlgorithmBySize(len(expectedSha.ToBytes()))
+	if !ok {
+		return nil, errors.Wrapf(ErrInvalidHash, "unsupported size of hash %s", expectedSha)
+	}
+
+	return algorithm.New(), nil
+}
+
+// copyAndVerify copy body of response to out and check whole content by verification
+// (verification can already contain previously downloaded content, nil verification means without check)
+func copyAndVerify(resp *http.Response, out io.Writer, v *verification, expectedSha hashutil.Hash) (successDownload, error) {
+	lastModified, err := getLastModifiedTime(resp)
 	if err != nil {
 		return successDownload{}, err
 	}
 
-	downSha256, err := hashutil.BytesToHash(sha256.New(), hasher.Sum(nil))
+	if v == nil {
+		size, err := io.Copy(out, resp.Body)
+		if err != nil {
+			return successDownload{}, err
+		}
+
+		return successDownload{size: size, lastModified: lastModified}, nil
+	}
+
+	multi := io.MultiWriter(out, v.writer())
+
+	size, err := io.Copy(multi, resp.Body)
 	if err != nil {
 		return successDownload{}, err
 	}
 
-	if !downSha256.Equal(expectedSha) {
-		return successDownload{}, fmt.Errorf("Downloaded sha (%s) is not equal with expected sha (%s)", downSha256, expectedSha)
+	if err := v.verify(expectedSha); err != nil {
+		return successDownload{}, err
 	}
 
 	return successDownload{
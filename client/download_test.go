@@ -1,6 +1,7 @@
 package storclient
 
 import (
+	"context"
 	"crypto/sha256"
 	"io"
 	"net/http"
@@ -155,6 +156,41 @@ func TestDownloadWorker(t *testing.T) {
 		})
 	})
 
+	t.Run("all-zero digest doesn't end worker", func(t *testing.T) {
+		zeroHash, err := hashutil.BytesToHash(sha256.New(), make([]byte, sha256.Size))
+		assert.NoError(t, err)
+
+		tempdir, err := pathutil.NewTempDir(pathutil.TempOpt{})
+		assert.NoError(t, err)
+		defer tempdir.RemoveTree()
+
+		storClient, err := New(url.URL{}, tempdir.Canonpath(), StorClientOpts{})
+		assert.NoError(t, err)
+		storClient.wg.Add(1)
+
+		// one worker gets zero digest first, so it must continue to next sha
+		jobs := newDownloadQueue(2)
+		assert.NoError(t, jobs.push(context.Background(), downloadJob{sha: zeroHash}))
+		assert.NoError(t, jobs.push(context.Background(), downloadJob{sha: emptyHash}))
+		jobs.close()
+
+		stats := make(chan DownStat, 2)
+		httpClient := func() httpClient { return &clientMock{statusCode: 404, status: "Not found"} }
+		go storClient.downloadWorker(7, httpClient, jobs, stats)
+
+		processed := map[string]int{}
+		for i := 0; i < 2; i++ {
+			select {
+			case stat := <-stats:
+				assert.Equal(t, DOWN_FAIL, stat.Status)
+				processed[stat.sha] = stat.worker
+			case <-time.After(5 * time.Second):
+				t.Fatalf("worker ended after %d of 2 shas", i)
+			}
+		}
+		assert.Equal(t, map[string]int{zeroHash.String(): 7, emptyHash.String(): 7}, processed, "both shas are processed by one worker")
+	})
+
 	t.Run("S3 first download ok", func(t *testing.T) {
 		httpClient := func() httpClient { return &clientMock{statusCode: 200, status: "Ok"} }
 		downloadWorkersTestDownloadOK(t, StorClientOpts{S3URL: &url.URL{}}, httpClient, []hashutil.Hash{emptyHash}, 1)
@@ -188,14 +224,14 @@ func downloadWorkersTest(t *testing.T, storClientOpts StorClientOpts, httpClient
 	storClient.wg.Add(workers)
 	log.SetLevel(log.DebugLevel)
 
-	shasForDownload := make(chan hashutil.Hash, 3)
+	shasForDownload := newDownloadQueue(3)
 	downloadedFilesStat := make(chan DownStat, 3)
 
 	for _, sha256 := range sha256list {
-		shasForDownload <- sha256
+		assert.NoError(t, shasForDownload.push(context.Background(), downloadJob{sha: sha256}))
 	}
 
-	shasForDownload <- workerEnd
+	shasForDownload.close()
 
 	for i := 0; i < workers; i++ {
 		go storClient.downloadWorker(0, httpClientFunc, shasForDownload, downloadedFilesStat)
@@ -221,3 +257,18 @@ func downloadWorkersTestDownloadOK(t *testing.T, storClientOpts StorClientOpts,
 		}
 	})
 }
+
+func TestStartCtxReplacesContextOfNew(t *testing.T) {
+	client, err := New(url.URL{Scheme: "http", Host: "stor.invalid"}, t.Name(), StorClientOpts{})
+	assert.NoError(t, err)
+	initial := client.ctx
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client.StartCtx(ctx)
+	assert.Error(t, initial.Err(), "context of New is released")
+	assert.NoError(t, client.ctx.Err())
+
+	cancel()
+	assert.Error(t, client.ctx.Err(), "context is bound to ctx")
+	client.Wait()
+}
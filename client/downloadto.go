@@ -0,0 +1,156 @@
+package storclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// DownloadTo download sha synchronously to w with retries and verification like download to directory
+// (download doesn't use worker pool and isn't counted in TotalStat)
+//
+// content is streamed to w before it is verified - if content doesn't match sha,
+// error is returned after w got whole (wrong) content; failed attempt is resumed by Range request
+// (with If-Range), so w gets each byte only once - if object changed between attempts, download fails
+func (client *StorClient) DownloadTo(sha hashutil.Hash, w io.Writer) (int64, error) {
+	if len(sha.ToBytes()) != client.HashAlgorithm.Size() {
+		return 0, errors.Wrapf(ErrInvalidHash, "%s isn't %s hash", sha, client.HashAlgorithm)
+	}
+
+	v, err := newVerification(client.Verifier, sha)
+	if err != nil {
+		return 0, err
+	}
+	out := &streamDownload{w: w, verification: v}
+
+	startTime := client.Clock.Now()
+	trySource := client.source != nil
+	mirror := client.root.mirrors.cursor()
+	err = client.RetryEngine.Do(
+		sha,
+		client.breaker.guard(&trySource, func() error {
+			if client.MaxElapsedTime > 0 && since(client.Clock, startTime) >= client.MaxElapsedTime {
+				return ErrMaxElapsedTime
+			}
+
+			u := client.attemptURL(log.Fields{"sha256": sha.String()}, sha, mirror.url(), trySource)
+
+			httpClient := client.throttle(client.httpClientFunc(), nil)
+			client.limitRequestTime(httpClient, startTime)
+
+			if client.SignatureVerifier != nil {
+				if err := client.verifySignature(httpClient, u, sha); err != nil {
+					return err
+				}
+			}
+
+			return out.download(httpClient, u, sha)
+		}),
+		func(err error) bool {
+			log.WithField("sha256", sha.String()).Debugf("Attempt fail: %s", err)
+
+			// w already got whole content
+			if _, ok := err.(shaMismatchError); ok {
+				return false
+			}
+			if _, ok := err.(writeError); ok {
+				return false
+			}
+
+			mirror.failover(err)
+			return retryable(err, &trySource)
+		},
+	)
+
+	return out.written, err
+}
+
+// DownloadBytes download sha synchronously to memory (see DownloadTo),
+// content is returned only if it matches sha
+func (client *StorClient) DownloadBytes(sha hashutil.Hash) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if _, err := client.DownloadTo(sha, buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// streamDownload is state of download to writer kept between attempts
+type streamDownload struct {
+	w            io.Writer
+	verification *verification
+	written      int64
+	// range validator of response which started download (see rangeValidator)
+	validator string
+}
+
+func (s *streamDownload) Write(p []byte) (int, error) {
+	n, err := errWriter{w: s.w}.Write(p)
+	s.written += int64(n)
+	return n, err
+}
+
+// writeError is error of writer of DownloadTo, it isn't retried
+type writeError struct {
+	err error
+}
+
+func (err writeError) Error() string {
+	return fmt.Sprintf("Write of downloaded content fail: %s", err.err)
+}
+
+func (err writeError) Cause() error {
+	return err.err
+}
+
+// download one attempt - continue from already written content
+func (s *streamDownload) download(httpClient httpClient, url string, expectedSha hashutil.Hash) (err error) {
+	if s.written > 0 {
+		setRequestHeader(httpClient, "Range", fmt.Sprintf("bytes=%d-", s.written))
+		if s.validator != "" {
+			setRequestHeader(httpClient, "If-Range", s.validator)
+		}
+	}
+
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+	}()
+
+	switch {
+	case s.written > 0 && resp.StatusCode == http.StatusPartialContent:
+		log.Debugf("Resume download of %s from offset %d", expectedSha, s.written)
+	case resp.StatusCode == http.StatusOK:
+		if s.written == 0 {
+			s.validator = rangeValidator(resp)
+			break
+		}
+		// written content can't be taken back from w if object changed
+		if validator := rangeValidator(resp); validator != s.validator {
+			return shaMismatchError{expected: expectedSha, reason: fmt.Sprintf("object changed after %d bytes were written (%s -> %s)", s.written, s.validator, validator)}
+		}
+		// server doesn't support ranges - skip already written content
+		if _, err := io.CopyN(ioutil.Discard, resp.Body, s.written); err != nil {
+			return err
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		return shaMismatchError{expected: expectedSha, reason: fmt.Sprintf("%d bytes are written and range isn't satisfiable", s.written)}
+	default:
+		return StatusError{Sha: expectedSha, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	_, err = copyAndVerify(resp, s, s.verification, expectedSha)
+	return err
+}
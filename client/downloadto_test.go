@@ -0,0 +1,66 @@
+package storclient
+
+import (
+	"bytes"
+	"crypto/md5"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadTo(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	sha := contentHash(content)
+
+	var ranges []string
+	ignoreRange := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) == 1 {
+			// first attempt of each download is broken in the middle of body
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = w.Write([]byte(content[:len(content)/2]))
+			panic(http.ErrAbortHandler)
+		}
+		if ignoreRange {
+			r.Header.Del("Range")
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{RetryAttempts: 3, RetryDelay: 1})
+	assert.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	size, err := client.DownloadTo(sha, out)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), size)
+	assert.Equal(t, content, out.String(), "each byte is written once")
+	assert.Equal(t, []string{"", "bytes=" + strconv.Itoa(len(content)/2) + "-"}, ranges, "second attempt is resumed")
+
+	ranges = nil
+	ignoreRange = true
+	downloaded, err := client.DownloadBytes(sha)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(downloaded), "server without ranges")
+	assert.Len(t, ranges, 2)
+
+	ranges = nil
+	downloaded, err = client.DownloadBytes(contentHash("other"))
+	assert.Error(t, err)
+	assert.Nil(t, downloaded, "wrong content isn't returned")
+	assert.Len(t, ranges, 2, "wrong content isn't retried")
+
+	_, err = client.DownloadTo(hashutil.EmptyHash(md5.New()), &bytes.Buffer{})
+	assert.Error(t, err, "md5 isn't accepted by sha256 client")
+}
@@ -0,0 +1,65 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRun(t *testing.T) {
+	existing, missing := contentHash("exists"), contentHash("missing")
+
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if path.Base(r.URL.Path) != existing.String() {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("exists"))
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "dryrun")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, tmpDir, StorClientOpts{Max: 1, DryRun: true, RetryAttempts: 2, RetryDelay: 1})
+	assert.NoError(t, err)
+
+	results := client.Results()
+	client.Start()
+	assert.NoError(t, client.Download(existing))
+	assert.NoError(t, client.Download(missing))
+
+	presence := map[string]bool{}
+	sizes := map[string]int64{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range results {
+			presence[result.Sha.String()] = result.Exists
+			sizes[result.Sha.String()] = result.Size
+			assert.Empty(t, result.Path)
+		}
+	}()
+
+	total := client.Wait()
+	<-done
+
+	assert.Equal(t, map[string]bool{existing.String(): true, missing.String(): false}, presence)
+	assert.Equal(t, int64(len("exists")), sizes[existing.String()])
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, []string{http.MethodHead, http.MethodHead}, methods, "only HEAD requests, missing sha isn't retried")
+
+	files, err := ioutil.ReadDir(tmpDir)
+	assert.NoError(t, err)
+	assert.Empty(t, files, "nothing is written")
+}
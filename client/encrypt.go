@@ -0,0 +1,265 @@
+package storclient
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+)
+
+// encrypted files format - chunked AES-GCM stream:
+//
+//	header: magic (8 bytes) | random nonce prefix (7 bytes)
+//	chunks: AES-GCM sealed plaintext of encryptionChunkSize bytes (last chunk can be shorter),
+//	        nonce is prefix | chunk counter (uint32 big endian) | 1 for last chunk (0 otherwise)
+//
+// last flag in nonce detects truncation of file, counter detects reordering of chunks
+const (
+	encryptionMagic     = "STORENC1"
+	encryptionChunkSize = 64 * 1024
+	noncePrefixSize     = 7
+)
+
+// ErrDecryption is cause of error of reading encrypted file with wrong key or damaged content
+var ErrDecryption = errors.New("decryption fail")
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid encryption key")
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = append(nonce, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+
+	return nonce
+}
+
+// encryptWriter encrypt plaintext written to it, Close writes last chunk (underlying writer isn't closed)
+type encryptWriter struct {
+	out     io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+}
+
+func newEncryptWriter(out io.Writer, aead cipher.AEAD) (*encryptWriter, error) {
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+
+	if _, err := io.WriteString(out, encryptionMagic); err != nil {
+		return nil, err
+	}
+	if _, err := out.Write(prefix); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{out: out, aead: aead, prefix: prefix, buf: make([]byte, 0, encryptionChunkSize)}, nil
+}
+
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// full chunk is sealed only when more data comes (so last chunk is known on Close)
+		if len(w.buf) == encryptionChunkSize {
+			if err := w.seal(false); err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+func (w *encryptWriter) seal(last bool) error {
+	if w.counter == ^uint32(0) {
+		return errors.New("encrypted file is too big")
+	}
+
+	sealed := w.aead.Seal(nil, chunkNonce(w.prefix, w.counter, last), w.buf, nil)
+	w.counter++
+	w.buf = w.buf[:0]
+
+	_, err := w.out.Write(sealed)
+	return err
+}
+
+func (w *encryptWriter) Close() error {
+	return w.seal(true)
+}
+
+// decryptReader is reader of plaintext of encrypted file
+type decryptReader struct {
+	in      *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	chunk   []byte
+	plain   []byte
+	done    bool
+}
+
+// NewDecryptReader return reader of plaintext of file encrypted by client (see StorClientOpts.EncryptionKey),
+// reading of damaged or truncated file (or with wrong key) fails with error caused by ErrDecryption
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(encryptionMagic)+noncePrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.Wrap(ErrDecryption, "header of encrypted file is missing")
+	}
+	if string(header[:len(encryptionMagic)]) != encryptionMagic {
+		return nil, errors.Wrap(ErrDecryption, "file isn't encrypted by stor-client")
+	}
+
+	return &decryptReader{
+		in:     bufio.NewReaderSize(r, encryptionChunkSize+aead.Overhead()+1),
+		aead:   aead,
+		prefix: header[len(encryptionMagic):],
+		chunk:  make([]byte, encryptionChunkSize+aead.Overhead()),
+	}, nil
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+
+	return n, nil
+}
+
+// open decrypt next chunk
+func (r *decryptReader) open() error {
+	n, err := io.ReadFull(r.in, r.chunk)
+	switch {
+	case err == io.EOF:
+		return errors.Wrap(ErrDecryption, "encrypted file is truncated")
+	case err == io.ErrUnexpectedEOF:
+		r.done = true
+	case err != nil:
+		return err
+	default:
+		if _, peekErr := r.in.Peek(1); peekErr == io.EOF {
+			r.done = true
+		}
+	}
+
+	plain, err := r.aead.Open(r.chunk[:0], chunkNonce(r.prefix, r.counter, r.done), r.chunk[:n], nil)
+	if err != nil {
+		return errors.Wrapf(ErrDecryption, "chunk %d of encrypted file is damaged (or key is wrong)", r.counter)
+	}
+	r.counter++
+	r.plain = plain
+
+	return nil
+}
+
+// downloadFileEncrypted download to <sha>.enc.temp (see tempFiles), content is encrypted during download
+// (plaintext never touches disk) and hash of plaintext is verified before rename to filepath
+//
+// encrypted temp file can't be resumed - each attempt starts from scratch
+func downloadFileEncrypted(httpClient httpClient, filepath pathutil.Path, url string, expectedSha hashutil.Hash, aead cipher.AEAD, attrs fileAttrs, staging tempFiles) (size int64, err error) {
+	tempfile, err := staging.path(filepath, expectedSha, ".enc")
+	if err != nil {
+		return 0, errors.Wrap(err, "Construct of temp file path fail")
+	}
+	temppath := tempfile.Canonpath()
+
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, StatusError{Sha: expectedSha, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	v, err := newVerificationOf(httpClient, expectedSha)
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := os.OpenFile(temppath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Create of tempfile %s fail", temppath)
+	}
+	defer func() {
+		if out != nil {
+			out.Close()
+		}
+		if err != nil {
+			os.Remove(temppath)
+		}
+	}()
+
+	enc, err := newEncryptWriter(out, aead)
+	if err != nil {
+		return 0, err
+	}
+
+	succ, err := copyAndVerify(resp, enc, v, expectedSha)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = enc.Close(); err != nil {
+		return 0, err
+	}
+
+	err = out.Close()
+	out = nil
+	if err != nil {
+		return 0, err
+	}
+
+	if err = attrs.apply(temppath); err != nil {
+		return 0, err
+	}
+
+	if err = os.Rename(temppath, filepath.Canonpath()); err != nil {
+		return 0, errors.Wrapf(err, "Rename temp %s to final path %s fail", temppath, filepath)
+	}
+
+	if err = os.Chtimes(filepath.Canonpath(), succ.lastModified, succ.lastModified); err != nil {
+		return 0, errors.Wrapf(err, "Chtimes(%s, %s) fail", filepath.Canonpath(), succ.lastModified.String())
+	}
+
+	return succ.size, nil
+}
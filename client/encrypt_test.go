@@ -0,0 +1,115 @@
+package storclient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var testEncryptionKey = []byte("0123456789abcdef0123456789abcdef")
+
+func encrypt(t *testing.T, plain []byte) []byte {
+	aead, err := newAEAD(testEncryptionKey)
+	assert.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	enc, err := newEncryptWriter(out, aead)
+	assert.NoError(t, err)
+	_, err = enc.Write(plain)
+	assert.NoError(t, err)
+	assert.NoError(t, enc.Close())
+
+	return out.Bytes()
+}
+
+func decrypt(encrypted []byte, key []byte) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(encrypted), key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(r)
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, encryptionChunkSize - 1, encryptionChunkSize, encryptionChunkSize + 1, 3 * encryptionChunkSize} {
+		plain := bytes.Repeat([]byte{'x'}, size)
+		encrypted := encrypt(t, plain)
+		assert.False(t, bytes.Contains(encrypted, []byte("xxxx")), "size %d is encrypted", size)
+
+		decrypted, err := decrypt(encrypted, testEncryptionKey)
+		assert.NoError(t, err, "size %d", size)
+		assert.Equal(t, plain, decrypted, "size %d", size)
+	}
+}
+
+func TestDecryptDamaged(t *testing.T) {
+	encrypted := encrypt(t, bytes.Repeat([]byte{'x'}, 2*encryptionChunkSize+10))
+
+	_, err := decrypt(encrypted, []byte("fedcba9876543210fedcba9876543210"))
+	assert.Equal(t, ErrDecryption, errors.Cause(err), "wrong key")
+
+	_, err = decrypt(encrypted[:len(encrypted)-20], testEncryptionKey)
+	assert.Equal(t, ErrDecryption, errors.Cause(err), "truncated last chunk")
+
+	chunk := len(encryptionMagic) + noncePrefixSize + encryptionChunkSize + 16
+	_, err = decrypt(encrypted[:chunk], testEncryptionKey)
+	assert.Equal(t, ErrDecryption, errors.Cause(err), "missing chunks")
+
+	damaged := append([]byte{}, encrypted...)
+	damaged[chunk+5] ^= 1
+	_, err = decrypt(damaged, testEncryptionKey)
+	assert.Equal(t, ErrDecryption, errors.Cause(err), "damaged chunk")
+
+	_, err = decrypt([]byte("plaintext file content"), testEncryptionKey)
+	assert.Equal(t, ErrDecryption, errors.Cause(err), "plaintext")
+}
+
+func TestEncryptedDownload(t *testing.T) {
+	content := strings.Repeat("secret sample ", 10000)
+	sha := contentHash(content)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "encrypt")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, tmpDir, StorClientOpts{RetryAttempts: 1, EncryptionKey: testEncryptionKey})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(sha))
+	assert.NoError(t, client.Download(contentHash("other")))
+	total := client.Wait()
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, 1, total.Failed(), "plaintext hash is verified")
+
+	encrypted, err := ioutil.ReadFile(filepath.Join(tmpDir, sha.String()))
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(encrypted, []byte("secret")))
+	decrypted, err := decrypt(encrypted, testEncryptionKey)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(decrypted))
+
+	files, err := filepath.Glob(filepath.Join(tmpDir, "*.temp"))
+	assert.NoError(t, err)
+	assert.Empty(t, files, "temp files are removed")
+
+	_, err = New(*serverURL, tmpDir, StorClientOpts{EncryptionKey: []byte("short")})
+	assert.Error(t, err, "invalid key")
+	_, err = New(*serverURL, tmpDir, StorClientOpts{EncryptionKey: testEncryptionKey, VerifyAfterRename: VerifyFull})
+	assert.Error(t, err)
+}
@@ -0,0 +1,116 @@
+package storclient
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/retry-go"
+	"github.com/pkg/errors"
+)
+
+// errors of download distinguishable by errors.Is - DownloadResult.Err (and error of DownloadTo)
+// wraps error of last attempt, so e.g. 404 from stor is told apart from network failure by
+//
+//	if errors.Is(result.Err, storclient.ErrNotFound) {
+//		// sha isn't on stor, retry is pointless
+//	}
+var (
+	// ErrNotFound is matched by error of download of sha which isn't on stor (404)
+	ErrNotFound = errors.New("not found on stor")
+	// ErrChecksumMismatch is matched by error of download whose content doesn't match expected sha
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrWrite is matched by error of write of downloaded content (to file or writer of DownloadTo)
+	ErrWrite = errors.New("write of downloaded content fail")
+	// ErrRetriesExhausted is matched by error of download whose retryable attempts were all used
+	// (RetryAttempts or MaxElapsedTime) - error of last attempt is still available by errors.Is/As
+	ErrRetriesExhausted = errors.New("retries exhausted")
+)
+
+// StatusError is returned if stor (or S3, mirror) responds with unexpected HTTP status,
+// use errors.As to get status code of failed download
+type StatusError struct {
+	Sha        hashutil.Hash
+	StatusCode int
+	Status     string
+}
+
+func (err StatusError) Error() string {
+	return fmt.Sprintf("Download of %s fail %d (%s)", err.Sha, err.StatusCode, err.Status)
+}
+
+// Is match ErrNotFound by 404
+func (err StatusError) Is(target error) bool {
+	return target == ErrNotFound && err.StatusCode == 404
+}
+
+// Is match ErrChecksumMismatch
+func (err shaMismatchError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+// Is match ErrWrite
+func (err writeError) Is(target error) bool {
+	return target == ErrWrite
+}
+
+func (err writeError) Unwrap() error {
+	return err.err
+}
+
+// errWriter wrap errors of w to writeError
+type errWriter struct {
+	w io.Writer
+}
+
+func (w errWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil {
+		return n, writeError{err: err}
+	}
+	return n, nil
+}
+
+// RetryError is error of download which failed in all attempts of default RetryEngine,
+// it unwraps to error of last attempt
+type RetryError struct {
+	// Attempts are errors of failed attempts in order
+	Attempts []error
+	// exhausted is true if retries stopped on retryable error
+	exhausted bool
+}
+
+func newRetryError(attemptErrors retry.Error, exhausted bool) RetryError {
+	attempts := make([]error, 0, len(attemptErrors))
+	for _, err := range attemptErrors {
+		if err != nil {
+			attempts = append(attempts, err)
+		}
+	}
+
+	return RetryError{Attempts: attempts, exhausted: exhausted}
+}
+
+func (err RetryError) Error() string {
+	lines := make([]string, len(err.Attempts))
+	for i, attemptErr := range err.Attempts {
+		lines[i] = fmt.Sprintf("#%d: %s", i+1, attemptErr)
+	}
+
+	return fmt.Sprintf("All attempts fail:\n%s", strings.Join(lines, "\n"))
+}
+
+// Unwrap return error of last attempt
+func (err RetryError) Unwrap() error {
+	if len(err.Attempts) == 0 {
+		return nil
+	}
+
+	return err.Attempts[len(err.Attempts)-1]
+}
+
+// Is match ErrRetriesExhausted if retries stopped on retryable error
+func (err RetryError) Is(target error) bool {
+	return target == ErrRetriesExhausted && err.exhausted
+}
@@ -0,0 +1,68 @@
+package storclient
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, os.ErrClosed
+}
+
+func TestTypedErrors(t *testing.T) {
+	found, missing, unavailable, wrong := contentHash("found"), contentHash("missing"), contentHash("unavailable"), contentHash("wrong")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path.Base(r.URL.Path) {
+		case found.String():
+			_, _ = w.Write([]byte("found"))
+		case wrong.String():
+			_, _ = w.Write([]byte("right"))
+		case unavailable.String():
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{RetryAttempts: 3, RetryDelay: 1})
+	assert.NoError(t, err)
+
+	_, err = client.DownloadTo(missing, &bytes.Buffer{})
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.False(t, errors.Is(err, ErrRetriesExhausted), "404 isn't retried")
+	var statusErr StatusError
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	assert.Equal(t, missing.String(), statusErr.Sha.String())
+
+	_, err = client.DownloadTo(unavailable, &bytes.Buffer{})
+	assert.True(t, errors.Is(err, ErrRetriesExhausted))
+	assert.False(t, errors.Is(err, ErrNotFound))
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+	var retryErr RetryError
+	assert.True(t, errors.As(err, &retryErr))
+	assert.Len(t, retryErr.Attempts, 3)
+
+	_, err = client.DownloadTo(wrong, &bytes.Buffer{})
+	assert.True(t, errors.Is(err, ErrChecksumMismatch))
+	assert.False(t, errors.Is(err, ErrRetriesExhausted), "wrong content isn't retried")
+
+	_, err = client.DownloadTo(found, failingWriter{})
+	assert.True(t, errors.Is(err, ErrWrite))
+	assert.True(t, errors.Is(err, os.ErrClosed))
+	assert.False(t, errors.Is(err, ErrNotFound))
+}
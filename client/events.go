@@ -0,0 +1,123 @@
+package storclient
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EventType is type of progress event
+type EventType string
+
+const (
+	// EventQueued - sha is added to download queue
+	EventQueued EventType = "queued"
+	// EventStarted - worker starts download of sha
+	EventStarted EventType = "started"
+	// EventFinished - sha is downloaded or skipped
+	EventFinished EventType = "finished"
+	// EventFailed - download of sha fail
+	EventFailed EventType = "failed"
+	// EventSummary - batch is done (emitted by Wait)
+	EventSummary EventType = "summary"
+)
+
+// Event is one progress event - serialized as one JSON line
+type Event struct {
+	Event    EventType `json:"event"`
+	Time     time.Time `json:"time"`
+	Sha      string    `json:"sha,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Status   string    `json:"status,omitempty"`
+	Size     int64     `json:"size,omitempty"`
+	Duration float64   `json:"duration,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	// captured response headers (see StorClientOpts.CaptureHeaders)
+	Header http.Header `json:"header,omitempty"`
+
+	// summary only
+	Expected   int                   `json:"expected,omitempty"`
+	Downloaded int                   `json:"downloaded,omitempty"`
+	Skipped    int                   `json:"skipped,omitempty"`
+	Metadata   int                   `json:"metadata,omitempty"`
+	Failed     int                   `json:"failed,omitempty"`
+	Bandwidth  []BandwidthWindow     `json:"bandwidth,omitempty"`
+	Mirrors    map[string]MirrorStat `json:"mirrors,omitempty"`
+}
+
+type eventWriter struct {
+	lock  sync.Mutex
+	enc   *json.Encoder
+	clock Clock
+}
+
+func newEventWriter(w io.Writer, clock Clock) *eventWriter {
+	if w == nil {
+		return nil
+	}
+
+	return &eventWriter{enc: json.NewEncoder(w), clock: clock}
+}
+
+// emit write event as JSON line, nil eventWriter is noop
+func (e *eventWriter) emit(event Event) {
+	if e == nil {
+		return
+	}
+
+	event.Time = e.clock.Now()
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if err := e.enc.Encode(event); err != nil {
+		log.Warningf("Write of %s event fail: %s", event.Event, err)
+	}
+}
+
+func (e *eventWriter) emitStat(sha string, stat DownStat, err error) {
+	e.emitResult(sha, DownloadResult{Size: stat.Size, Duration: stat.Duration, Status: stat.Status, Err: err})
+}
+
+func (e *eventWriter) emitResult(sha string, result DownloadResult) {
+	if e == nil {
+		return
+	}
+
+	event := Event{
+		Event:    EventFinished,
+		Sha:      sha,
+		Status:   result.Status.String(),
+		Size:     result.Size,
+		Duration: result.Duration.Seconds(),
+		Header:   result.Header,
+	}
+
+	if result.Status == DOWN_FAIL {
+		event.Event = EventFailed
+		if result.Err != nil {
+			event.Error = result.Err.Error()
+		}
+	}
+
+	e.emit(event)
+}
+
+func (e *eventWriter) emitSummary(total TotalStat) {
+	e.emit(Event{
+		Event:      EventSummary,
+		Size:       total.Size,
+		Duration:   total.Duration.Seconds(),
+		Expected:   total.expectedDownloadCount,
+		Downloaded: total.Count,
+		Skipped:    total.Skip,
+		Metadata:   total.Metadata,
+		Failed:     total.Failed(),
+		Bandwidth:  total.Bandwidth,
+		Mirrors:    total.Mirrors,
+	})
+}
@@ -0,0 +1,60 @@
+package storclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func readEvents(t *testing.T, buf *bytes.Buffer) []Event {
+	events := make([]Event, 0)
+
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var event Event
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func TestEventsFromWorker(t *testing.T) {
+	var buf bytes.Buffer
+
+	httpClient := func() httpClient { return &clientMock{statusCode: 200, status: "Ok"} }
+	downloadWorkersTestDownloadOK(t, StorClientOpts{EventWriter: &buf}, httpClient, []hashutil.Hash{emptyHash}, 1)
+
+	events := readEvents(t, &buf)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, EventStarted, events[0].Event)
+		assert.Equal(t, emptyHash.String(), events[0].Sha)
+		assert.Equal(t, EventFinished, events[1].Event)
+		assert.Equal(t, "ok", events[1].Status)
+	}
+}
+
+func TestEventWriter(t *testing.T) {
+	var nilWriter *eventWriter
+	nilWriter.emit(Event{Event: EventQueued})
+
+	var buf bytes.Buffer
+	events := newEventWriter(&buf, realClock{})
+
+	events.emitStat("abc", DownStat{Status: DOWN_FAIL}, errors.New("404"))
+	events.emitSummary(TotalStat{Count: 1, Skip: 1, Fail: 1, expectedDownloadCount: 3})
+
+	got := readEvents(t, &buf)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, EventFailed, got[0].Event)
+		assert.Equal(t, "404", got[0].Error)
+		assert.Equal(t, EventSummary, got[1].Event)
+		assert.Equal(t, 3, got[1].Expected)
+		assert.Equal(t, 1, got[1].Failed)
+	}
+}
@@ -0,0 +1,44 @@
+package storclient
+
+import "fmt"
+
+// conventional exit codes for CLI wrappers
+const (
+	// ExitOK - all files are downloaded (or skipped)
+	ExitOK = 0
+	// ExitPartialFailure - some files fail
+	ExitPartialFailure = 1
+	// ExitFatal - fatal or configuration error, batch wasn't processed
+	ExitFatal = 2
+)
+
+// ExitCode map batch outcome to conventional exit code
+//
+// err is fatal error (e.g. from New), if is set, ExitFatal is returned
+func ExitCode(total TotalStat, err error) int {
+	if err != nil {
+		return ExitFatal
+	}
+
+	if !total.Status() {
+		return ExitPartialFailure
+	}
+
+	return ExitOK
+}
+
+// Failed return count of files which fail
+func (total TotalStat) Failed() int {
+	return total.Fail
+}
+
+// Summary return one line human readable summary of batch
+func (total TotalStat) Summary() string {
+	return fmt.Sprintf("%d of %d files downloaded, %d skipped, %d failed (%0.3fMB)",
+		total.Count,
+		total.expectedDownloadCount,
+		total.Skip,
+		total.Failed(),
+		(float64)(total.Size)/(1024*1024),
+	)
+}
@@ -0,0 +1,27 @@
+package storclient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitOK, ExitCode(TotalStat{Count: 2, Skip: 1, expectedDownloadCount: 3}, nil))
+	assert.Equal(t, ExitPartialFailure, ExitCode(TotalStat{Count: 1, Fail: 2, expectedDownloadCount: 3}, nil))
+	assert.Equal(t, ExitFatal, ExitCode(TotalStat{}, errors.New("invalid template")))
+}
+
+func TestStatus(t *testing.T) {
+	assert.True(t, TotalStat{Count: 1, Skip: 1, Metadata: 1, expectedDownloadCount: 3}.Status())
+	assert.False(t, TotalStat{Count: 2, Fail: 1, expectedDownloadCount: 3}.Status())
+	assert.True(t, TotalStat{Count: 1, expectedDownloadCount: 2}.Status(), "only failures fail batch")
+}
+
+func TestSummary(t *testing.T) {
+	total := TotalStat{Size: 1024 * 1024, Count: 1, Skip: 1, Fail: 1, expectedDownloadCount: 3}
+
+	assert.Equal(t, 1, total.Failed())
+	assert.Equal(t, "1 of 3 files downloaded, 1 skipped, 1 failed (1.000MB)", total.Summary())
+}
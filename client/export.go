@@ -0,0 +1,96 @@
+package storclient
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// totalStatJSON is serialized form of TotalStat (see TotalStat.MarshalJSON)
+type totalStatJSON struct {
+	Expected     int     `json:"expected"`
+	Downloaded   int     `json:"downloaded"`
+	Skipped      int     `json:"skipped"`
+	Failed       int     `json:"failed"`
+	Metadata     int     `json:"metadata"`
+	Unverified   int     `json:"unverified"`
+	Abandoned    int     `json:"abandoned"`
+	Backpressure int     `json:"backpressure"`
+	Size         int64   `json:"size"`
+	Duration     float64 `json:"duration"`
+	Elapsed      float64 `json:"elapsed"`
+	Rate         float64 `json:"rate"`
+	Status       bool    `json:"status"`
+
+	Bandwidth []BandwidthWindow     `json:"bandwidth,omitempty"`
+	Mirrors   map[string]MirrorStat `json:"mirrors,omitempty"`
+	Workers   map[int]WorkerStat    `json:"workers,omitempty"`
+}
+
+// Rate return downloaded bytes per second of wall time (0 if Elapsed isn't known)
+func (total TotalStat) Rate() float64 {
+	if total.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(total.Size) / total.Elapsed.Seconds()
+}
+
+// MarshalJSON serialize counts (downloaded, skipped, failed, ...), size in bytes,
+// durations in seconds (sum of downloads and wall time), rate in bytes per second and
+// status of batch, with bandwidth, mirror and worker statistics
+func (total TotalStat) MarshalJSON() ([]byte, error) {
+	return json.Marshal(totalStatJSON{
+		Expected:     total.expectedDownloadCount,
+		Downloaded:   total.Count,
+		Skipped:      total.Skip,
+		Failed:       total.Fail,
+		Metadata:     total.Metadata,
+		Unverified:   total.Unverified,
+		Abandoned:    total.Abandoned,
+		Backpressure: total.Backpressure,
+		Size:         total.Size,
+		Duration:     total.Duration.Seconds(),
+		Elapsed:      total.Elapsed.Seconds(),
+		Rate:         total.Rate(),
+		Status:       total.Status(),
+		Bandwidth:    total.Bandwidth,
+		Mirrors:      total.Mirrors,
+		Workers:      total.Workers,
+	})
+}
+
+// totalStatCSVHeader is header row of TotalStat.WriteCSV
+var totalStatCSVHeader = []string{"expected", "downloaded", "skipped", "failed", "metadata", "unverified", "abandoned", "backpressure", "size", "duration", "elapsed", "rate", "status"}
+
+// WriteCSV write header row and row of totals (same columns as MarshalJSON without nested statistics)
+func (total TotalStat) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(totalStatCSVHeader); err != nil {
+		return err
+	}
+
+	err := writer.Write([]string{
+		strconv.Itoa(total.expectedDownloadCount),
+		strconv.Itoa(total.Count),
+		strconv.Itoa(total.Skip),
+		strconv.Itoa(total.Fail),
+		strconv.Itoa(total.Metadata),
+		strconv.Itoa(total.Unverified),
+		strconv.Itoa(total.Abandoned),
+		strconv.Itoa(total.Backpressure),
+		strconv.FormatInt(total.Size, 10),
+		strconv.FormatFloat(total.Duration.Seconds(), 'f', 3, 64),
+		strconv.FormatFloat(total.Elapsed.Seconds(), 'f', 3, 64),
+		strconv.FormatFloat(total.Rate(), 'f', 3, 64),
+		strconv.FormatBool(total.Status()),
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
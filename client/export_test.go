@@ -0,0 +1,51 @@
+package storclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTotalStatJSON(t *testing.T) {
+	total := TotalStat{
+		Size:                  2048,
+		Duration:              3 * time.Second,
+		Elapsed:               2 * time.Second,
+		Count:                 1,
+		Skip:                  1,
+		Fail:                  1,
+		Workers:               map[int]WorkerStat{0: {Count: 1, Size: 2048}},
+		expectedDownloadCount: 3,
+	}
+
+	content, err := json.Marshal(total)
+	assert.NoError(t, err)
+
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(content, &got))
+	assert.Equal(t, float64(3), got["expected"])
+	assert.Equal(t, float64(1), got["downloaded"])
+	assert.Equal(t, float64(1), got["skipped"])
+	assert.Equal(t, float64(1), got["failed"])
+	assert.Equal(t, float64(2048), got["size"])
+	assert.Equal(t, float64(3), got["duration"])
+	assert.Equal(t, float64(2), got["elapsed"])
+	assert.Equal(t, float64(1024), got["rate"])
+	assert.Equal(t, false, got["status"])
+	assert.Contains(t, got["workers"], "0")
+	assert.NotContains(t, got, "mirrors")
+}
+
+func TestTotalStatCSV(t *testing.T) {
+	total := TotalStat{Size: 2048, Duration: 3 * time.Second, Elapsed: 2 * time.Second, Count: 2, expectedDownloadCount: 2}
+
+	var buf bytes.Buffer
+	assert.NoError(t, total.WriteCSV(&buf))
+	assert.Equal(t,
+		"expected,downloaded,skipped,failed,metadata,unverified,abandoned,backpressure,size,duration,elapsed,rate,status\n"+
+			"2,2,0,0,0,0,0,0,2048,3.000,2.000,1024.000,true\n",
+		buf.String())
+}
@@ -0,0 +1,52 @@
+package storclient
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// FileOwner is owner of downloaded files (see StorClientOpts.FileOwner)
+type FileOwner struct {
+	UID int
+	GID int
+}
+
+// fileAttrs is mode and owner applied to downloaded file before rename to final path
+type fileAttrs struct {
+	mode  os.FileMode
+	owner *FileOwner
+}
+
+func newFileAttrs(mode os.FileMode, owner *FileOwner) (fileAttrs, error) {
+	if mode&^os.ModePerm != 0 {
+		return fileAttrs{}, errors.Errorf("file mode %s isn't only permission bits", mode)
+	}
+	if owner != nil && runtime.GOOS == "windows" {
+		return fileAttrs{}, errors.New("file owner isn't supported on windows")
+	}
+
+	return fileAttrs{mode: mode, owner: owner}, nil
+}
+
+func (attrs fileAttrs) empty() bool {
+	return attrs.mode == 0 && attrs.owner == nil
+}
+
+// apply set mode and owner of path, nothing is changed if attrs are empty
+func (attrs fileAttrs) apply(path string) error {
+	if attrs.mode != 0 {
+		if err := os.Chmod(path, attrs.mode); err != nil {
+			return errors.Wrapf(err, "Chmod(%s, %s) fail", path, attrs.mode)
+		}
+	}
+
+	if attrs.owner != nil {
+		if err := os.Chown(path, attrs.owner.UID, attrs.owner.GID); err != nil {
+			return errors.Wrapf(err, "Chown(%s, %d, %d) fail", path, attrs.owner.UID, attrs.owner.GID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package storclient_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileAttrs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits and owner aren't supported on windows")
+	}
+
+	content := "sample"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimPrefix(r.URL.Path, "/") != sha256Of(content).String() {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+	storUrl, _ := url.Parse(server.URL)
+
+	tmpDir, err := ioutil.TempDir("", "fileattrs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	owner := &storclient.FileOwner{UID: os.Getuid(), GID: os.Getgid()}
+	client, err := storclient.New(*storUrl, tmpDir, storclient.StorClientOpts{FileMode: 0604, FileOwner: owner})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(sha256Of(content)))
+	total := client.Wait()
+	assert.True(t, total.Status())
+
+	info, err := os.Stat(filepath.Join(tmpDir, sha256Of(content).String()))
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0604), info.Mode().Perm())
+	}
+}
+
+func TestFileAttrsInvalidMode(t *testing.T) {
+	_, err := storclient.New(url.URL{}, "", storclient.StorClientOpts{FileMode: os.ModeSetuid | 0755})
+	assert.Error(t, err)
+}
@@ -0,0 +1,466 @@
+package storclient
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// GRPCScheme is scheme of storage url (or mirror) of stor gateway with gRPC interface (plaintext HTTP/2)
+	GRPCScheme = "grpc"
+	// GRPCSScheme is scheme of stor gateway with gRPC interface over TLS (see StorClientOpts.TLS)
+	GRPCSScheme = "grpcs"
+)
+
+// GRPCDownloadMethod is full name of streaming RPC of download of object
+//
+//	service Stor {
+//		rpc DownloadObject(DownloadObjectRequest) returns (stream DownloadObjectChunk);
+//	}
+//
+//	message DownloadObjectRequest {
+//		string sha = 1;   // hex hash of object
+//		int64 offset = 2; // first byte of content (resume of download)
+//	}
+//
+//	message DownloadObjectChunk {
+//		bytes data = 1;
+//		int64 size = 2;          // size of object (first chunk only)
+//		int64 last_modified = 3; // unix time of object (first chunk only)
+//	}
+//
+// headers of request (e.g. Authorization of StorClientOpts.Auth) are sent as metadata,
+// gRPC status is mapped to HTTP status (NotFound to 404 etc.), so retries, verification
+// and stats work as with HTTP stor
+const GRPCDownloadMethod = "/stor.Stor/DownloadObject"
+
+// isGRPC return true if u is url of stor gateway with gRPC interface
+func isGRPC(u url.URL) bool {
+	return u.Scheme == GRPCScheme || u.Scheme == GRPCSScheme
+}
+
+// usesGRPC return true if storage url or any of mirrors is stor gateway with gRPC interface
+func usesGRPC(storageUrl url.URL, mirrors []url.URL) bool {
+	if isGRPC(storageUrl) {
+		return true
+	}
+
+	for _, mirror := range mirrors {
+		if isGRPC(mirror) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// grpcTransport serve GET of grpc:// (and grpcs://) urls by GRPCDownloadMethod,
+// other requests are passed to next; one connection (multiplexing all workers) is kept per host
+type grpcTransport struct {
+	next  http.RoundTripper
+	tls   *tls.Config
+	lock  sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+func newGRPCTransport(next http.RoundTripper, tlsConfig *tls.Config) *grpcTransport {
+	return &grpcTransport{next: next, tls: tlsConfig, conns: make(map[string]*grpc.ClientConn)}
+}
+
+func (t *grpcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isGRPC(*req.URL) {
+		return t.next.RoundTrip(req)
+	}
+
+	if req.Method != http.MethodGet {
+		return grpcResponse(req, http.StatusNotImplemented, ioutil.NopCloser(strings.NewReader(req.Method+" isn't supported by gRPC stor"))), nil
+	}
+
+	conn, err := t.conn(*req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := rangeOffset(req.Header.Get("Range"))
+
+	ctx, cancel := context.WithCancel(req.Context())
+	ctx = metadata.NewOutgoingContext(ctx, grpcMetadata(req.Header))
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, GRPCDownloadMethod, grpc.CallCustomCodec(grpcCodec{}))
+	if err != nil {
+		cancel()
+		return grpcErrorResponse(req, err)
+	}
+
+	request := &downloadObjectRequest{Sha: path.Base(req.URL.Path), Offset: offset}
+	// io.EOF means stream is already closed by stor, its status is returned by RecvMsg
+	if err := stream.SendMsg(request); err != nil && err != io.EOF {
+		cancel()
+		return grpcErrorResponse(req, err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		cancel()
+		return grpcErrorResponse(req, err)
+	}
+
+	// first chunk carries size and status of object
+	first := &downloadObjectChunk{}
+	err = stream.RecvMsg(first)
+	if err != nil && err != io.EOF {
+		cancel()
+		return grpcErrorResponse(req, err)
+	}
+
+	body := &grpcBody{stream: stream, buf: first.Data, cancel: cancel}
+	if err == io.EOF {
+		body.err = io.EOF
+	}
+
+	statusCode := http.StatusOK
+	if offset > 0 {
+		statusCode = http.StatusPartialContent
+	}
+
+	resp := grpcResponse(req, statusCode, body)
+	if offset > 0 {
+		resp.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, first.Size-1, first.Size))
+	}
+	if first.Size > 0 {
+		resp.ContentLength = first.Size - offset
+		resp.Header.Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+	}
+	if first.LastModified > 0 {
+		resp.Header.Set("Last-Modified", time.Unix(first.LastModified, 0).UTC().Format(http.TimeFormat))
+	}
+
+	return resp, nil
+}
+
+// conn return (pooled) connection to host of u
+func (t *grpcTransport) conn(u url.URL) (*grpc.ClientConn, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	target := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == GRPCSScheme {
+			port = "443"
+		}
+		target = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	key := u.Scheme + "://" + target
+	if conn, ok := t.conns[key]; ok {
+		return conn, nil
+	}
+
+	security := grpc.WithInsecure()
+	if u.Scheme == GRPCSScheme {
+		tlsConfig := &tls.Config{}
+		if t.tls != nil {
+			tlsConfig = t.tls.Clone()
+		}
+		security = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+
+	conn, err := grpc.Dial(target, security)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Dial of gRPC stor %s fail", target)
+	}
+	t.conns[key] = conn
+
+	return conn, nil
+}
+
+// CloseIdleConnections close all connections, next request dials again
+func (t *grpcTransport) CloseIdleConnections() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for key, conn := range t.conns {
+		_ = conn.Close()
+		delete(t.conns, key)
+	}
+}
+
+// rangeOffset return offset of "bytes=<offset>-" range (resume of download), 0 otherwise
+func rangeOffset(header string) int64 {
+	if !strings.HasPrefix(header, "bytes=") || !strings.HasSuffix(header, "-") {
+		return 0
+	}
+
+	offset, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(header, "bytes="), "-"), 10, 64)
+	if err != nil || offset < 0 {
+		return 0
+	}
+
+	return offset
+}
+
+// grpcMetadata return headers of request as gRPC metadata, headers of HTTP transport are skipped
+func grpcMetadata(header http.Header) metadata.MD {
+	md := metadata.MD{}
+	for key, values := range header {
+		switch key {
+		case "Range", "If-Range", "Accept-Encoding", "Connection":
+			continue
+		}
+		md[strings.ToLower(key)] = values
+	}
+
+	return md
+}
+
+func grpcResponse(req *http.Request, statusCode int, body io.ReadCloser) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/2.0",
+		ProtoMajor:    2,
+		Header:        make(http.Header),
+		Body:          body,
+		ContentLength: -1,
+		Request:       req,
+	}
+}
+
+// grpcErrorResponse return response with HTTP status of gRPC error,
+// error without HTTP equivalent (e.g. canceled request) is returned as error
+func grpcErrorResponse(req *http.Request, err error) (*http.Response, error) {
+	statusCode, ok := map[codes.Code]int{
+		codes.InvalidArgument:   http.StatusBadRequest,
+		codes.Unauthenticated:   http.StatusUnauthorized,
+		codes.PermissionDenied:  http.StatusForbidden,
+		codes.NotFound:          http.StatusNotFound,
+		codes.OutOfRange:        http.StatusRequestedRangeNotSatisfiable,
+		codes.ResourceExhausted: http.StatusTooManyRequests,
+		codes.Internal:          http.StatusInternalServerError,
+		codes.Unknown:           http.StatusInternalServerError,
+		codes.Unimplemented:     http.StatusNotImplemented,
+		codes.Unavailable:       http.StatusServiceUnavailable,
+		codes.DeadlineExceeded:  http.StatusGatewayTimeout,
+	}[status.Code(err)]
+	if !ok {
+		return nil, err
+	}
+
+	return grpcResponse(req, statusCode, ioutil.NopCloser(strings.NewReader(status.Convert(err).Message()))), nil
+}
+
+// grpcBody is body of response read from stream of chunks
+type grpcBody struct {
+	stream grpc.ClientStream
+	buf    []byte
+	err    error
+	cancel context.CancelFunc
+}
+
+func (body *grpcBody) Read(p []byte) (int, error) {
+	for len(body.buf) == 0 {
+		if body.err != nil {
+			return 0, body.err
+		}
+
+		chunk := &downloadObjectChunk{}
+		if err := body.stream.RecvMsg(chunk); err != nil {
+			body.err = err
+			if err != io.EOF {
+				body.err = errors.Wrap(err, "gRPC stream of object fail")
+			}
+			continue
+		}
+		body.buf = chunk.Data
+	}
+
+	n := copy(p, body.buf)
+	body.buf = body.buf[n:]
+
+	return n, nil
+}
+
+func (body *grpcBody) Close() error {
+	body.cancel()
+	return nil
+}
+
+// downloadObjectRequest is DownloadObjectRequest message (see GRPCDownloadMethod)
+type downloadObjectRequest struct {
+	Sha    string
+	Offset int64
+}
+
+// downloadObjectChunk is DownloadObjectChunk message (see GRPCDownloadMethod)
+type downloadObjectChunk struct {
+	Data         []byte
+	Size         int64
+	LastModified int64
+}
+
+// grpcMessage is message encoded by grpcCodec
+type grpcMessage interface {
+	marshal() []byte
+	unmarshal(data []byte) error
+}
+
+func (m *downloadObjectRequest) marshal() []byte {
+	b := appendProtoBytes(nil, 1, []byte(m.Sha))
+	return appendProtoVarint(b, 2, uint64(m.Offset))
+}
+
+func (m *downloadObjectRequest) unmarshal(data []byte) error {
+	return decodeProto(data, func(field int, varint uint64, bytes []byte) {
+		switch field {
+		case 1:
+			m.Sha = string(bytes)
+		case 2:
+			m.Offset = int64(varint)
+		}
+	})
+}
+
+func (m *downloadObjectChunk) marshal() []byte {
+	b := appendProtoBytes(nil, 1, m.Data)
+	b = appendProtoVarint(b, 2, uint64(m.Size))
+	return appendProtoVarint(b, 3, uint64(m.LastModified))
+}
+
+func (m *downloadObjectChunk) unmarshal(data []byte) error {
+	return decodeProto(data, func(field int, varint uint64, bytes []byte) {
+		switch field {
+		case 1:
+			m.Data = bytes
+		case 2:
+			m.Size = int64(varint)
+		case 3:
+			m.LastModified = int64(varint)
+		}
+	})
+}
+
+// grpcCodec encode messages of GRPCDownloadMethod in protobuf wire format,
+// so gateway with generated protobuf code is talked to without generated code here
+type grpcCodec struct{}
+
+func (grpcCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(grpcMessage)
+	if !ok {
+		return nil, errors.Errorf("unsupported gRPC message %T", v)
+	}
+
+	return m.marshal(), nil
+}
+
+func (grpcCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(grpcMessage)
+	if !ok {
+		return errors.Errorf("unsupported gRPC message %T", v)
+	}
+
+	return m.unmarshal(data)
+}
+
+// Name is content subtype of messages (application/grpc+proto)
+func (grpcCodec) Name() string {
+	return "proto"
+}
+
+func (c grpcCodec) String() string {
+	return c.Name()
+}
+
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// appendProtoVarint append varint field (zero value is omitted)
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+
+	b = appendUvarint(b, uint64(field)<<3|protoVarint)
+	return appendUvarint(b, v)
+}
+
+// appendProtoBytes append length-delimited field (empty value is omitted)
+func appendProtoBytes(b []byte, field int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+
+	b = appendUvarint(b, uint64(field)<<3|protoBytes)
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+// decodeProto call field for each varint and length-delimited field of message, other fields are skipped
+func decodeProto(data []byte, field func(field int, varint uint64, bytes []byte)) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("invalid protobuf field key")
+		}
+		data = data[n:]
+
+		number := int(key >> 3)
+		switch key & 7 {
+		case protoVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return errors.Errorf("invalid protobuf varint of field %d", number)
+			}
+			data = data[n:]
+			field(number, v, nil)
+		case protoBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return errors.Errorf("invalid protobuf length of field %d", number)
+			}
+			field(number, 0, data[n:n+int(length)])
+			data = data[n+int(length):]
+		case protoFixed64:
+			if len(data) < 8 {
+				return errors.Errorf("invalid protobuf fixed64 of field %d", number)
+			}
+			data = data[8:]
+		case protoFixed32:
+			if len(data) < 4 {
+				return errors.Errorf("invalid protobuf fixed32 of field %d", number)
+			}
+			data = data[4:]
+		default:
+			return errors.Errorf("unsupported protobuf wire type %d of field %d", key&7, number)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcStor serve objects by GRPCDownloadMethod in chunks of 3 bytes, it records authorization metadata
+type grpcStor struct {
+	objects       map[string]string
+	lock          sync.Mutex
+	authorization []string
+}
+
+func (stor *grpcStor) downloadObject(srv interface{}, stream grpc.ServerStream) error {
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		stor.lock.Lock()
+		stor.authorization = append(stor.authorization, md.Get("authorization")...)
+		stor.lock.Unlock()
+	}
+
+	request := &downloadObjectRequest{}
+	if err := stream.RecvMsg(request); err != nil {
+		return err
+	}
+
+	content, ok := stor.objects[request.Sha]
+	if !ok {
+		return status.Errorf(codes.NotFound, "%s isn't on stor", request.Sha)
+	}
+
+	first := &downloadObjectChunk{Size: int64(len(content)), LastModified: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC).Unix()}
+	content = content[request.Offset:]
+	for {
+		chunk := &downloadObjectChunk{}
+		if first != nil {
+			chunk, first = first, nil
+		}
+		n := 3
+		if len(content) < n {
+			n = len(content)
+		}
+		chunk.Data, content = []byte(content[:n]), content[n:]
+		if err := stream.SendMsg(chunk); err != nil {
+			return err
+		}
+		if len(content) == 0 {
+			return nil
+		}
+	}
+}
+
+func startGRPCStor(t *testing.T, stor *grpcStor) (*url.URL, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server := grpc.NewServer(grpc.CustomCodec(grpcCodec{}))
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "stor.Stor",
+		HandlerType: (*interface{})(nil),
+		Streams:     []grpc.StreamDesc{{StreamName: "DownloadObject", Handler: stor.downloadObject, ServerStreams: true}},
+	}, struct{}{})
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	return &url.URL{Scheme: GRPCScheme, Host: listener.Addr().String()}, server.Stop
+}
+
+func TestGRPC(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "grpc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	sha := contentHash("object from gRPC stor")
+	stor := &grpcStor{objects: map[string]string{sha.String(): "object from gRPC stor"}}
+	storURL, stop := startGRPCStor(t, stor)
+	defer stop()
+
+	client, err := New(*storURL, tmpDir, StorClientOpts{RetryAttempts: 1, Auth: &Auth{BearerToken: "token"}})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(sha))
+	assert.NoError(t, client.Download(contentHash("missing")))
+	total := client.Wait()
+
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, 1, total.Fail)
+
+	content, err := ioutil.ReadFile(filepath.Join(tmpDir, sha.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, "object from gRPC stor", string(content))
+
+	info, err := os.Stat(filepath.Join(tmpDir, sha.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), info.ModTime().UTC())
+
+	assert.Equal(t, []string{"Bearer token", "Bearer token"}, stor.authorization)
+}
+
+func TestGRPCTransport(t *testing.T) {
+	stor := &grpcStor{objects: map[string]string{"object": "0123456789"}}
+	storURL, stop := startGRPCStor(t, stor)
+	defer stop()
+
+	transport := newGRPCTransport(http.DefaultTransport, nil)
+	defer transport.CloseIdleConnections()
+	httpClient := &http.Client{Transport: transport}
+
+	req, err := http.NewRequest(http.MethodGet, storURL.String()+"/object", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Range", "bytes=4-")
+
+	resp, err := httpClient.Do(req)
+	assert.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, resp.Body.Close())
+
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "456789", string(body))
+	assert.Equal(t, "bytes 4-9/10", resp.Header.Get("Content-Range"))
+	assert.Equal(t, int64(6), resp.ContentLength)
+
+	resp, err = httpClient.Get(storURL.String() + "/missing")
+	assert.NoError(t, err)
+	assert.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = httpClient.Head(storURL.String() + "/object")
+	assert.NoError(t, err)
+	assert.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+}
+
+func TestGRPCCodec(t *testing.T) {
+	codec := grpcCodec{}
+
+	data, err := codec.Marshal(&downloadObjectChunk{Data: []byte("data"), Size: 300, LastModified: 1577934245})
+	assert.NoError(t, err)
+
+	chunk := &downloadObjectChunk{}
+	assert.NoError(t, codec.Unmarshal(data, chunk))
+	assert.Equal(t, &downloadObjectChunk{Data: []byte("data"), Size: 300, LastModified: 1577934245}, chunk)
+
+	// unknown fields (fixed64 of field 9) are skipped
+	request := &downloadObjectRequest{}
+	assert.NoError(t, codec.Unmarshal([]byte{0x0a, 0x01, 'a', 0x49, 1, 2, 3, 4, 5, 6, 7, 8, 0x10, 0x05}, request))
+	assert.Equal(t, &downloadObjectRequest{Sha: "a", Offset: 5}, request)
+
+	assert.Error(t, codec.Unmarshal([]byte{0x0a, 0x05, 'a'}, request))
+	_, err = codec.Marshal("not a message")
+	assert.Error(t, err)
+}
+
+func TestGRPCOpts(t *testing.T) {
+	storURL, _ := url.Parse("grpc://stor.domain.tld")
+
+	_, err := New(*storURL, os.TempDir(), StorClientOpts{DryRun: true})
+	assert.Error(t, err)
+
+	client, err := New(url.URL{Scheme: "http", Host: "stor.domain.tld"}, os.TempDir(), StorClientOpts{Mirrors: []url.URL{*storURL}})
+	assert.NoError(t, err)
+	assert.NotNil(t, client.grpc, "gRPC mirror")
+}
@@ -0,0 +1,95 @@
+package storclient
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidHash is cause of all ParseHash errors
+var ErrInvalidHash = errors.New("invalid hash")
+
+// ParseHash parse sha256 from string in any of supported formats and normalize it
+//
+//   - hex in any case - e.g. EE2BF0BF...B72B8 or ee2bf0bf...b72b8
+//   - with algorithm prefix - e.g. sha256:ee2bf0bf...b72b8 or SHA256-ee2bf0bf...b72b8
+//   - base64 (standard or URL alphabet, with or without padding)
+//
+// ambiguous or malformed values (unknown algorithm, mixed base64 alphabets, wrong length) are rejected
+func ParseHash(s string) (hashutil.Hash, error) {
+	return ParseHashAlgorithm(s, SHA256)
+}
+
+// ParseHashAlgorithm parse hash of given algorithm like ParseHash
+//
+// algorithm prefix (if is present) must match algorithm
+func ParseHashAlgorithm(s string, algorithm HashAlgorithm) (hashutil.Hash, error) {
+	if !algorithm.Valid() {
+		return hashutil.Hash{}, errors.Wrapf(ErrInvalidHash, "unsupported algorithm %q", algorithm)
+	}
+
+	value := strings.TrimSpace(s)
+
+	if prefix, rest, ok := splitAlgorithmPrefix(value); ok {
+		if HashAlgorithm(prefix) != algorithm {
+			return hashutil.Hash{}, errors.Wrapf(ErrInvalidHash, "unsupported algorithm %q in %q (expected %s)", prefix, s, algorithm)
+		}
+		value = rest
+	}
+
+	if len(value) == hex.EncodedLen(algorithm.Size()) {
+		b, err := hex.DecodeString(strings.ToLower(value))
+		if err != nil {
+			return hashutil.Hash{}, errors.Wrapf(ErrInvalidHash, "%q isn't hex: %s", s, err)
+		}
+
+		return hashutil.BytesToHash(algorithm.New(), b)
+	}
+
+	b, err := decodeBase64(value)
+	if err != nil {
+		return hashutil.Hash{}, errors.Wrapf(ErrInvalidHash, "%q is neither hex nor base64 %s: %s", s, algorithm, err)
+	}
+
+	if len(b) != algorithm.Size() {
+		return hashutil.Hash{}, errors.Wrapf(ErrInvalidHash, "%q has %d bytes, %s has %d bytes", s, len(b), algorithm, algorithm.Size())
+	}
+
+	return hashutil.BytesToHash(algorithm.New(), b)
+}
+
+var knownAlgorithms = []string{"md5", "sha1", "sha224", "sha256", "sha384", "sha512"}
+
+// splitAlgorithmPrefix split "algorithm:value" (any algorithm)
+// or "algorithm-value" (known algorithms only, because '-' is valid base64 character)
+func splitAlgorithmPrefix(value string) (algorithm, rest string, ok bool) {
+	if idx := strings.Index(value, ":"); idx >= 0 {
+		return strings.ToLower(value[:idx]), value[idx+1:], true
+	}
+
+	for _, known := range knownAlgorithms {
+		if len(value) > len(known) && strings.EqualFold(value[:len(known)], known) && value[len(known)] == '-' {
+			return known, value[len(known)+1:], true
+		}
+	}
+
+	return "", value, false
+}
+
+func decodeBase64(value string) ([]byte, error) {
+	std := strings.ContainsAny(value, "+/")
+	url := strings.ContainsAny(value, "-_")
+	if std && url {
+		return nil, errors.New("mixed standard and URL base64 alphabets")
+	}
+
+	encoding := base64.StdEncoding
+	if url {
+		encoding = base64.URLEncoding
+	}
+
+	return encoding.WithPadding(base64.NoPadding).DecodeString(strings.TrimRight(value, "="))
+}
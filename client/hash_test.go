@@ -0,0 +1,54 @@
+package storclient
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHash(t *testing.T) {
+	sum := sha256.Sum256([]byte("sample"))
+	expected := hex.EncodeToString(sum[:])
+
+	valid := []string{
+		expected,
+		" " + expected + "\n",
+		strings.ToUpper(expected),
+		"sha256:" + expected,
+		"SHA256-" + strings.ToUpper(expected),
+		base64.StdEncoding.EncodeToString(sum[:]),
+		base64.RawStdEncoding.EncodeToString(sum[:]),
+		base64.URLEncoding.EncodeToString(sum[:]),
+		"sha256:" + base64.RawURLEncoding.EncodeToString(sum[:]),
+	}
+
+	for _, s := range valid {
+		hash, err := ParseHash(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, hash.String(), s)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"nosha",
+		"md5:" + expected,
+		"foo:" + expected,
+		expected[:62],
+		"zz" + expected[2:],
+		base64.StdEncoding.EncodeToString(sum[:16]),
+		"+_" + base64.RawStdEncoding.EncodeToString(sum[:])[2:],
+	}
+
+	for _, s := range invalid {
+		_, err := ParseHash(s)
+		if assert.Error(t, err, s) {
+			assert.Equal(t, ErrInvalidHash, errors.Cause(err), s)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package storclient
+
+import "net/http"
+
+// headerCaptureClient is httpClient which keeps selected headers of last response
+type headerCaptureClient struct {
+	httpClient
+	names  []string
+	header http.Header
+}
+
+func (c *headerCaptureClient) Get(url string) (*http.Response, error) {
+	resp, err := c.httpClient.Get(url)
+	if resp != nil {
+		c.header = captureHeader(resp.Header, c.names)
+	}
+
+	return resp, err
+}
+
+func (c *headerCaptureClient) unwrap() httpClient {
+	return c.httpClient
+}
+
+// captureHeader return copy of selected headers which are present in header
+func captureHeader(header http.Header, names []string) http.Header {
+	captured := make(http.Header)
+	for _, name := range names {
+		if values, ok := header[http.CanonicalHeaderKey(name)]; ok {
+			captured[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+		}
+	}
+
+	return captured
+}
+
+// headerTransport add headers (and User-Agent) to all requests, headers set by request
+// (e.g. header of tenant) have priority
+type headerTransport struct {
+	next      http.RoundTripper
+	header    http.Header
+	userAgent string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripper must not modify request
+	req = req.Clone(req.Context())
+
+	for key, values := range t.header {
+		if _, ok := req.Header[key]; !ok {
+			req.Header[key] = values
+		}
+	}
+
+	if t.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,97 @@
+package storclient_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptureHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", "node-1")
+		w.Header().Set("Etag", `"abc"`)
+		w.Header().Set("X-Other", "ignored")
+		if r.URL.Path == "/"+sha256Of("first").String() {
+			w.Write([]byte("first"))
+			return
+		}
+		w.Write([]byte("corrupted"))
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "headers")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, tmpDir, storclient.StorClientOpts{
+		RetryAttempts:  1,
+		CaptureHeaders: []string{"x-served-by", "ETag", "Age"},
+	})
+	assert.NoError(t, err)
+
+	results := client.Results()
+	client.Start()
+
+	collected := make(map[string]storclient.DownloadResult)
+	done := make(chan struct{})
+	go func() {
+		for result := range results {
+			collected[result.Sha.String()] = result
+		}
+		close(done)
+	}()
+
+	assert.NoError(t, client.Download(sha256Of("first")))
+	assert.NoError(t, client.Download(sha256Of("second")))
+	client.Wait()
+	<-done
+
+	expected := http.Header{"X-Served-By": {"node-1"}, "Etag": {`"abc"`}}
+
+	ok := collected[sha256Of("first").String()]
+	assert.Equal(t, storclient.DOWN_OK, ok.Status)
+	assert.Equal(t, expected, ok.Header)
+
+	corrupted := collected[sha256Of("second").String()]
+	assert.Equal(t, storclient.DOWN_FAIL, corrupted.Status)
+	assert.Equal(t, expected, corrupted.Header, "headers of node which served corrupted content")
+}
+
+func TestHeadersAndUserAgent(t *testing.T) {
+	requests := make(chan http.Header, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Header
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, os.TempDir(), storclient.StorClientOpts{
+		Devnull:   true,
+		Headers:   http.Header{"x-gateway-route": {"samples"}, "X-Tenant": {"default"}},
+		UserAgent: "stor-client/test",
+	})
+	assert.NoError(t, err)
+
+	tenant := client.WithTenant(os.TempDir(), *serverURL, http.Header{"X-Tenant": {"other"}})
+
+	client.Start()
+	assert.NoError(t, client.Download(sha256Of("")))
+	header := <-requests
+	assert.NoError(t, tenant.Download(sha256Of("")))
+	tenantHeader := <-requests
+	client.Wait()
+
+	assert.Equal(t, "stor-client/test", header.Get("User-Agent"))
+	assert.Equal(t, "samples", header.Get("X-Gateway-Route"))
+	assert.Equal(t, "default", header.Get("X-Tenant"))
+
+	assert.Equal(t, "stor-client/test", tenantHeader.Get("User-Agent"))
+	assert.Equal(t, "other", tenantHeader.Get("X-Tenant"), "header of tenant has priority")
+}
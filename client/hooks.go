@@ -0,0 +1,40 @@
+package storclient
+
+import "net/http"
+
+// RequestHook is called with every request just before it is sent (after authentication and headers are added),
+// request can be modified (e.g. signed); error aborts request (attempt fails and is retried)
+type RequestHook func(req *http.Request) error
+
+// ResponseHook is called with every response (e.g. to record cache headers) before its body is read;
+// error fails attempt (it's retried), body is closed
+type ResponseHook func(resp *http.Response) error
+
+// hookTransport call request and response hooks around next transport
+type hookTransport struct {
+	next     http.RoundTripper
+	request  RequestHook
+	response ResponseHook
+}
+
+func (t *hookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.request != nil {
+		// RoundTripper must not modify request
+		req = req.Clone(req.Context())
+		if err := t.request(req); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || t.response == nil {
+		return resp, err
+	}
+
+	if err := t.response(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,73 @@
+package storclient_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestResponseHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Signature") != "signed "+r.URL.Path+" "+r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("X-Cache", "HIT")
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	var lock sync.Mutex
+	cacheHeaders := make([]string, 0)
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "", storclient.StorClientOpts{
+		Auth: &storclient.Auth{BearerToken: "token"},
+		RequestHook: func(req *http.Request) error {
+			// authorization is already set
+			req.Header.Set("X-Signature", "signed "+req.URL.Path+" "+req.Header.Get("Authorization"))
+			return nil
+		},
+		ResponseHook: func(resp *http.Response) error {
+			lock.Lock()
+			defer lock.Unlock()
+
+			cacheHeaders = append(cacheHeaders, resp.Header.Get("X-Cache"))
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	content, err := client.DownloadBytes(sha256Of("content"))
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+	assert.Equal(t, []string{"HIT"}, cacheHeaders)
+}
+
+func TestResponseHookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	calls := 0
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "", storclient.StorClientOpts{
+		RetryAttempts: 2,
+		ResponseHook: func(resp *http.Response) error {
+			calls++
+			return errors.New("rejected by hook")
+		},
+	})
+	assert.NoError(t, err)
+
+	_, err = client.DownloadBytes(sha256Of("content"))
+	assert.Error(t, err)
+	assert.Equal(t, 2, calls, "hook error is retried")
+}
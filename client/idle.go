@@ -0,0 +1,93 @@
+package storclient
+
+import (
+	"sync"
+	"time"
+)
+
+// workerPool tracks running download workers, so workers can be scaled down to zero
+// when idle (see StorClientOpts.IdleWorkerTimeout) and recreated on demand by pushes
+type workerPool struct {
+	lock    sync.Mutex
+	running int
+	nextID  int
+}
+
+// spawnWorker start new download worker, caller must hold workers.lock
+func (client *StorClient) spawnWorker() {
+	client.workers.running++
+	id := client.workers.nextID
+	client.workers.nextID++
+
+	client.wg.Add(1)
+	go client.downloadWorker(id, client.httpClientFunc, client.queue, client.pool.output)
+}
+
+// ensureWorker start worker (up to Max) for pushed job, it's called by queue under its lock,
+// so no worker is spawned after queue is closed
+func (client *StorClient) ensureWorker() {
+	client.workers.lock.Lock()
+	defer client.workers.lock.Unlock()
+
+	if client.workers.running < client.Max {
+		client.spawnWorker()
+	}
+}
+
+// retireWorker return true if idle worker can end - it can't if there are pending jobs
+// (pushed concurrently with idle timeout), last retired worker closes idle connections
+//
+// queue is checked under workers.lock, so push either sees this worker running
+// or is seen here (ensureWorker takes the same lock)
+func (client *StorClient) retireWorker(jobs *priorityQueue) bool {
+	client.workers.lock.Lock()
+	defer client.workers.lock.Unlock()
+
+	if jobs.len() > 0 {
+		return false
+	}
+
+	client.workers.running--
+	if client.workers.running == 0 {
+		client.closeIdleConnections()
+	}
+
+	return true
+}
+
+// nextJob return next job with highest priority for worker,
+// ok is false if queue is closed and empty or worker is idle too long
+func (client *StorClient) nextJob(queue *downloadQueue) (job downloadJob, ok bool) {
+	jobs := queue.jobs
+	if job, ok := jobs.tryPop(); ok {
+		return job, true
+	}
+
+	var shrink chan struct{}
+	if client.autoscaler != nil {
+		shrink = client.autoscaler.shrink
+	}
+
+	for {
+		var idle <-chan time.Time
+		if client.IdleWorkerTimeout > 0 {
+			idle = client.Clock.After(client.IdleWorkerTimeout)
+		}
+
+		select {
+		case <-jobs.ready:
+			return jobs.pop(), true
+		case <-jobs.closed:
+			// queue is closed, so no job is pushed anymore
+			return jobs.tryPop()
+		case <-idle:
+			if client.retireWorker(jobs) {
+				return downloadJob{}, false
+			}
+		case <-shrink:
+			if client.shrinkWorker(jobs) {
+				return downloadJob{}, false
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package storclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runningWorkers(client *StorClient) int {
+	client.workers.lock.Lock()
+	defer client.workers.lock.Unlock()
+
+	return client.workers.running
+}
+
+func TestIdleWorkers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{Max: 3, Devnull: true, IdleWorkerTimeout: 20 * time.Millisecond})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.Equal(t, 3, runningWorkers(client))
+
+	waitForIdle := func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for runningWorkers(client) > 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		assert.Equal(t, 0, runningWorkers(client), "idle workers end")
+	}
+
+	waitForIdle()
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, client.Download(emptyHash))
+	}
+	assert.True(t, runningWorkers(client) <= 3, "workers are recreated up to Max")
+
+	waitForIdle()
+
+	assert.NoError(t, client.Download(emptyHash))
+	total := client.Wait()
+
+	assert.Equal(t, 6, total.Count+total.Skip)
+}
+
+func TestRetireWorkerWithQueuedJob(t *testing.T) {
+	client, err := New(url.URL{}, os.TempDir(), StorClientOpts{Devnull: true, IdleWorkerTimeout: time.Hour})
+	assert.NoError(t, err)
+
+	client.workers.running = 1
+	assert.NoError(t, client.queue.push(context.Background(), downloadJob{sha: emptyHash}))
+
+	assert.False(t, client.retireWorker(client.queue.jobs), "worker isn't retired while job is queued")
+	assert.Equal(t, 1, runningWorkers(client))
+
+	_, ok := client.queue.jobs.tryPop()
+	assert.True(t, ok)
+	assert.True(t, client.retireWorker(client.queue.jobs))
+	assert.Equal(t, 0, runningWorkers(client))
+}
@@ -0,0 +1,45 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// validatorSuffix is suffix of file with range validator of temp file (see downloadFile)
+const validatorSuffix = ".validator"
+
+// rangeValidator return validator of response usable in If-Range - strong ETag, otherwise Last-Modified
+// ("" if response has neither, weak ETag can't be used for ranges)
+func rangeValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+
+	return resp.Header.Get("Last-Modified")
+}
+
+// readValidator return stored range validator of temp file ("" if there isn't any e.g. temp file of older version)
+func readValidator(temp string) string {
+	content, err := ioutil.ReadFile(temp + validatorSuffix)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(content))
+}
+
+// writeValidator store range validator of temp file, empty validator removes stored one
+func writeValidator(temp, validator string) error {
+	if validator == "" {
+		removeValidator(temp)
+		return nil
+	}
+
+	return ioutil.WriteFile(temp+validatorSuffix, []byte(validator), 0644)
+}
+
+func removeValidator(temp string) {
+	_ = os.Remove(temp + validatorSuffix)
+}
@@ -0,0 +1,125 @@
+package storclient
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// DownloadIndex is persistent set of successfully downloaded shas (see StorClientOpts.DownloadIndex)
+//
+// shas in index are skipped in subsequent runs even if files were moved out of download dir,
+// implementation must be safe for concurrent use
+type DownloadIndex interface {
+	Contains(sha hashutil.Hash) bool
+	// Add record successfully downloaded sha
+	Add(sha hashutil.Hash) error
+}
+
+// FileIndex is DownloadIndex in append-only file (one hex sha per line)
+//
+// whole index is loaded to memory on open, torn last line (crash during write) is ignored
+type FileIndex struct {
+	lock sync.RWMutex
+	file *os.File
+	shas map[string]struct{}
+}
+
+// OpenFileIndex open (or create) index file
+func OpenFileIndex(path string) (*FileIndex, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Open of index %s fail", path)
+	}
+
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrapf(err, "Read of index %s fail", path)
+	}
+
+	index := &FileIndex{file: file, shas: make(map[string]struct{})}
+
+	lines := strings.Split(string(content), "\n")
+	for _, line := range lines[:len(lines)-1] {
+		// torn line of crashed run isn't sha
+		if validIndexLine(line) {
+			index.shas[line] = struct{}{}
+		}
+	}
+
+	// torn last line (without new line) is dropped, next record starts on new line
+	if lines[len(lines)-1] != "" {
+		if _, err := file.WriteString("\n"); err != nil {
+			file.Close()
+			return nil, errors.Wrapf(err, "Write to index %s fail", path)
+		}
+	}
+
+	return index, nil
+}
+
+func validIndexLine(line string) bool {
+	if _, err := hex.DecodeString(line); err != nil {
+		return false
+	}
+
+	_, ok := hashAlgorithmBySize(hex.DecodedLen(len(line)))
+	return ok
+}
+
+// Contains return true if sha is in index
+func (index *FileIndex) Contains(sha hashutil.Hash) bool {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+
+	_, ok := index.shas[sha.String()]
+	return ok
+}
+
+// Add append sha to index file
+func (index *FileIndex) Add(sha hashutil.Hash) error {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	if _, ok := index.shas[sha.String()]; ok {
+		return nil
+	}
+
+	if _, err := index.file.WriteString(sha.String() + "\n"); err != nil {
+		return errors.Wrapf(err, "Write of %s to index fail", sha)
+	}
+	index.shas[sha.String()] = struct{}{}
+
+	return nil
+}
+
+// Len return count of shas in index
+func (index *FileIndex) Len() int {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+
+	return len(index.shas)
+}
+
+// Close close index file
+func (index *FileIndex) Close() error {
+	return index.file.Close()
+}
+
+// addToIndex record downloaded sha to DownloadIndex (if is set)
+func (client *StorClient) addToIndex(sha hashutil.Hash) {
+	if client.DownloadIndex == nil || client.DryRun {
+		return
+	}
+
+	if err := client.DownloadIndex.Add(sha); err != nil {
+		log.WithField("sha256", sha.String()).Warning(err)
+	}
+}
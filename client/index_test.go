@@ -0,0 +1,86 @@
+package storclient_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileIndex(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "index")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "index")
+	// torn last line of crashed run
+	assert.NoError(t, ioutil.WriteFile(path, []byte(sha256Of("first").String()+"\n"+sha256Of("second").String()[:10]), 0644))
+
+	index, err := storclient.OpenFileIndex(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, index.Len())
+	assert.True(t, index.Contains(sha256Of("first")))
+	assert.False(t, index.Contains(sha256Of("second")))
+
+	assert.NoError(t, index.Add(sha256Of("second")))
+	assert.NoError(t, index.Add(sha256Of("second")))
+	assert.NoError(t, index.Close())
+
+	index, err = storclient.OpenFileIndex(path)
+	assert.NoError(t, err)
+	defer index.Close()
+	assert.Equal(t, 2, index.Len())
+	assert.True(t, index.Contains(sha256Of("second")))
+}
+
+func TestDownloadIndex(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if strings.TrimPrefix(r.URL.Path, "/") == sha256Of("first").String() {
+			w.Write([]byte("first"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	storUrl, _ := url.Parse(server.URL)
+
+	tmpDir, err := ioutil.TempDir("", "index")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	indexPath := filepath.Join(tmpDir, "index")
+	run := func() storclient.TotalStat {
+		index, err := storclient.OpenFileIndex(indexPath)
+		assert.NoError(t, err)
+		defer index.Close()
+
+		client, err := storclient.New(*storUrl, tmpDir, storclient.StorClientOpts{DownloadIndex: index})
+		assert.NoError(t, err)
+
+		client.Start()
+		assert.NoError(t, client.Download(sha256Of("first")))
+		return client.Wait()
+	}
+
+	total := run()
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// post-processing moved file away
+	assert.NoError(t, os.Remove(filepath.Join(tmpDir, sha256Of("first").String())))
+
+	total = run()
+	assert.Equal(t, 0, total.Count)
+	assert.Equal(t, 1, total.Skip)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "indexed sha isn't downloaded again")
+}
@@ -0,0 +1,94 @@
+package storclient
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ManifestLineError is invalid line of manifest (see DownloadFromReader)
+type ManifestLineError struct {
+	// number of line (from 1)
+	Line int
+	Text string
+	Err  error
+}
+
+func (err ManifestLineError) Error() string {
+	return fmt.Sprintf("line %d: %s", err.Line, err.Err)
+}
+
+// ManifestErrors is list of all invalid lines of manifest
+type ManifestErrors []ManifestLineError
+
+func (errs ManifestErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d invalid lines of manifest:\n%s", len(errs), strings.Join(lines, "\n"))
+}
+
+// manifestLine return hash of manifest line without comment (# ...) and surrounding spaces,
+// false if line is blank or comment only
+func manifestLine(text string) (string, bool) {
+	if idx := strings.Index(text, "#"); idx >= 0 {
+		text = text[:idx]
+	}
+	text = strings.TrimSpace(text)
+
+	return text, text != ""
+}
+
+// DownloadFromReader add all hashes of manifest to download queue
+//
+// manifest has one hash (of StorClientOpts.HashAlgorithm in any format of ParseHash) per line,
+// blank lines and comments (from # to end of line) are ignored.
+// Valid hashes are enqueued, invalid lines are returned as ManifestErrors (with line numbers);
+// error of reading or of queue (e.g. ErrQueueClosed) stops enqueueing and is returned instead
+func (client *StorClient) DownloadFromReader(r io.Reader) error {
+	var invalid ManifestErrors
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line, ok := manifestLine(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		sha, err := ParseHashAlgorithm(line, client.HashAlgorithm)
+		if err != nil {
+			invalid = append(invalid, ManifestLineError{Line: n, Text: line, Err: err})
+			continue
+		}
+
+		if err := client.Download(sha); err != nil {
+			return errors.Wrapf(err, "Enqueue of line %d fail", n)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "Read of manifest fail")
+	}
+
+	if len(invalid) > 0 {
+		return invalid
+	}
+
+	return nil
+}
+
+// DownloadFromFile add all hashes of manifest file to download queue (see DownloadFromReader)
+func (client *StorClient) DownloadFromFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "Open of manifest fail")
+	}
+	defer file.Close()
+
+	return client.DownloadFromReader(file)
+}
@@ -0,0 +1,67 @@
+package storclient_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadFromReader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, content := range []string{"first", "second"} {
+			if strings.TrimPrefix(r.URL.Path, "/") == sha256Of(content).String() {
+				w.Write([]byte(content))
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	storUrl, _ := url.Parse(server.URL)
+
+	tmpDir, err := ioutil.TempDir("", "input")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	client, err := storclient.New(*storUrl, tmpDir, storclient.StorClientOpts{})
+	assert.NoError(t, err)
+
+	manifest := strings.Join([]string{
+		"# backfill of 2019-03",
+		"",
+		sha256Of("first").String() + "  # first object",
+		"   ",
+		"not-a-hash",
+		"sha256:" + strings.ToUpper(sha256Of("second").String()),
+		sha256Of("second").String()[:10],
+	}, "\n")
+
+	client.Start()
+	err = client.DownloadFromReader(strings.NewReader(manifest))
+	total := client.Wait()
+
+	if assert.IsType(t, storclient.ManifestErrors{}, err) {
+		errs := err.(storclient.ManifestErrors)
+		if assert.Len(t, errs, 2) {
+			assert.Equal(t, 5, errs[0].Line)
+			assert.Equal(t, "not-a-hash", errs[0].Text)
+			assert.Equal(t, 7, errs[1].Line)
+		}
+		assert.Contains(t, err.Error(), "line 5:")
+	}
+
+	assert.Equal(t, 2, total.Count)
+	assert.FileExists(t, filepath.Join(tmpDir, sha256Of("second").String()))
+
+	assert.Error(t, client.DownloadFromFile(filepath.Join(tmpDir, "missing")))
+	assert.Equal(t, storclient.ErrQueueClosed, errors.Cause(client.DownloadFromReader(strings.NewReader(sha256Of("first").String()))))
+}
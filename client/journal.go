@@ -0,0 +1,129 @@
+package storclient
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Journal is durable audit trail of downloads (and uploads) - every result is recorded
+// to SQL database across runs (see StorClientOpts.Journal)
+//
+// journal uses SQLite dialect, database driver is not imported by storclient
+// (e.g. github.com/mattn/go-sqlite3), so application opens database itself
+//
+//	db, err := sql.Open("sqlite3", "journal.db")
+//	journal, err := storclient.NewJournal(db)
+//	client, err := storclient.New(storageUrl, downloadDir, storclient.StorClientOpts{Journal: journal})
+type Journal struct {
+	db *sql.DB
+}
+
+// JournalEntry is one recorded result
+type JournalEntry struct {
+	Time     time.Time
+	Sha      string
+	Path     string
+	Status   string
+	Size     int64
+	Duration time.Duration
+	Error    string
+}
+
+// DailyBytes is downloaded size and count of successful downloads per day (UTC)
+type DailyBytes struct {
+	// day in format YYYY-MM-DD
+	Day   string
+	Size  int64
+	Count int
+}
+
+const journalSchema = `
+CREATE TABLE IF NOT EXISTS stor_journal (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	time     INTEGER NOT NULL,
+	sha      TEXT NOT NULL,
+	path     TEXT NOT NULL,
+	status   TEXT NOT NULL,
+	size     INTEGER NOT NULL,
+	duration INTEGER NOT NULL,
+	error    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS stor_journal_time ON stor_journal (time);
+`
+
+// NewJournal create journal in db (table is created if not exists)
+func NewJournal(db *sql.DB) (*Journal, error) {
+	if _, err := db.Exec(journalSchema); err != nil {
+		return nil, errors.Wrap(err, "Create of journal table fail")
+	}
+
+	return &Journal{db: db}, nil
+}
+
+// Record write result to journal
+func (journal *Journal) Record(t time.Time, result DownloadResult) error {
+	errStr := ""
+	if result.Err != nil {
+		errStr = result.Err.Error()
+	}
+
+	_, err := journal.db.Exec(
+		"INSERT INTO stor_journal (time, sha, path, status, size, duration, error) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		t.Unix(), result.Sha.String(), result.Path, result.Status.String(), result.Size, int64(result.Duration), errStr,
+	)
+
+	return errors.Wrapf(err, "Record of %s to journal fail", result.Sha)
+}
+
+// Failures return failed downloads since given time (e.g. time.Now().Add(-24*time.Hour)), oldest first
+func (journal *Journal) Failures(since time.Time) ([]JournalEntry, error) {
+	rows, err := journal.db.Query(
+		"SELECT time, sha, path, status, size, duration, error FROM stor_journal WHERE status = ? AND time >= ? ORDER BY time, id",
+		DOWN_FAIL.String(), since.Unix(),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "Query of journal failures fail")
+	}
+	defer rows.Close()
+
+	entries := make([]JournalEntry, 0)
+	for rows.Next() {
+		var entry JournalEntry
+		var unix, duration int64
+		if err := rows.Scan(&unix, &entry.Sha, &entry.Path, &entry.Status, &entry.Size, &duration, &entry.Error); err != nil {
+			return nil, errors.Wrap(err, "Scan of journal entry fail")
+		}
+		entry.Time = time.Unix(unix, 0)
+		entry.Duration = time.Duration(duration)
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// BytesPerDay return downloaded bytes per day (UTC) since given time, oldest day first
+func (journal *Journal) BytesPerDay(since time.Time) ([]DailyBytes, error) {
+	rows, err := journal.db.Query(
+		"SELECT date(time, 'unixepoch') AS day, SUM(size), COUNT(*) FROM stor_journal WHERE status = ? AND time >= ? GROUP BY day ORDER BY day",
+		DOWN_OK.String(), since.Unix(),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "Query of journal bytes per day fail")
+	}
+	defer rows.Close()
+
+	days := make([]DailyBytes, 0)
+	for rows.Next() {
+		var day DailyBytes
+		if err := rows.Scan(&day.Day, &day.Size, &day.Count); err != nil {
+			return nil, errors.Wrap(err, "Scan of journal day fail")
+		}
+
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
@@ -0,0 +1,91 @@
+package storclient_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/avast/stor-client/client"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	tmpDir, cleanup := tempDir(t, "journal")
+	defer cleanup()
+
+	db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "journal.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	journal, err := storclient.NewJournal(db)
+	assert.NoError(t, err)
+
+	day := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	records := []struct {
+		time   time.Time
+		result storclient.DownloadResult
+	}{
+		{day, storclient.DownloadResult{Sha: sha256Of("first"), Status: storclient.DOWN_OK, Size: 10}},
+		{day.Add(time.Hour), storclient.DownloadResult{Sha: sha256Of("second"), Status: storclient.DOWN_OK, Size: 5}},
+		{day.Add(24 * time.Hour), storclient.DownloadResult{Sha: sha256Of("third"), Status: storclient.DOWN_OK, Size: 1}},
+		{day.Add(25 * time.Hour), storclient.DownloadResult{Sha: sha256Of("skipped"), Status: storclient.DOWN_SKIP}},
+		{day, storclient.DownloadResult{Sha: sha256Of("old"), Status: storclient.DOWN_FAIL, Err: errors.New("old failure")}},
+		{day.Add(25 * time.Hour), storclient.DownloadResult{Sha: sha256Of("failed"), Status: storclient.DOWN_FAIL, Err: errors.New("404 Not Found")}},
+	}
+	for _, record := range records {
+		assert.NoError(t, journal.Record(record.time, record.result))
+	}
+
+	failures, err := journal.Failures(day.Add(24 * time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, sha256Of("failed").String(), failures[0].Sha)
+		assert.Equal(t, "404 Not Found", failures[0].Error)
+		assert.True(t, failures[0].Time.Equal(day.Add(25*time.Hour)))
+	}
+
+	days, err := journal.BytesPerDay(day)
+	assert.NoError(t, err)
+	assert.Equal(t, []storclient.DailyBytes{
+		{Day: "2019-03-01", Size: 15, Count: 2},
+		{Day: "2019-03-02", Size: 1, Count: 1},
+	}, days)
+}
+
+func TestJournalOfClient(t *testing.T) {
+	serverURL, stop := startFirstStor()
+	defer stop()
+
+	tmpDir, cleanup := tempDir(t, "journal")
+	defer cleanup()
+
+	db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "journal.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	journal, err := storclient.NewJournal(db)
+	assert.NoError(t, err)
+
+	client, err := storclient.New(*serverURL, tmpDir, storclient.StorClientOpts{RetryAttempts: 1, Journal: journal})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(sha256Of("first")))
+	assert.NoError(t, client.Download(sha256Of("missing")))
+	client.Wait()
+
+	failures, err := journal.Failures(time.Now().Add(-24 * time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, sha256Of("missing").String(), failures[0].Sha)
+	}
+
+	days, err := journal.BytesPerDay(time.Now().Add(-24 * time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, days, 1) {
+		assert.Equal(t, int64(len("first")), days[0].Size)
+	}
+}
@@ -0,0 +1,41 @@
+package storclient
+
+import (
+	"path"
+	"strings"
+
+	"github.com/avast/hashutil-go"
+)
+
+// DefaultShardWidth is count of hex characters of one directory level of sharded layout
+const DefaultShardWidth = 2
+
+// filename return name of downloaded file of sha (relative to download dir)
+func (client *StorClient) filename(sha hashutil.Hash) string {
+	filename := sha.String()
+	if client.UpperCase {
+		filename = strings.ToUpper(sha.String())
+	}
+
+	filename += client.Suffix
+	if client.ZipPassword != "" {
+		filename = zipName(filename)
+	}
+
+	return client.shardedFilename(filename)
+}
+
+// shardedFilename return filename nested in ShardDepth directories named by prefix
+// of hex sha (ShardWidth characters each), e.g. ab/cd/abcdef... - filename starts with hex sha
+func (client *StorClient) shardedFilename(filename string) string {
+	if client.ShardDepth <= 0 {
+		return filename
+	}
+
+	dirs := make([]string, 0, client.ShardDepth+1)
+	for level := 0; level < client.ShardDepth; level++ {
+		dirs = append(dirs, filename[level*client.ShardWidth:(level+1)*client.ShardWidth])
+	}
+
+	return path.Join(append(dirs, filename)...)
+}
@@ -0,0 +1,38 @@
+package storclient
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedFilename(t *testing.T) {
+	filename := emptyHash.String() + ".dat"
+
+	client := &StorClient{}
+	assert.Equal(t, filename, client.shardedFilename(filename))
+
+	client.ShardDepth, client.ShardWidth = 2, 2
+	assert.Equal(t, "e3/b0/"+filename, client.shardedFilename(filename))
+
+	client.ShardDepth, client.ShardWidth = 1, 3
+	assert.Equal(t, "e3b/"+filename, client.shardedFilename(filename))
+
+	_, err := New(url.URL{}, "", StorClientOpts{ShardDepth: 33})
+	assert.Error(t, err, "sha256 has only 64 hex characters")
+}
+
+func TestShardedDownload(t *testing.T) {
+	httpClient := func() httpClient { return &clientMock{statusCode: 200, status: "OK"} }
+
+	opts := StorClientOpts{ShardDepth: 2, UpperCase: true}
+	downloadWorkersTest(t, opts, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
+		assert.Equal(t, DOWN_OK, stat[0].Status)
+		assert.FileExists(t, filepath.Join(tempdir.Canonpath(), "E3", "B0", strings.ToUpper(emptyHash.String())))
+	})
+}
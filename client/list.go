@@ -0,0 +1,152 @@
+package storclient
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+)
+
+// ListPath is path of listing endpoint relative to storage url (see StorClient.List)
+const ListPath = "list"
+
+// ListCursorHeader is response header of listing with cursor of next page (empty on last page)
+const ListCursorHeader = "X-Next-Cursor"
+
+// ListOpts is filter of listing of objects on stor (see StorClient.List)
+type ListOpts struct {
+	// prefix of hex hash
+	// default ("") means all objects
+	Prefix string
+	// objects stored at or after Since
+	// default (zero) means without lower bound
+	Since time.Time
+	// objects stored before Until
+	// default (zero) means without upper bound
+	Until time.Time
+}
+
+// query return query of listing page
+func (opts ListOpts) query(cursor string) url.Values {
+	query := url.Values{}
+	if opts.Prefix != "" {
+		query.Set("prefix", strings.ToLower(opts.Prefix))
+	}
+	if !opts.Since.IsZero() {
+		query.Set("since", opts.Since.UTC().Format(time.RFC3339))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", opts.Until.UTC().Format(time.RFC3339))
+	}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+
+	return query
+}
+
+// List stream hashes of objects on stor matching opts
+//
+// listing is GET <storage url>/list?prefix=<hex>&since=<RFC3339>&until=<RFC3339> returning one hash per line,
+// next page is requested with &cursor=<ListCursorHeader of previous page> until the header is empty.
+// Hash channel is closed at end of listing (or when ctx is done), then error channel gets result
+// of listing (nil or error) - hashes can be passed to Download, see DownloadListed
+func (client *StorClient) List(ctx context.Context, opts ListOpts) (<-chan hashutil.Hash, <-chan error) {
+	hashes := make(chan hashutil.Hash, 64)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(hashes)
+
+		httpClient := &contextClient{Client: client.newStdHTTPClient(), ctx: ctx}
+
+		cursor := ""
+		for {
+			next, err := client.listPage(ctx, httpClient, opts, cursor, hashes)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if next == "" {
+				return
+			}
+			cursor = next
+		}
+	}()
+
+	return hashes, errs
+}
+
+// listPage send hashes of one page of listing to hashes, returns cursor of next page
+func (client *StorClient) listPage(ctx context.Context, httpClient httpClient, opts ListOpts, cursor string, hashes chan<- hashutil.Hash) (next string, err error) {
+	listURL := client.storageUrl
+	listURL.Path = path.Join("/", listURL.Path, ListPath)
+	listURL.RawQuery = opts.query(cursor).Encode()
+
+	resp, err := httpClient.Get(listURL.String())
+	if err != nil {
+		return "", errors.Wrap(err, "Listing of stor fail")
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("Listing of stor fail %d (%s)", resp.StatusCode, resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		sha, err := ParseHashAlgorithm(line, client.HashAlgorithm)
+		if err != nil {
+			return "", errors.Wrapf(err, "Invalid hash %q in listing", line)
+		}
+
+		select {
+		case hashes <- sha:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.Wrap(err, "Read of listing fail")
+	}
+
+	return resp.Header.Get(ListCursorHeader), nil
+}
+
+// DownloadListed add all hashes of listing of stor (see List) to download queue,
+// returns count of queued hashes; error of listing or of queue stops enqueueing
+func (client *StorClient) DownloadListed(ctx context.Context, opts ListOpts) (int, error) {
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	hashes, errs := client.List(listCtx, opts)
+
+	queued := 0
+	for sha := range hashes {
+		if err := client.DownloadCtx(ctx, sha); err != nil {
+			cancel()
+			for range hashes {
+			}
+			return queued, errors.Wrap(err, "Enqueue of listed hash fail")
+		}
+		queued++
+	}
+
+	return queued, <-errs
+}
@@ -0,0 +1,110 @@
+package storclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestList(t *testing.T) {
+	first, second, third := contentHash("first"), contentHash("second"), contentHash("third")
+	since := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var queries []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stor/list" {
+			http.NotFound(w, r)
+			return
+		}
+
+		query := r.URL.Query()
+		queries = append(queries, query)
+		switch query.Get("cursor") {
+		case "":
+			w.Header().Set(ListCursorHeader, "page2")
+			_, _ = w.Write([]byte(first.String() + "\n\n" + second.String() + "\n"))
+		case "page2":
+			_, _ = w.Write([]byte(third.String() + "\n"))
+		}
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL + "/stor")
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{})
+	assert.NoError(t, err)
+
+	hashes, errs := client.List(context.Background(), ListOpts{Prefix: "AB", Since: since})
+
+	var listed []hashutil.Hash
+	for sha := range hashes {
+		listed = append(listed, sha)
+	}
+	assert.NoError(t, <-errs)
+
+	assert.Equal(t, []string{first.String(), second.String(), third.String()}, []string{listed[0].String(), listed[1].String(), listed[2].String()})
+	assert.Len(t, queries, 2)
+	assert.Equal(t, "ab", queries[0].Get("prefix"))
+	assert.Equal(t, "2020-01-02T03:04:05Z", queries[0].Get("since"))
+	assert.Empty(t, queries[0].Get("until"))
+	assert.Equal(t, "page2", queries[1].Get("cursor"))
+	assert.Equal(t, "ab", queries[1].Get("prefix"), "filter is kept on next pages")
+}
+
+func TestListError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("prefix") == "bad" {
+			_, _ = w.Write([]byte("not a hash\n"))
+			return
+		}
+		http.Error(w, "listing isn't supported", http.StatusNotImplemented)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{})
+	assert.NoError(t, err)
+
+	for _, prefix := range []string{"", "bad"} {
+		hashes, errs := client.List(context.Background(), ListOpts{Prefix: prefix})
+		for range hashes {
+		}
+		assert.Error(t, <-errs)
+	}
+}
+
+func TestDownloadListed(t *testing.T) {
+	contents := map[string]string{contentHash("first").String(): "first", contentHash("second").String(): "second"}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/list" {
+			for sha := range contents {
+				_, _ = w.Write([]byte(sha + "\n"))
+			}
+			return
+		}
+		_, _ = w.Write([]byte(contents[r.URL.Path[1:]]))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, "", StorClientOpts{Devnull: true, RetryAttempts: 1})
+	assert.NoError(t, err)
+
+	client.Start()
+	queued, err := client.DownloadListed(context.Background(), ListOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, queued)
+
+	total := client.Wait()
+	assert.Equal(t, 2, total.Count)
+
+	_, err = client.DownloadListed(context.Background(), ListOpts{})
+	assert.Error(t, err, "queue is closed")
+}
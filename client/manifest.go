@@ -0,0 +1,225 @@
+package storclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultManifestChunkSize is count of hashes per chunk if ManifestOpts.ChunkSize isn't set
+const DefaultManifestChunkSize = 100000
+
+// ManifestOpts configure ProcessManifest
+type ManifestOpts struct {
+	// count of hashes (lines of manifest without blank lines and comments) per chunk
+	// default (0) means DefaultManifestChunkSize
+	ChunkSize int
+	// directory of checkpoint files (one per finished chunk), created if not exists
+	CheckpointDir string
+	// algorithm of hashes in manifest
+	// default ("") means SHA256
+	HashAlgorithm HashAlgorithm
+}
+
+// ManifestChunk is result of one chunk of manifest
+type ManifestChunk struct {
+	// index of chunk (from 0)
+	Index int
+	// count of hashes in chunk
+	Hashes int
+	// chunk was finished in previous run (stats are loaded from checkpoint)
+	Resumed bool
+	Total   TotalStat
+}
+
+// manifestCheckpoint is content of checkpoint file of finished chunk
+type manifestCheckpoint struct {
+	Chunk    int           `json:"chunk"`
+	Hashes   int           `json:"hashes"`
+	Expected int           `json:"expected"`
+	Size     int64         `json:"size"`
+	Duration time.Duration `json:"duration"`
+	Count    int           `json:"count"`
+	Skip     int           `json:"skip"`
+	Metadata int           `json:"metadata"`
+}
+
+// ProcessManifest download all hashes of (very large) manifest in fixed-size chunks
+//
+// manifest contains one hash per line (blank lines and comments are ignored, see DownloadFromReader), chunks are processed
+// sequentially, each by new client from newClient (Start, Download of every hash, Wait).
+// TotalStat of finished chunk is written to checkpoint file in opts.CheckpointDir,
+// so next run with same manifest and CheckpointDir skips finished chunks and
+// resumes at first incomplete one.
+//
+// cancel of ctx stops enqueueing, already queued downloads of current chunk finish,
+// but chunk isn't checkpointed; finished chunks and ctx.Err() are returned
+func ProcessManifest(ctx context.Context, manifest io.Reader, opts ManifestOpts, newClient func() (*StorClient, error)) ([]ManifestChunk, error) {
+	if opts.CheckpointDir == "" {
+		return nil, errors.New("CheckpointDir of manifest is required")
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultManifestChunkSize
+	}
+	if opts.HashAlgorithm == "" {
+		opts.HashAlgorithm = SHA256
+	}
+
+	if err := os.MkdirAll(opts.CheckpointDir, 0755); err != nil {
+		return nil, errors.Wrap(err, "Create of checkpoint dir fail")
+	}
+
+	chunks := make([]ManifestChunk, 0)
+	lines := make([]string, 0, opts.ChunkSize)
+	flush := func() error {
+		chunk, err := processManifestChunk(ctx, len(chunks), lines, opts, newClient)
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, chunk)
+		lines = lines[:0]
+
+		return nil
+	}
+
+	scanner := bufio.NewScanner(manifest)
+	for scanner.Scan() {
+		line, ok := manifestLine(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		lines = append(lines, line)
+		if len(lines) == opts.ChunkSize {
+			if err := flush(); err != nil {
+				return chunks, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return chunks, errors.Wrap(err, "Read of manifest fail")
+	}
+
+	if len(lines) > 0 {
+		if err := flush(); err != nil {
+			return chunks, err
+		}
+	}
+
+	return chunks, nil
+}
+
+func processManifestChunk(ctx context.Context, index int, lines []string, opts ManifestOpts, newClient func() (*StorClient, error)) (ManifestChunk, error) {
+	checkpointPath := filepath.Join(opts.CheckpointDir, fmt.Sprintf("chunk-%06d.json", index))
+
+	if checkpoint, err := readManifestCheckpoint(checkpointPath); err == nil {
+		if checkpoint.Hashes != len(lines) {
+			return ManifestChunk{}, errors.Errorf("Checkpoint %s is of %d hashes, but chunk has %d (manifest changed?)", checkpointPath, checkpoint.Hashes, len(lines))
+		}
+
+		log.Debugf("Chunk %d of manifest is already finished, skip", index)
+		return ManifestChunk{Index: index, Hashes: len(lines), Resumed: true, Total: checkpoint.total()}, nil
+	} else if !os.IsNotExist(errors.Cause(err)) {
+		return ManifestChunk{}, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return ManifestChunk{}, err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return ManifestChunk{}, errors.Wrapf(err, "Create of client for chunk %d fail", index)
+	}
+
+	log.Infof("Start chunk %d of manifest (%d hashes)", index, len(lines))
+	client.Start()
+
+	for _, line := range lines {
+		if ctx.Err() != nil {
+			break
+		}
+
+		sha, err := ParseHashAlgorithm(line, opts.HashAlgorithm)
+		if err != nil {
+			log.Errorf("Invalid %s: %s", opts.HashAlgorithm, err)
+			continue
+		}
+
+		if err := client.DownloadCtx(ctx, sha); err != nil {
+			log.Error(err)
+		}
+	}
+
+	total := client.Wait()
+	if err := ctx.Err(); err != nil {
+		return ManifestChunk{}, err
+	}
+
+	checkpoint := manifestCheckpoint{
+		Chunk:    index,
+		Hashes:   len(lines),
+		Expected: total.expectedDownloadCount,
+		Size:     total.Size,
+		Duration: total.Duration,
+		Count:    total.Count,
+		Skip:     total.Skip,
+		Metadata: total.Metadata,
+	}
+	if err := writeManifestCheckpoint(checkpointPath, checkpoint); err != nil {
+		return ManifestChunk{}, err
+	}
+
+	return ManifestChunk{Index: index, Hashes: len(lines), Total: total}, nil
+}
+
+func (checkpoint manifestCheckpoint) total() TotalStat {
+	return TotalStat{
+		Size:                  checkpoint.Size,
+		Duration:              checkpoint.Duration,
+		Count:                 checkpoint.Count,
+		Skip:                  checkpoint.Skip,
+		Metadata:              checkpoint.Metadata,
+		Fail:                  checkpoint.Expected - checkpoint.Count - checkpoint.Skip - checkpoint.Metadata,
+		expectedDownloadCount: checkpoint.Expected,
+	}
+}
+
+func readManifestCheckpoint(path string) (manifestCheckpoint, error) {
+	var checkpoint manifestCheckpoint
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return checkpoint, err
+	}
+
+	if err := json.Unmarshal(content, &checkpoint); err != nil {
+		return checkpoint, errors.Wrapf(err, "Parse of checkpoint %s fail", path)
+	}
+
+	return checkpoint, nil
+}
+
+// writeManifestCheckpoint write checkpoint atomically (temp file + rename)
+func writeManifestCheckpoint(path string, checkpoint manifestCheckpoint) error {
+	content, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return errors.Wrapf(err, "Write of checkpoint %s fail", path)
+	}
+
+	return errors.Wrapf(os.Rename(tmp, path), "Rename of checkpoint %s fail", path)
+}
@@ -0,0 +1,108 @@
+package storclient_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessManifest(t *testing.T) {
+	contents := []string{"first", "second", "third", "fourth", "fifth"}
+	objects := make(map[string]string)
+	manifest := ""
+	for _, content := range contents {
+		objects[sha256Of(content).String()] = content
+		manifest += sha256Of(content).String() + "\n\n"
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := objects[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+	storUrl, _ := url.Parse(server.URL)
+
+	tmpDir, err := ioutil.TempDir("", "manifest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	downloadDir := tmpDir
+	opts := storclient.ManifestOpts{ChunkSize: 2, CheckpointDir: filepath.Join(tmpDir, "checkpoints")}
+
+	clients := 0
+	newClient := func() (*storclient.StorClient, error) {
+		clients++
+		return storclient.New(*storUrl, downloadDir, storclient.StorClientOpts{Max: 2})
+	}
+
+	// interrupted before last chunk
+	interrupted := func() (*storclient.StorClient, error) {
+		if clients == 2 {
+			return nil, errors.New("interrupted")
+		}
+		return newClient()
+	}
+
+	chunks, err := storclient.ProcessManifest(context.Background(), strings.NewReader(manifest), opts, interrupted)
+	assert.Error(t, err)
+	if assert.Len(t, chunks, 2) {
+		for _, chunk := range chunks {
+			assert.False(t, chunk.Resumed)
+			assert.Equal(t, 2, chunk.Hashes)
+			assert.Equal(t, 2, chunk.Total.Count)
+			assert.True(t, chunk.Total.Status())
+		}
+	}
+	_, err = os.Stat(filepath.Join(downloadDir, sha256Of("fifth").String()))
+	assert.True(t, os.IsNotExist(err))
+
+	clients = 0
+	chunks, err = storclient.ProcessManifest(context.Background(), strings.NewReader(manifest), opts, newClient)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, clients, "only incomplete chunk is processed")
+	if assert.Len(t, chunks, 3) {
+		assert.True(t, chunks[0].Resumed)
+		assert.True(t, chunks[1].Resumed)
+		assert.Equal(t, int64(len("third")+len("fourth")), chunks[1].Total.Size)
+		assert.True(t, chunks[1].Total.Status())
+
+		assert.False(t, chunks[2].Resumed)
+		assert.Equal(t, 1, chunks[2].Hashes)
+		assert.Equal(t, 1, chunks[2].Total.Count)
+	}
+	assert.FileExists(t, filepath.Join(downloadDir, sha256Of("fifth").String()))
+}
+
+func TestProcessManifestCanceled(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "manifest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	opts := storclient.ManifestOpts{CheckpointDir: tmpDir}
+	chunks, err := storclient.ProcessManifest(ctx, strings.NewReader(sha256Of("first").String()+"\n"), opts, func() (*storclient.StorClient, error) {
+		t.Fatal("client of canceled manifest is created")
+		return nil, nil
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, chunks)
+
+	_, err = storclient.ProcessManifest(context.Background(), strings.NewReader(""), storclient.ManifestOpts{}, nil)
+	assert.Error(t, err, "CheckpointDir is required")
+}
@@ -0,0 +1,50 @@
+package storclient
+
+import (
+	"net/http"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+)
+
+// Metadata of sha on server from HEAD request
+type Metadata struct {
+	// size of file (-1 if server doesn't report it)
+	Size int64
+	// response headers
+	Header http.Header
+}
+
+// MetadataOnlyFunc return true if only metadata of sha are recorded (status DOWN_METADATA)
+// instead of download of file (see StorClientOpts.MetadataOnly)
+//
+// it's called concurrently from workers
+type MetadataOnlyFunc func(sha hashutil.Hash, metadata Metadata) bool
+
+// MetadataLargerThan return MetadataOnlyFunc which select files larger than size
+// (file of unknown size is downloaded)
+func MetadataLargerThan(size int64) MetadataOnlyFunc {
+	return func(_ hashutil.Hash, metadata Metadata) bool {
+		return metadata.Size > size
+	}
+}
+
+// headMetadata return metadata of sha by HEAD request
+func headMetadata(httpClient httpClient, url string, expectedSha hashutil.Hash) (Metadata, error) {
+	c := findContextClient(httpClient)
+	if c == nil {
+		return Metadata{}, errors.New("HEAD request isn't supported by http client")
+	}
+
+	resp, err := c.Head(url)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return Metadata{}, StatusError{Sha: expectedSha, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	return Metadata{Size: resp.ContentLength, Header: resp.Header}, nil
+}
@@ -0,0 +1,73 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataOnly(t *testing.T) {
+	small, large := contentHash("small"), contentHash("large content")
+	contents := map[string]string{small.String(): "small", large.String(): "large content"}
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+contents[path.Base(r.URL.Path)])
+		w.Header().Set("X-Origin", "stor")
+		_, _ = w.Write([]byte(contents[path.Base(r.URL.Path)]))
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "metadata")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, tmpDir, StorClientOpts{Max: 1, MetadataOnly: MetadataLargerThan(5)})
+	assert.NoError(t, err)
+
+	results := client.Results()
+	client.Start()
+	assert.NoError(t, client.Download(small))
+	assert.NoError(t, client.Download(large))
+
+	statuses := map[string]DownloadResult{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range results {
+			statuses[result.Sha.String()] = result
+		}
+	}()
+
+	total := client.Wait()
+	<-done
+
+	assert.Equal(t, DOWN_OK, statuses[small.String()].Status)
+	assert.Equal(t, DOWN_METADATA, statuses[large.String()].Status)
+	assert.Equal(t, int64(len("large content")), statuses[large.String()].Size)
+	assert.Equal(t, "stor", statuses[large.String()].Metadata.Get("X-Origin"))
+	assert.Empty(t, statuses[large.String()].Path)
+	assert.Equal(t, "metadata", DOWN_METADATA.String())
+
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, 1, total.Metadata)
+	assert.Equal(t, int64(len("small")), total.Size, "size of metadata only file isn't counted")
+	assert.True(t, total.Status())
+	assert.Equal(t, 0, total.Failed())
+	assert.Equal(t, []string{"HEAD small", "GET small", "HEAD large content"}, requests)
+
+	assert.True(t, fileExists(path.Join(tmpDir, small.String())))
+	assert.False(t, fileExists(path.Join(tmpDir, large.String())))
+}
+
+func TestMetadataOnlyOpts(t *testing.T) {
+	_, err := New(url.URL{}, os.TempDir(), StorClientOpts{DryRun: true, MetadataOnly: MetadataLargerThan(0)})
+	assert.Error(t, err)
+}
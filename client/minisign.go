@@ -0,0 +1,143 @@
+package storclient
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisign algorithms of signature
+const (
+	// legacy - signed message is whole content
+	minisignLegacy = "Ed"
+	// prehashed - signed message is BLAKE2b-512 of content
+	minisignPrehashed = "ED"
+)
+
+// minisignVerifier verify minisign signatures (<object>.minisig) of content
+type minisignVerifier struct {
+	keyID     []byte
+	publicKey ed25519.PublicKey
+}
+
+// NewMinisignVerifier return verifier of minisign signatures of content of objects made by publicKey
+//
+// publicKey is content of minisign public key file (with untrusted comment) or its base64 line,
+// both prehashed and legacy signatures are supported - content of object with legacy signature is kept in memory
+// until verification, signature file must have trusted comment (it's verified too)
+func NewMinisignVerifier(publicKey []byte) (ContentSignatureVerifier, error) {
+	decoded, err := base64.StdEncoding.DecodeString(lastLine(publicKey))
+	if err != nil {
+		return nil, errors.Wrap(err, "minisign public key isn't base64")
+	}
+	if len(decoded) != 2+8+ed25519.PublicKeySize || string(decoded[:2]) != minisignLegacy {
+		return nil, errors.New("minisign public key isn't Ed25519 key")
+	}
+
+	return minisignVerifier{keyID: decoded[2:10], publicKey: decoded[10:]}, nil
+}
+
+// lastLine return last non-empty line without comment lines
+func lastLine(content []byte) string {
+	last := ""
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			last = line
+		}
+	}
+
+	return last
+}
+
+// minisignSignature is parsed minisign signature file
+type minisignSignature struct {
+	algorithm string
+	signature []byte
+}
+
+// parse check signature file - key id and global signature of trusted comment
+func (v minisignVerifier) parse(signature []byte) (minisignSignature, error) {
+	lines := strings.Split(strings.TrimSpace(string(signature)), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "untrusted comment:") || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return minisignSignature{}, errors.Wrap(ErrSignature, "signature isn't minisign signature file with trusted comment")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(decoded) != 2+8+ed25519.SignatureSize {
+		return minisignSignature{}, errors.Wrap(ErrSignature, "minisign signature isn't base64 Ed25519 signature")
+	}
+
+	algorithm := string(decoded[:2])
+	if algorithm != minisignLegacy && algorithm != minisignPrehashed {
+		return minisignSignature{}, errors.Wrapf(ErrSignature, "unknown minisign algorithm %q", algorithm)
+	}
+	if !bytes.Equal(decoded[2:10], v.keyID) {
+		return minisignSignature{}, errors.Wrapf(ErrSignature, "minisign signature is made by key %X, expected %X", decoded[2:10], v.keyID)
+	}
+
+	globalSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil {
+		return minisignSignature{}, errors.Wrap(ErrSignature, "global minisign signature isn't base64")
+	}
+	trustedComment := strings.TrimSuffix(strings.TrimPrefix(lines[2], "trusted comment: "), "\r")
+	signed := make([]byte, 0, ed25519.SignatureSize+len(trustedComment))
+	signed = append(append(signed, decoded[10:]...), trustedComment...)
+	if !ed25519.Verify(v.publicKey, signed, globalSignature) {
+		return minisignSignature{}, errors.Wrap(ErrSignature, "trusted comment of minisign signature")
+	}
+
+	return minisignSignature{algorithm: algorithm, signature: decoded[10:]}, nil
+}
+
+func (v minisignVerifier) Verify(_ hashutil.Hash, signature []byte) error {
+	_, err := v.parse(signature)
+	return err
+}
+
+func (v minisignVerifier) Content(signature []byte) (Verifier, error) {
+	parsed, err := v.parse(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return &minisignContent{verifier: v, signature: parsed}, nil
+}
+
+// minisignContent verify content against one minisign signature
+type minisignContent struct {
+	verifier  minisignVerifier
+	signature minisignSignature
+	// BLAKE2b-512 of prehashed signature or whole content of legacy one
+	prehash hash.Hash
+	content bytes.Buffer
+}
+
+func (c *minisignContent) NewWriter() io.Writer {
+	c.content.Reset()
+	if c.signature.algorithm == minisignLegacy {
+		return &c.content
+	}
+
+	c.prehash, _ = blake2b.New512(nil)
+	return c.prehash
+}
+
+func (c *minisignContent) Verify(expected hashutil.Hash) error {
+	message := c.content.Bytes()
+	if c.signature.algorithm == minisignPrehashed {
+		message = c.prehash.Sum(nil)
+	}
+
+	if !ed25519.Verify(c.verifier.publicKey, message, c.signature.signature) {
+		return errors.Wrapf(ErrSignature, "minisign signature of content of %s", expected)
+	}
+
+	return nil
+}
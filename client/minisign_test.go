@@ -0,0 +1,78 @@
+package storclient
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisignKey is minisign key pair for tests
+type minisignKey struct {
+	id         []byte
+	publicKey  ed25519.PublicKey
+	privateKey ed25519.PrivateKey
+}
+
+func newMinisignKey(t *testing.T) minisignKey {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	return minisignKey{id: []byte("keyid123"), publicKey: publicKey, privateKey: privateKey}
+}
+
+func (key minisignKey) publicKeyFile() []byte {
+	encoded := base64.StdEncoding.EncodeToString(append(append([]byte(minisignLegacy), key.id...), key.publicKey...))
+	return []byte(fmt.Sprintf("untrusted comment: minisign public key\n%s\n", encoded))
+}
+
+// sign return minisign signature file of content
+func (key minisignKey) sign(content string, algorithm string) []byte {
+	message := []byte(content)
+	if algorithm == minisignPrehashed {
+		sum := blake2b.Sum512(message)
+		message = sum[:]
+	}
+
+	signature := ed25519.Sign(key.privateKey, message)
+	trustedComment := "timestamp:1556193335\tfile:" + content
+	globalSignature := ed25519.Sign(key.privateKey, append(append([]byte{}, signature...), trustedComment...))
+
+	return []byte(fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), key.id...), signature...)),
+		trustedComment,
+		base64.StdEncoding.EncodeToString(globalSignature),
+	))
+}
+
+func TestMinisignVerifier(t *testing.T) {
+	key := newMinisignKey(t)
+	other := newMinisignKey(t)
+
+	verifier, err := NewMinisignVerifier(key.publicKeyFile())
+	assert.NoError(t, err)
+
+	tampered := key.sign("tampered", minisignPrehashed)
+	tampered[len(tampered)-5] ^= 1
+
+	errs := downloadSigned(t, verifier, map[string][]byte{
+		"prehashed": key.sign("prehashed", minisignPrehashed),
+		"legacy":    key.sign("legacy", minisignLegacy),
+		"forged":    key.sign("other content", minisignPrehashed),
+		"other key": other.sign("other key", minisignPrehashed),
+		"tampered":  tampered,
+	}, "prehashed", "legacy", "forged", "other key", "tampered", "unsigned")
+
+	assert.NoError(t, errs["prehashed"])
+	assert.NoError(t, errs["legacy"])
+	for _, content := range []string{"forged", "other key", "tampered", "unsigned"} {
+		assert.Error(t, errs[content], content)
+	}
+	assert.Contains(t, errs["forged"].Error(), ErrSignature.Error())
+
+	_, err = NewMinisignVerifier([]byte("untrusted comment: minisign public key\nAAAA\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package storclient
+
+import (
+	"net/url"
+	"sync"
+	"sync/atomic"
+)
+
+// MirrorPolicy is order in which storage url and its mirrors (see StorClientOpts.Mirrors) are used
+type MirrorPolicy int
+
+const (
+	// MirrorFailover - every download starts on primary storage url, mirrors are used after failures
+	MirrorFailover MirrorPolicy = iota
+	// MirrorRoundRobin - downloads start on storage url and mirrors in turn
+	MirrorRoundRobin
+)
+
+func (policy MirrorPolicy) String() string {
+	switch policy {
+	case MirrorRoundRobin:
+		return "round-robin"
+	default:
+		return "failover"
+	}
+}
+
+// storMirrors is list of replicas of stor, first is primary storage url
+// (or the fastest healthy one when mirrors are probed, see StorClientOpts.MirrorProbe)
+type storMirrors struct {
+	lock   sync.RWMutex
+	urls   []url.URL
+	policy MirrorPolicy
+	next   uint64
+}
+
+func newStorMirrors(primary url.URL, mirrors []url.URL, policy MirrorPolicy) *storMirrors {
+	return &storMirrors{urls: append([]url.URL{primary}, mirrors...), policy: policy}
+}
+
+// list return current order of mirrors
+func (m *storMirrors) list() []url.URL {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.urls
+}
+
+// reorder replace order of mirrors (downloads in progress keep old order)
+func (m *storMirrors) reorder(urls []url.URL) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.urls = urls
+}
+
+// cursor return mirror of new download by policy
+func (m *storMirrors) cursor() *mirrorCursor {
+	urls := m.list()
+
+	index := 0
+	if m.policy == MirrorRoundRobin {
+		index = int((atomic.AddUint64(&m.next, 1) - 1) % uint64(len(urls)))
+	}
+
+	return &mirrorCursor{urls: urls, index: index}
+}
+
+// mirrorCursor is mirror used by attempts of one download
+type mirrorCursor struct {
+	urls  []url.URL
+	index int
+}
+
+func (c *mirrorCursor) url() url.URL {
+	return c.urls[c.index]
+}
+
+// failover move cursor to next mirror after 5xx or connection error,
+// other errors (e.g. 404) are answer of mirror and retry stays on it
+func (c *mirrorCursor) failover(err error) {
+	if !isMirrorFailure(err) {
+		return
+	}
+
+	c.index = (c.index + 1) % len(c.urls)
+}
+
+func isMirrorFailure(err error) bool {
+	switch e := err.(type) {
+	case StatusError:
+		return e.StatusCode >= 500
+	case *url.Error:
+		return true
+	default:
+		return false
+	}
+}
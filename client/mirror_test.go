@@ -0,0 +1,110 @@
+package storclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mirrorServer struct {
+	*httptest.Server
+	lock     sync.Mutex
+	requests int
+}
+
+func newMirrorServer(status int) *mirrorServer {
+	server := &mirrorServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.lock.Lock()
+		server.requests++
+		server.lock.Unlock()
+
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte("mirror"))
+	}))
+
+	return server
+}
+
+func (server *mirrorServer) url() url.URL {
+	u, _ := url.Parse(server.URL)
+	return *u
+}
+
+func TestMirrorFailover(t *testing.T) {
+	primary := newMirrorServer(http.StatusServiceUnavailable)
+	defer primary.Close()
+	secondary := newMirrorServer(http.StatusOK)
+	defer secondary.Close()
+
+	client, err := New(primary.url(), os.TempDir(), StorClientOpts{
+		Max:          1,
+		Devnull:      true,
+		RetryDelay:   1,
+		Mirrors:      []url.URL{secondary.url()},
+		MirrorPolicy: MirrorFailover,
+	})
+	assert.NoError(t, err)
+
+	client.Start()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, client.Download(contentHash("mirror")))
+	}
+	total := client.Wait()
+
+	assert.Equal(t, 3, total.Count)
+	assert.Equal(t, 3, primary.requests, "every download starts on primary")
+	assert.Equal(t, 3, secondary.requests)
+}
+
+func TestMirrorRoundRobin(t *testing.T) {
+	servers := []*mirrorServer{newMirrorServer(http.StatusOK), newMirrorServer(http.StatusOK), newMirrorServer(http.StatusOK)}
+	for _, server := range servers {
+		defer server.Close()
+	}
+
+	client, err := New(servers[0].url(), os.TempDir(), StorClientOpts{
+		Max:          1,
+		Devnull:      true,
+		Mirrors:      []url.URL{servers[1].url(), servers[2].url()},
+		MirrorPolicy: MirrorRoundRobin,
+	})
+	assert.NoError(t, err)
+
+	client.Start()
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, client.Download(contentHash("mirror")))
+	}
+	total := client.Wait()
+
+	assert.Equal(t, 6, total.Count)
+	for _, server := range servers {
+		assert.Equal(t, 2, server.requests)
+	}
+}
+
+func TestMirrorCursor(t *testing.T) {
+	primary, _ := url.Parse("http://primary")
+	secondary, _ := url.Parse("http://secondary")
+	cursor := newStorMirrors(*primary, []url.URL{*secondary}, MirrorFailover).cursor()
+
+	cursor.failover(StatusError{StatusCode: 404})
+	assert.Equal(t, "primary", cursor.url().Host, "404 is answer of mirror")
+
+	cursor.failover(StatusError{StatusCode: 502})
+	assert.Equal(t, "secondary", cursor.url().Host)
+
+	cursor.failover(&url.Error{Op: "Get", URL: "http://secondary", Err: errors.New("connection refused")})
+	assert.Equal(t, "primary", cursor.url().Host, "failover wraps around")
+
+	assert.Equal(t, "round-robin", MirrorRoundRobin.String())
+}
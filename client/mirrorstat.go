@@ -0,0 +1,93 @@
+package storclient
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// MirrorStat is statistics of requests to one mirror (storage host)
+type MirrorStat struct {
+	// count of requests (attempts)
+	Requests int `json:"requests"`
+	// count of failed requests
+	Failures int `json:"failures"`
+	// downloaded bytes
+	Size int64 `json:"size"`
+	// sum of durations of all requests
+	Latency time.Duration `json:"latency"`
+}
+
+// MeanLatency return mean duration of request
+func (stat MirrorStat) MeanLatency() time.Duration {
+	if stat.Requests == 0 {
+		return 0
+	}
+
+	return stat.Latency / time.Duration(stat.Requests)
+}
+
+func (stat MirrorStat) merge(other MirrorStat) MirrorStat {
+	return MirrorStat{
+		Requests: stat.Requests + other.Requests,
+		Failures: stat.Failures + other.Failures,
+		Size:     stat.Size + other.Size,
+		Latency:  stat.Latency + other.Latency,
+	}
+}
+
+// mirrorKey return mirror identification (scheme://host) of url
+func mirrorKey(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return rawurl
+	}
+
+	return u.Scheme + "://" + u.Host
+}
+
+type mirrorStats struct {
+	lock  sync.Mutex
+	stats map[string]MirrorStat
+}
+
+func (m *mirrorStats) record(rawurl string, size int64, latency time.Duration, err error) {
+	stat := MirrorStat{Requests: 1, Size: size, Latency: latency}
+	if err != nil {
+		stat.Failures = 1
+	}
+
+	key := mirrorKey(rawurl)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stats == nil {
+		m.stats = make(map[string]MirrorStat)
+	}
+	m.stats[key] = m.stats[key].merge(stat)
+}
+
+// snapshot return copy of stats (nil if nothing is recorded)
+func (m *mirrorStats) snapshot() map[string]MirrorStat {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return mergeMirrorStats(m.stats, nil)
+}
+
+func mergeMirrorStats(a, b map[string]MirrorStat) map[string]MirrorStat {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]MirrorStat, len(a)+len(b))
+	for key, stat := range a {
+		merged[key] = merged[key].merge(stat)
+	}
+	for key, stat := range b {
+		merged[key] = merged[key].merge(stat)
+	}
+
+	return merged
+}
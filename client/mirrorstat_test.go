@@ -0,0 +1,26 @@
+package storclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirrorStats(t *testing.T) {
+	var stats mirrorStats
+	assert.Nil(t, stats.snapshot())
+
+	stats.record("http://stor.domain.tld/abc", 10, time.Second, nil)
+	stats.record("http://stor.domain.tld/def", 0, 3*time.Second, errors.New("500"))
+	stats.record("https://bucket.s3.amazonaws.com/ab/cd/ef/abc", 20, time.Second, nil)
+
+	snapshot := stats.snapshot()
+	assert.Equal(t, MirrorStat{Requests: 2, Failures: 1, Size: 10, Latency: 4 * time.Second}, snapshot["http://stor.domain.tld"])
+	assert.Equal(t, 2*time.Second, snapshot["http://stor.domain.tld"].MeanLatency())
+	assert.Equal(t, int64(20), snapshot["https://bucket.s3.amazonaws.com"].Size)
+
+	merged := TotalStat{Mirrors: snapshot}.Merge(TotalStat{Mirrors: snapshot})
+	assert.Equal(t, 4, merged.Mirrors["http://stor.domain.tld"].Requests)
+}
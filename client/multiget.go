@@ -0,0 +1,430 @@
+package storclient
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// MultiGetPath is path of batch endpoint relative to storage url (see StorClientOpts.MultiGet)
+const MultiGetPath = "multi"
+
+// MultiGetShaHeader is header of part of multipart response of batch with hex hash of its content
+const MultiGetShaHeader = "X-Sha"
+
+// DefaultMultiGetSize is max count of hashes of one batch request
+const DefaultMultiGetSize = 100
+
+// MultiGetOpts is configuration of download of many hashes by one request (see StorClientOpts.MultiGet)
+//
+// worker which pops sha from queue requests it with next queued shas (jobs stay in queue, so priorities
+// and fair queuing are kept), batch is POST <storage url>/multi with one hex hash per line, stor responds
+// with multipart/mixed (one part per object, hash in MultiGetShaHeader) or application/x-tar (one member
+// per object named by hash), objects missing on stor are omitted. Content of each part is verified
+// (by Verifier or ChecksumOffload like GET) and staged as temp file (see StorClientOpts.TempDir), worker
+// then finishes the sha as usual - sha missing in response (or with wrong content) is downloaded by GET.
+// Stor responding 404, 405 or 501 doesn't support batches, so all next downloads use GET
+type MultiGetOpts struct {
+	// max count of hashes of one request
+	// default (0) is DefaultMultiGetSize
+	Size int
+}
+
+// multiGetter is state of batch downloads shared by workers
+type multiGetter struct {
+	size int
+	// stor doesn't support batches
+	unsupported int32
+	lock        sync.Mutex
+	// verified content by hex hash
+	staged map[string]stagedPart
+	// shas requested by batch (each sha at most once) until taken by worker,
+	// channel is closed when batch ends
+	batched map[string]chan struct{}
+}
+
+// stagedPart is verified content of sha from batch response
+type stagedPart struct {
+	// temp file of content, empty with Devnull
+	path         string
+	size         int64
+	lastModified time.Time
+}
+
+func newMultiGetter(opts MultiGetOpts) *multiGetter {
+	size := opts.Size
+	if size == 0 {
+		size = DefaultMultiGetSize
+	}
+
+	return &multiGetter{size: size, staged: make(map[string]stagedPart), batched: make(map[string]chan struct{})}
+}
+
+func (m *multiGetter) supported() bool {
+	return atomic.LoadInt32(&m.unsupported) == 0
+}
+
+func (m *multiGetter) put(sha hashutil.Hash, part stagedPart) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if old, ok := m.staged[sha.String()]; ok {
+		old.discard()
+	}
+	m.staged[sha.String()] = part
+}
+
+// claim return shas which weren't batched yet (duplicates are removed) and channel which
+// must be closed when batch ends, nothing is claimed if there are less than 2 shas
+// (one sha is downloaded by GET with resume, mirrors etc.)
+func (m *multiGetter) claim(shas []hashutil.Hash) ([]hashutil.Hash, chan struct{}) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	claimed := make([]hashutil.Hash, 0, len(shas))
+	seen := make(map[string]bool, len(shas))
+	for _, sha := range shas {
+		key := sha.String()
+		if _, ok := m.batched[key]; ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		claimed = append(claimed, sha)
+	}
+
+	if len(claimed) < 2 {
+		return nil, nil
+	}
+
+	done := make(chan struct{})
+	for _, sha := range claimed {
+		m.batched[sha.String()] = done
+	}
+
+	return claimed, done
+}
+
+// take return staged content of sha (if any), it waits for end of batch of sha;
+// nil multiGetter hasn't any
+func (m *multiGetter) take(sha hashutil.Hash) (stagedPart, bool) {
+	if m == nil {
+		return stagedPart{}, false
+	}
+
+	m.lock.Lock()
+	done, ok := m.batched[sha.String()]
+	m.lock.Unlock()
+	if ok {
+		<-done
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	part, ok := m.staged[sha.String()]
+	delete(m.staged, sha.String())
+	delete(m.batched, sha.String())
+
+	return part, ok
+}
+
+// discardAll remove staged content which wasn't taken by worker (e.g. sha was skipped)
+func (m *multiGetter) discardAll() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for key, part := range m.staged {
+		part.discard()
+		delete(m.staged, key)
+	}
+}
+
+// install rename staged content to final path, staged file is removed on failure
+func (part stagedPart) install(filepath string, attrs fileAttrs) (size int64, err error) {
+	if part.path == "" {
+		return part.size, nil
+	}
+
+	defer func() {
+		if err != nil {
+			part.discard()
+		}
+	}()
+
+	if err := attrs.apply(part.path); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(part.path, filepath); err != nil {
+		return 0, errors.Wrapf(err, "Rename staged %s to final path %s fail", part.path, filepath)
+	}
+
+	if err := os.Chtimes(filepath, part.lastModified, part.lastModified); err != nil {
+		return 0, errors.Wrapf(err, "Chtimes(%s, %s) fail", filepath, part.lastModified.String())
+	}
+
+	return part.size, nil
+}
+
+// installStaged check staged content by ChecksumOffload (if any) and rename it to final path,
+// staged file is removed on failure
+func (client *StorClient) installStaged(part stagedPart, sha hashutil.Hash, filepath string) (int64, error) {
+	if checkTemp := client.checkTemp(sha); checkTemp != nil && part.path != "" {
+		if err := checkTemp(part.path); err != nil {
+			part.discard()
+			if errors.Is(err, ErrChecksumMismatch) {
+				return 0, shaMismatchError{expected: sha, reason: err.Error()}
+			}
+			return 0, err
+		}
+	}
+
+	size, err := part.install(filepath, client.fileAttrs)
+	if err != nil || client.Devnull {
+		return size, err
+	}
+
+	return size, verifyFile(filepath, size, sha, client.VerifyAfterRename)
+}
+
+func (part stagedPart) discard() {
+	if part.path != "" {
+		_ = os.Remove(part.path)
+	}
+}
+
+// prefetch download content of job and next queued jobs by one batch request, jobs stay in queue
+// and workers which pop them take staged content (see multiGetter.take); failed batch is only
+// logged (shas are downloaded by GET)
+func (client *StorClient) prefetch(id int, queue *priorityQueue, job downloadJob, httpClientFunc func() httpClient, workerBucket *tokenBucket) {
+	if !client.multiGet.supported() {
+		return
+	}
+
+	batch := append([]downloadJob{job}, queue.peek(client.multiGet.size-1)...)
+
+	shas := make([]hashutil.Hash, 0, len(batch))
+	for _, job := range batch {
+		if client.multiGettable(job) {
+			shas = append(shas, job.sha)
+		}
+	}
+
+	shas, done := client.multiGet.claim(shas)
+	if done == nil {
+		return
+	}
+	defer close(done)
+
+	httpClient := client.throttle(httpClientFunc(), workerBucket)
+	client.limitRequestTime(httpClient, client.Clock.Now())
+
+	if err := client.multiGetShas(httpClient, shas); err != nil {
+		log.WithField("worker", id).Warnf("Batch download of %d shas fail, fallback to GET: %s", len(shas), err)
+	}
+}
+
+// multiGettable return true if job is download to download dir of client which isn't done yet
+func (client *StorClient) multiGettable(job downloadJob) bool {
+	if job.uploadPath != "" || job.tenant != nil || job.bundle || job.err(client.ctx) != nil {
+		return false
+	}
+
+	if client.Devnull || client.Overwrite {
+		return true
+	}
+
+	filepath, err := pathutil.New(client.downloadDir, client.filename(job.sha))
+	return err == nil && !filepath.Exists()
+}
+
+// multiGetShas request content of shas by one batch request and stage verified content
+func (client *StorClient) multiGetShas(httpClient httpClient, shas []hashutil.Hash) (err error) {
+	wanted := make(map[string]hashutil.Hash, len(shas))
+	var body strings.Builder
+	for _, sha := range shas {
+		wanted[sha.String()] = sha
+		body.WriteString(sha.String())
+		body.WriteString("\n")
+	}
+
+	multiURL := client.storageUrl
+	multiURL.Path = path.Join("/", multiURL.Path, MultiGetPath)
+
+	if !setRequestBody(httpClient, []byte(body.String())) {
+		return errors.New("batch request needs client of worker")
+	}
+	setRequestHeader(httpClient, "Content-Type", "text/plain")
+	setRequestHeader(httpClient, "Accept", "multipart/mixed, application/x-tar")
+
+	resp, err := httpClient.Get(multiURL.String())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		atomic.StoreInt32(&client.multiGet.unsupported, 1)
+		return errors.Errorf("Batch download isn't supported by stor %d (%s)", resp.StatusCode, resp.Status)
+	default:
+		return errors.Errorf("Batch download fail %d (%s)", resp.StatusCode, resp.Status)
+	}
+
+	lastModified, err := getLastModifiedTime(resp)
+	if err != nil {
+		return err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return errors.Wrap(err, "Invalid content type of batch response")
+	}
+
+	files := 0
+	stage := func(name string, content io.Reader) error {
+		files++
+		if err := client.decompression.checkFiles(files); err != nil {
+			return err
+		}
+
+		sha, ok := wanted[strings.ToLower(name)]
+		if !ok {
+			log.Warnf("Unexpected part %q of batch response - skip", name)
+			_, err := io.Copy(ioutil.Discard, content)
+			return err
+		}
+		delete(wanted, sha.String())
+
+		return client.stagePart(sha, content, lastModified)
+	}
+
+	switch mediaType {
+	case "multipart/mixed":
+		parts := multipart.NewReader(resp.Body, params["boundary"])
+		for {
+			part, err := parts.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return errors.Wrap(err, "Read of batch response fail")
+			}
+			if err := stage(part.Header.Get(MultiGetShaHeader), part); err != nil {
+				return err
+			}
+		}
+	case "application/x-tar":
+		members := tar.NewReader(resp.Body)
+		for {
+			header, err := members.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return errors.Wrap(err, "Read of batch response fail")
+			}
+			if header.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := stage(path.Base(header.Name), members); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.Errorf("Unsupported content type %q of batch response", mediaType)
+	}
+}
+
+// stagePart verify content of sha by Verifier and write it to temp file (only verify with Devnull),
+// part with wrong content is dropped (sha is downloaded by GET); with ChecksumOffload content
+// is verified by worker before install (see StorClient.installStaged)
+func (client *StorClient) stagePart(sha hashutil.Hash, content io.Reader, lastModified time.Time) (err error) {
+	var v *verification
+	if client.ChecksumOffload == nil {
+		if v, err = newVerification(client.Verifier, sha); err != nil {
+			return err
+		}
+	}
+
+	part := stagedPart{lastModified: lastModified}
+
+	var out io.Writer = ioutil.Discard
+	if !client.Devnull {
+		// err of defer below must be result of stagePart
+		file, errCreate := client.createStagedFile(sha)
+		if errCreate != nil {
+			return errCreate
+		}
+		part.path = file.Name()
+		defer func() {
+			if errClose := file.Close(); errClose != nil && err == nil {
+				err = errClose
+			}
+			if err != nil {
+				part.discard()
+			}
+		}()
+		out = file
+	}
+
+	part.size, err = io.Copy(io.MultiWriter(errWriter{w: out}, v.writer()), content)
+	if err != nil {
+		return err
+	}
+
+	if errVerify := v.verify(sha); errVerify != nil {
+		log.WithField("sha256", sha.String()).Warnf("Part of batch response is wrong (%s) - fallback to GET", errVerify)
+		part.discard()
+		return nil
+	}
+
+	client.multiGet.put(sha, part)
+
+	return nil
+}
+
+// createStagedFile create temp file of sha from batch response (see tempFiles)
+func (client *StorClient) createStagedFile(sha hashutil.Hash) (*os.File, error) {
+	filepath, err := pathutil.New(client.downloadDir, client.filename(sha))
+	if err != nil {
+		return nil, err
+	}
+
+	if client.staging.dir == "" {
+		if err := client.dirs.ensure(filepath.Parent().Canonpath()); err != nil {
+			return nil, err
+		}
+	}
+
+	temppath, err := client.staging.path(filepath, sha, ".multi")
+	if err != nil {
+		return nil, errors.Wrap(err, "Construct of temp file path fail")
+	}
+
+	file, err := os.OpenFile(temppath.Canonpath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Create of tempfile %s fail", temppath)
+	}
+
+	return file, nil
+}
@@ -0,0 +1,188 @@
+package storclient
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// multiGetServer serve objects by GET and by batch in format (multipart or tar),
+// batch part of "broken" object has wrong content and "missing" object isn't in batch
+func multiGetServer(t *testing.T, format string, objects map[string]string, gets, posts *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(gets, 1)
+			content, ok := objects[strings.TrimPrefix(r.URL.Path, "/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(content))
+			return
+		}
+
+		atomic.AddInt32(posts, 1)
+		if r.URL.Path != "/"+MultiGetPath || format == "" {
+			http.Error(w, "batch isn't supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var shas []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			shas = append(shas, scanner.Text())
+		}
+
+		switch format {
+		case "multipart":
+			parts := multipart.NewWriter(w)
+			w.Header().Set("Content-Type", "multipart/mixed; boundary="+parts.Boundary())
+			for _, sha := range shas {
+				content := objects[sha]
+				switch content {
+				case "missing":
+					continue
+				case "broken":
+					content = "other"
+				}
+				part, err := parts.CreatePart(map[string][]string{MultiGetShaHeader: {strings.ToUpper(sha)}})
+				assert.NoError(t, err)
+				_, _ = part.Write([]byte(content))
+			}
+			assert.NoError(t, parts.Close())
+		case "tar":
+			w.Header().Set("Content-Type", "application/x-tar")
+			members := tar.NewWriter(w)
+			for _, sha := range shas {
+				content := objects[sha]
+				assert.NoError(t, members.WriteHeader(&tar.Header{Name: "objects/" + sha, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+				_, _ = members.Write([]byte(content))
+			}
+			assert.NoError(t, members.Close())
+		}
+	}))
+}
+
+func TestMultiGet(t *testing.T) {
+	objects := map[string]string{}
+	for _, content := range []string{"first", "second", "third", "broken", "missing"} {
+		objects[contentHash(content).String()] = content
+	}
+
+	for _, test := range []struct {
+		format   string
+		maxFiles int
+		gets     int32
+		posts    int32
+	}{
+		{format: "multipart", gets: 2, posts: 2},
+		{format: "tar", gets: 0, posts: 2},
+		{format: "", gets: 5, posts: 1},
+		// each batch stages first member only
+		{format: "tar", maxFiles: 1, gets: 3, posts: 2},
+	} {
+		t.Run(test.format, func(t *testing.T) {
+			tmpDir, err := ioutil.TempDir("", "multiget")
+			assert.NoError(t, err)
+			defer os.RemoveAll(tmpDir)
+
+			var gets, posts int32
+			server := multiGetServer(t, test.format, objects, &gets, &posts)
+			defer server.Close()
+
+			serverURL, _ := url.Parse(server.URL)
+			client, err := New(*serverURL, tmpDir, StorClientOpts{Max: 1, RetryAttempts: 1, MultiGet: &MultiGetOpts{Size: 3}, MaxArchiveFiles: test.maxFiles})
+			assert.NoError(t, err)
+
+			for _, content := range []string{"first", "second", "third", "broken", "missing"} {
+				assert.NoError(t, client.Download(contentHash(content)))
+			}
+			client.Start()
+			total := client.Wait()
+
+			assert.Equal(t, 5, total.Count)
+			assert.Equal(t, test.gets, atomic.LoadInt32(&gets))
+			assert.Equal(t, test.posts, atomic.LoadInt32(&posts), "unsupported batch isn't requested again")
+			if test.gets == 0 {
+				var transferred int64
+				for _, window := range total.Bandwidth {
+					transferred += window.Size
+				}
+				assert.True(t, transferred > 0, "batch response is metered like GET")
+			}
+
+			for sha, content := range objects {
+				downloaded, err := ioutil.ReadFile(filepath.Join(tmpDir, sha))
+				assert.NoError(t, err)
+				assert.Equal(t, content, string(downloaded))
+			}
+
+			files, err := ioutil.ReadDir(tmpDir)
+			assert.NoError(t, err)
+			assert.Len(t, files, len(objects), "staged files are renamed or removed")
+		})
+	}
+}
+
+func TestMultiGetVerification(t *testing.T) {
+	objects := map[string]string{}
+	for _, content := range []string{"first", "second"} {
+		objects[contentHash(content).String()] = content
+	}
+
+	for name, opts := range map[string]StorClientOpts{
+		"verifier": {Verifier: sizeVerifierOf(1)},
+		"offload": {ChecksumOffload: ChecksumOffloadFunc(func(context.Context, string, hashutil.Hash) error {
+			return errors.Wrap(ErrChecksumMismatch, "rejected by sidecar")
+		})},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tmpDir, err := ioutil.TempDir("", "multiget")
+			assert.NoError(t, err)
+			defer os.RemoveAll(tmpDir)
+
+			var gets, posts int32
+			server := multiGetServer(t, "multipart", objects, &gets, &posts)
+			defer server.Close()
+
+			opts.Max, opts.RetryAttempts, opts.MultiGet = 1, 2, &MultiGetOpts{Size: 2}
+			serverURL, _ := url.Parse(server.URL)
+			client, err := New(*serverURL, tmpDir, opts)
+			assert.NoError(t, err)
+
+			for _, content := range []string{"first", "second"} {
+				assert.NoError(t, client.Download(contentHash(content)))
+			}
+			client.Start()
+			total := client.Wait()
+
+			assert.Equal(t, int32(1), atomic.LoadInt32(&posts))
+			assert.Equal(t, 2, total.Fail, "batch content is verified like GET")
+			files, err := ioutil.ReadDir(tmpDir)
+			assert.NoError(t, err)
+			assert.Empty(t, files)
+		})
+	}
+}
+
+func TestMultiGetOpts(t *testing.T) {
+	_, err := New(url.URL{}, os.TempDir(), StorClientOpts{MultiGet: &MultiGetOpts{}, DryRun: true})
+	assert.Error(t, err)
+
+	_, err = New(url.URL{}, os.TempDir(), StorClientOpts{MultiGet: &MultiGetOpts{Size: -1}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package storclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+)
+
+// ChecksumOffload verify downloaded file outside of process
+// (e.g. sidecar service in HSM-backed or sandboxed environment)
+//
+// Verify is called with path of complete temp file before it is renamed to final path,
+// error matching ErrChecksumMismatch (errors.Is) means wrong content - temp file is removed
+// and download is retried, other error (e.g. unreachable sidecar) fails attempt and temp file
+// is kept for next attempt
+type ChecksumOffload interface {
+	Verify(ctx context.Context, path string, expected hashutil.Hash) error
+}
+
+// ChecksumOffloadFunc is adapter of function to ChecksumOffload (plugin in process)
+type ChecksumOffloadFunc func(ctx context.Context, path string, expected hashutil.Hash) error
+
+// Verify call f(ctx, path, expected)
+func (f ChecksumOffloadFunc) Verify(ctx context.Context, path string, expected hashutil.Hash) error {
+	return f(ctx, path, expected)
+}
+
+// HTTPChecksumOffload is sidecar service verifying files over http
+//
+// request is POST to URL with json body {"path": "<path>", "sha": "<hex hash>"},
+// status 200 means file is ok, status 409 or 422 means wrong file (body is reason),
+// other statuses and network errors are failures of sidecar
+type HTTPChecksumOffload struct {
+	URL string
+	// default (nil) means http.DefaultClient
+	Client *http.Client
+}
+
+type checksumOffloadRequest struct {
+	Path string `json:"path"`
+	Sha  string `json:"sha"`
+}
+
+// Verify ask sidecar service to check file
+func (offload HTTPChecksumOffload) Verify(ctx context.Context, path string, expected hashutil.Hash) error {
+	body, err := json.Marshal(checksumOffloadRequest{Path: path, Sha: expected.String()})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, offload.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := offload.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "Checksum offload fail")
+	}
+	defer resp.Body.Close()
+
+	reason, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusConflict, http.StatusUnprocessableEntity:
+		return errors.Wrapf(ErrChecksumMismatch, "Checksum offload reject %s: %s %s", path, resp.Status, bytes.TrimSpace(reason))
+	default:
+		return errors.Errorf("Checksum offload fail %s: %s %s", path, resp.Status, bytes.TrimSpace(reason))
+	}
+}
+
+// checkTemp return check of temp file by ChecksumOffload (nil means in-process hash check)
+func (client *StorClient) checkTemp(sha hashutil.Hash) func(path string) error {
+	if client.ChecksumOffload == nil {
+		return nil
+	}
+
+	return func(path string) error {
+		return client.ChecksumOffload.Verify(client.ctx, path, sha)
+	}
+}
@@ -0,0 +1,100 @@
+package storclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksumOffload(t *testing.T) {
+	content := []byte("offloaded content")
+	sum := sha256.Sum256(content)
+	sha, _ := hashutil.BytesToHash(sha256.New(), sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	accept, fail := true, false
+	var checked []checksumOffloadRequest
+	sidecar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req checksumOffloadRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		checked = append(checked, req)
+		if fail {
+			http.Error(w, "sidecar is overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		if !accept {
+			http.Error(w, "hash mismatch", http.StatusConflict)
+		}
+	}))
+	defer sidecar.Close()
+
+	tmpDir, err := ioutil.TempDir("", "offload")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	target, err := pathutil.New(tmpDir, sha.String())
+	assert.NoError(t, err)
+	temp := filepath.Join(tmpDir, sha.String()+".temp")
+
+	offload := HTTPChecksumOffload{URL: sidecar.URL}
+	check := func(path string) error {
+		return offload.Verify(context.Background(), path, sha)
+	}
+	httpClient := &contextClient{Client: &http.Client{}, ctx: context.Background()}
+
+	size, err := downloadFileViaTempFileWith(httpClient, target, server.URL, sha, check, fileAttrs{}, tempFiles{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), size)
+	assert.True(t, fileExists(target.Canonpath()))
+	assert.Equal(t, []checksumOffloadRequest{{Path: temp, Sha: sha.String()}}, checked, "temp file is verified by sidecar")
+
+	assert.NoError(t, os.Remove(target.Canonpath()))
+	accept = false
+
+	_, err = downloadFileViaTempFileWith(httpClient, target, server.URL, sha, check, fileAttrs{}, tempFiles{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "hash mismatch")
+	assert.False(t, fileExists(target.Canonpath()), "rejected file isn't renamed")
+	assert.False(t, fileExists(temp), "rejected temp file is removed")
+	assert.True(t, errors.Is(err, ErrChecksumMismatch))
+
+	accept, fail = true, true
+
+	_, err = downloadFileViaTempFileWith(httpClient, target, server.URL, sha, check, fileAttrs{}, tempFiles{})
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrChecksumMismatch), "failure of sidecar isn't mismatch")
+	assert.True(t, retryable(err, new(bool)))
+	assert.True(t, fileExists(temp), "temp file is kept for next attempt")
+
+	fail = false
+	checked = nil
+
+	size, err = downloadFileViaTempFileWith(httpClient, target, server.URL, sha, check, fileAttrs{}, tempFiles{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), size, "complete temp file isn't downloaded again")
+	assert.True(t, fileExists(target.Canonpath()))
+	assert.Len(t, checked, 1)
+}
+
+func TestChecksumOffloadWithDevnull(t *testing.T) {
+	_, err := New(url.URL{Scheme: "http", Host: "localhost"}, os.TempDir(), StorClientOpts{Devnull: true, ChecksumOffload: HTTPChecksumOffload{URL: "http://localhost"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package storclient
+
+import (
+	"sync"
+
+	"github.com/avast/hashutil-go"
+)
+
+// overwriteDedupSet is DedupSet of Overwrite mode - sha which is downloading now isn't skipped,
+// ContainsOrAdd waits for end of running download and sha is fetched again
+// (downloads of one sha never share temp file)
+type overwriteDedupSet struct {
+	set DedupSet
+
+	lock    sync.Mutex
+	running map[string]*overwriteDownload
+}
+
+type overwriteDownload struct {
+	done chan struct{}
+	// added is true if sha was added to underlying set (so it's removed at the end)
+	added bool
+}
+
+func newOverwriteDedupSet(set DedupSet) *overwriteDedupSet {
+	return &overwriteDedupSet{set: set, running: make(map[string]*overwriteDownload)}
+}
+
+// ContainsOrAdd wait for end of running download of hash, it always returns true
+func (s *overwriteDedupSet) ContainsOrAdd(hash hashutil.Hash) bool {
+	download := &overwriteDownload{done: make(chan struct{})}
+	for {
+		s.lock.Lock()
+		running, ok := s.running[hash.String()]
+		if !ok {
+			s.running[hash.String()] = download
+			s.lock.Unlock()
+			break
+		}
+		s.lock.Unlock()
+
+		<-running.done
+	}
+
+	// shared set still sees the download, but download in other process doesn't skip it
+	download.added = s.set.ContainsOrAdd(hash)
+
+	return true
+}
+
+// Del end download of hash and wake up waiting one
+func (s *overwriteDedupSet) Del(hash hashutil.Hash) {
+	s.lock.Lock()
+	download, ok := s.running[hash.String()]
+	delete(s.running, hash.String())
+	s.lock.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if download.added {
+		s.set.Del(hash)
+	}
+	close(download.done)
+}
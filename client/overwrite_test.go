@@ -0,0 +1,75 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverwrite(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "overwrite")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	sha := contentHash("fresh")
+	path := filepath.Join(tmpDir, sha.String())
+	assert.NoError(t, ioutil.WriteFile(path, []byte("stale"), 0644))
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = w.Write([]byte("fresh"))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, tmpDir, StorClientOpts{Max: 2, RetryAttempts: 1, Overwrite: true})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(sha))
+	assert.NoError(t, client.Download(sha))
+	total := client.Wait()
+
+	assert.Equal(t, 2, total.Count, "existing and downloading files aren't skipped")
+	assert.Zero(t, total.Skip)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", string(content))
+}
+
+func TestOverwriteDedupSet(t *testing.T) {
+	shared := NewMemoryDedupSet()
+	set := newOverwriteDedupSet(shared)
+
+	assert.True(t, set.ContainsOrAdd(emptyHash))
+	assert.False(t, shared.ContainsOrAdd(emptyHash), "running download is in shared set")
+
+	second := make(chan struct{})
+	go func() {
+		set.ContainsOrAdd(emptyHash)
+		close(second)
+	}()
+
+	select {
+	case <-second:
+		t.Fatal("second download of sha doesn't wait for running one")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	set.Del(emptyHash)
+	<-second
+	set.Del(emptyHash)
+
+	assert.True(t, shared.ContainsOrAdd(emptyHash), "sha is removed from shared set")
+}
@@ -0,0 +1,208 @@
+package storclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultParallelThreshold is min size of object downloaded by parallel chunks (see StorClientOpts.ParallelChunks)
+const DefaultParallelThreshold = 64 << 20
+
+// errChunkAborted stops writes of chunks after failure of other chunk
+var errChunkAborted = errors.New("download of other chunk fail")
+
+// parallelDownload is object downloaded by concurrent range requests
+type parallelDownload struct {
+	// new http client for each chunk (range header is set per client)
+	newClient func() httpClient
+	url       string
+	sha       hashutil.Hash
+	size      int64
+	chunks    int
+	aborted   int32
+}
+
+// parallelMetadata return metadata of object and true if it should be downloaded in parallel chunks
+// (server accepts byte ranges and object isn't smaller than threshold), objects of source are always single stream
+func (client *StorClient) parallelMetadata(httpClient httpClient, url string, sha hashutil.Hash, trySource bool) (Metadata, bool) {
+	if client.ParallelChunks < 2 || trySource {
+		return Metadata{}, false
+	}
+
+	metadata, err := headMetadata(httpClient, url, sha)
+	if err != nil {
+		log.WithField("sha256", sha.String()).Debugf("HEAD before parallel download fail, download in single stream: %s", err)
+		return Metadata{}, false
+	}
+
+	if metadata.Size < client.ParallelThreshold || metadata.Header.Get("Accept-Ranges") != "bytes" {
+		return Metadata{}, false
+	}
+
+	return metadata, true
+}
+
+// downloadFileParallel download object of known size to <sha>.parts.temp (see tempFiles) by concurrent range
+// requests, whole content is verified (by checkTemp if it's set) before rename to filepath
+//
+// unlike downloadFileViaTempFile, failed download isn't resumed - temp file is always removed on failure
+func downloadFileParallel(httpClient httpClient, newClient func() httpClient, filepath pathutil.Path, url string, expectedSha hashutil.Hash, metadata Metadata, chunks int, checkTemp func(path string) error, attrs fileAttrs, staging tempFiles) (int64, error) {
+	size := metadata.Size
+
+	temppath, err := staging.path(filepath, expectedSha, ".parts")
+	if err != nil {
+		return 0, errors.Wrap(err, "Construct of temp file path fail")
+	}
+	temp := temppath.Canonpath()
+
+	out, err := os.OpenFile(temp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Open of tempfile %s fail", temp)
+	}
+	defer os.Remove(temp)
+
+	err = out.Truncate(size)
+	if err == nil {
+		download := &parallelDownload{newClient: newClient, url: url, sha: expectedSha, size: size, chunks: chunks}
+		err = download.run(out)
+	}
+	if err == nil && checkTemp == nil {
+		err = verifyParallel(httpClient, out, expectedSha)
+	}
+	if errClose := out.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil && checkTemp != nil {
+		if err = checkTemp(temp); errors.Is(err, ErrChecksumMismatch) {
+			err = shaMismatchError{expected: expectedSha, reason: err.Error()}
+		}
+	}
+	if err == nil {
+		err = attrs.apply(temp)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(temp, filepath.Canonpath()); err != nil {
+		return 0, errors.Wrapf(err, "Rename temp %s to final path %s fail", temp, filepath)
+	}
+
+	if lastModified, err := http.ParseTime(metadata.Header.Get("Last-Modified")); err == nil {
+		if err := os.Chtimes(filepath.Canonpath(), lastModified, lastModified); err != nil {
+			return 0, errors.Wrapf(err, "Chtimes(%s, %s) fail", filepath.Canonpath(), lastModified.String())
+		}
+	}
+
+	return size, nil
+}
+
+// verifyParallel check whole content of file
+func verifyParallel(httpClient httpClient, file *os.File, expectedSha hashutil.Hash) error {
+	v, err := newVerificationOf(httpClient, expectedSha)
+	if err != nil || v == nil {
+		return err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(v.writer(), file); err != nil {
+		return errors.Wrapf(err, "Read of tempfile %s fail", file.Name())
+	}
+
+	return v.verify(expectedSha)
+}
+
+// run download all chunks concurrently to out, first error is returned
+func (d *parallelDownload) run(out io.WriterAt) error {
+	chunkSize := (d.size + int64(d.chunks) - 1) / int64(d.chunks)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, d.chunks)
+	for start := int64(0); start < d.size; start += chunkSize {
+		end := start + chunkSize - 1
+		if end >= d.size {
+			end = d.size - 1
+		}
+
+		wg.Add(1)
+		go func(start, end int64) {
+			defer wg.Done()
+
+			if err := d.chunk(out, start, end); err != nil {
+				atomic.StoreInt32(&d.aborted, 1)
+				errs <- err
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != errChunkAborted {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// chunk download bytes start-end (inclusive) to out
+func (d *parallelDownload) chunk(out io.WriterAt, start, end int64) (err error) {
+	httpClient := d.newClient()
+	if !setRequestHeader(httpClient, "Range", fmt.Sprintf("bytes=%d-%d", start, end)) {
+		return errors.New("range request isn't supported by http client")
+	}
+
+	resp, err := httpClient.Get(d.url)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+	}()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return StatusError{Sha: d.sha, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	length := end - start + 1
+	written, err := io.Copy(&chunkWriter{out: out, offset: start, download: d}, io.LimitReader(resp.Body, length))
+	if err != nil {
+		return err
+	}
+	if written != length {
+		return errors.Errorf("Chunk %d-%d of %s is truncated (%d bytes)", start, end, d.sha, written)
+	}
+
+	return nil
+}
+
+// chunkWriter write to out from offset, writes fail after failure of other chunk
+type chunkWriter struct {
+	out      io.WriterAt
+	offset   int64
+	download *parallelDownload
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	if atomic.LoadInt32(&w.download.aborted) != 0 {
+		return 0, errChunkAborted
+	}
+
+	n, err := w.out.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
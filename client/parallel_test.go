@@ -0,0 +1,76 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelDownload(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	small := "small"
+	corrupted := contentHash("corrupted")
+
+	var lock sync.Mutex
+	ranges := make(map[string][]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		if r.Method == http.MethodGet {
+			lock.Lock()
+			ranges[key] = append(ranges[key], r.Header.Get("Range"))
+			lock.Unlock()
+		}
+
+		body := content
+		switch key {
+		case contentHash(small).String():
+			body = small
+		case corrupted.String():
+			body = strings.Repeat("x", len(content))
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(body))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	tmpDir, err := ioutil.TempDir("", "parallel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	client, err := New(*serverURL, tmpDir, StorClientOpts{ParallelChunks: 4, ParallelThreshold: 100, RetryAttempts: 1})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(contentHash(content)))
+	assert.NoError(t, client.Download(contentHash(small)))
+	assert.NoError(t, client.Download(corrupted))
+	total := client.Wait()
+
+	assert.Equal(t, 2, total.Count)
+	assert.False(t, total.Status(), "corrupted object fails")
+
+	downloaded, err := ioutil.ReadFile(filepath.Join(tmpDir, contentHash(content).String()))
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(downloaded))
+	chunks := ranges[contentHash(content).String()]
+	sort.Strings(chunks)
+	assert.Equal(t, []string{"bytes=0-249", "bytes=250-499", "bytes=500-749", "bytes=750-999"}, chunks)
+
+	assert.Equal(t, []string{""}, ranges[contentHash(small).String()], "object under threshold is single stream")
+
+	_, err = os.Stat(filepath.Join(tmpDir, corrupted.String()+".parts.temp"))
+	assert.True(t, os.IsNotExist(err), "temp file of failed download is removed")
+
+	_, err = New(*serverURL, tmpDir, StorClientOpts{ParallelChunks: 4, Decompress: true})
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package storclient
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Pause stop workers from starting of next downloads, queue is kept and Download still enqueues
+//
+// in-flight downloads finish, Wait (and Stop with drain) blocks until Resume;
+// cancel of client (WaitCtx, WaitDeadline, Stop without drain) ends pause - queued downloads fail
+func (client *StorClient) Pause() {
+	if client.pause.pause() {
+		log.Info("Downloads paused")
+	}
+}
+
+// Resume continue downloading after Pause
+func (client *StorClient) Resume() {
+	if client.pause.resume() {
+		log.Info("Downloads resumed")
+	}
+}
+
+// Paused return true if downloads are paused (see Pause)
+func (client *StorClient) Paused() bool {
+	return client.pause.paused()
+}
+
+// pauseGate holds workers while paused
+type pauseGate struct {
+	lock sync.Mutex
+	// closed on resume, nil if isn't paused
+	resumed chan struct{}
+}
+
+// pause return false if is already paused
+func (gate *pauseGate) pause() bool {
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+
+	if gate.resumed != nil {
+		return false
+	}
+	gate.resumed = make(chan struct{})
+
+	return true
+}
+
+// resume return false if isn't paused
+func (gate *pauseGate) resume() bool {
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+
+	if gate.resumed == nil {
+		return false
+	}
+	close(gate.resumed)
+	gate.resumed = nil
+
+	return true
+}
+
+func (gate *pauseGate) paused() bool {
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+
+	return gate.resumed != nil
+}
+
+// wait block while is paused (or until ctx is done)
+func (gate *pauseGate) wait(ctx context.Context) {
+	gate.lock.Lock()
+	resumed := gate.resumed
+	gate.lock.Unlock()
+
+	if resumed == nil {
+		return
+	}
+
+	select {
+	case <-resumed:
+	case <-ctx.Done():
+	}
+}
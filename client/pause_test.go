@@ -0,0 +1,100 @@
+package storclient_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPauseResume(t *testing.T) {
+	objects := map[string]string{sha256Of("first").String(): "first", sha256Of("second").String(): "second"}
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(objects[strings.TrimPrefix(r.URL.Path, "/")]))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "", storclient.StorClientOpts{Devnull: true, Max: 2})
+	assert.NoError(t, err)
+
+	client.Start()
+	client.Pause()
+	assert.True(t, client.Paused())
+
+	assert.NoError(t, client.Download(sha256Of("first")))
+	assert.NoError(t, client.Download(sha256Of("second")))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests), "paused workers don't start downloads")
+	assert.True(t, client.Snapshot().Paused)
+
+	client.Resume()
+	assert.False(t, client.Paused())
+
+	total := client.Wait()
+	assert.True(t, total.Status())
+	assert.Equal(t, 2, total.Count)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestPausedStop(t *testing.T) {
+	client, err := storclient.New(url.URL{}, "", storclient.StorClientOpts{Devnull: true})
+	assert.NoError(t, err)
+
+	client.Start()
+	client.Pause()
+	assert.NoError(t, client.Download(sha256Of("first")))
+
+	total := client.Stop(false)
+	assert.Equal(t, 1, total.Failed())
+	assert.Equal(t, 1, total.Abandoned, "queued download of paused client is discarded")
+}
+
+func TestPausedStopWithFullQueue(t *testing.T) {
+	client, err := storclient.New(url.URL{}, "", storclient.StorClientOpts{Devnull: true, Max: 1})
+	assert.NoError(t, err)
+
+	client.Start()
+	client.Pause()
+
+	// pushes fill queue, last one blocks on full queue
+	var queued int32
+	pushErr := make(chan error, 1)
+	go func() {
+		for i := 0; ; i++ {
+			if err := client.Download(sha256Of(strconv.Itoa(i))); err != nil {
+				pushErr <- err
+				return
+			}
+			atomic.AddInt32(&queued, 1)
+		}
+	}()
+	for atomic.LoadInt32(&queued) < 1024 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	stopped := make(chan storclient.TotalStat)
+	go func() {
+		stopped <- client.Stop(false)
+	}()
+
+	select {
+	case total := <-stopped:
+		assert.Equal(t, int(atomic.LoadInt32(&queued)), total.Failed())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop is blocked by full queue of paused client")
+	}
+	assert.Equal(t, storclient.ErrQueueClosed, <-pushErr, "blocked Download is refused")
+}
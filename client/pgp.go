@@ -0,0 +1,128 @@
+package storclient
+
+import (
+	"bytes"
+	"hash"
+	"io"
+	"io/ioutil"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// pgpVerifier verify detached binary PGP signatures (<object>.sig or .asc) of content
+type pgpVerifier struct {
+	keyring openpgp.EntityList
+}
+
+// NewPGPVerifier return verifier of detached PGP signatures (binary or armored) of content of objects
+// made by key of keyRing (armored or binary public keyring)
+func NewPGPVerifier(keyRing io.Reader) (ContentSignatureVerifier, error) {
+	content, err := ioutil.ReadAll(keyRing)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyring openpgp.EntityList
+	if isArmored(content) {
+		keyring, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(content))
+	} else {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(content))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Read of PGP keyring fail")
+	}
+	if len(keyring) == 0 {
+		return nil, errors.New("PGP keyring is empty")
+	}
+
+	return pgpVerifier{keyring: keyring}, nil
+}
+
+func isArmored(content []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(content), []byte("-----BEGIN PGP"))
+}
+
+// parse return signature packet and keys which could make it
+func (v pgpVerifier) parse(signature []byte) (*packet.Signature, []openpgp.Key, error) {
+	var r io.Reader = bytes.NewReader(signature)
+	if isArmored(signature) {
+		block, err := armor.Decode(r)
+		if err != nil {
+			return nil, nil, errors.Wrapf(ErrSignature, "armored PGP signature: %s", err)
+		}
+		r = block.Body
+	}
+
+	p, err := packet.Read(r)
+	if err != nil {
+		return nil, nil, errors.Wrapf(ErrSignature, "PGP signature: %s", err)
+	}
+
+	sig, ok := p.(*packet.Signature)
+	if !ok {
+		return nil, nil, errors.Wrap(ErrSignature, "isn't PGP signature (v4)")
+	}
+	if sig.SigType != packet.SigTypeBinary {
+		return nil, nil, errors.Wrap(ErrSignature, "only binary PGP signature is supported")
+	}
+	if sig.IssuerKeyId == nil {
+		return nil, nil, errors.Wrap(ErrSignature, "PGP signature without issuer")
+	}
+	if !sig.Hash.Available() {
+		return nil, nil, errors.Wrapf(ErrSignature, "hash %d of PGP signature isn't supported", sig.Hash)
+	}
+
+	keys := v.keyring.KeysById(*sig.IssuerKeyId)
+	if len(keys) == 0 {
+		return nil, nil, errors.Wrapf(ErrSignature, "PGP signature is made by unknown key %X", *sig.IssuerKeyId)
+	}
+
+	return sig, keys, nil
+}
+
+func (v pgpVerifier) Verify(_ hashutil.Hash, signature []byte) error {
+	_, _, err := v.parse(signature)
+	return err
+}
+
+func (v pgpVerifier) Content(signature []byte) (Verifier, error) {
+	sig, keys, err := v.parse(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pgpContent{signature: sig, keys: keys}, nil
+}
+
+// pgpContent verify content against one PGP signature
+type pgpContent struct {
+	signature *packet.Signature
+	keys      []openpgp.Key
+	// hash of content per key (verification consumes hash)
+	hashes []hash.Hash
+}
+
+func (c *pgpContent) NewWriter() io.Writer {
+	c.hashes = make([]hash.Hash, len(c.keys))
+	writers := make([]io.Writer, len(c.keys))
+	for i := range c.keys {
+		c.hashes[i] = c.signature.Hash.New()
+		writers[i] = c.hashes[i]
+	}
+
+	return io.MultiWriter(writers...)
+}
+
+func (c *pgpContent) Verify(expected hashutil.Hash) error {
+	for i, key := range c.keys {
+		if key.PublicKey.VerifySignature(c.hashes[i], c.signature) == nil {
+			return nil
+		}
+	}
+
+	return errors.Wrapf(ErrSignature, "PGP signature of content of %s", expected)
+}
@@ -0,0 +1,54 @@
+package storclient
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func TestPGPVerifier(t *testing.T) {
+	entity, err := openpgp.NewEntity("stor", "", "stor@domain.tld", nil)
+	assert.NoError(t, err)
+	other, err := openpgp.NewEntity("other", "", "other@domain.tld", nil)
+	assert.NoError(t, err)
+
+	var keyRing bytes.Buffer
+	w, err := armor.Encode(&keyRing, openpgp.PublicKeyType, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, entity.Serialize(w))
+	assert.NoError(t, w.Close())
+
+	verifier, err := NewPGPVerifier(&keyRing)
+	assert.NoError(t, err)
+
+	sign := func(signer *openpgp.Entity, content string, armored bool) []byte {
+		var signature bytes.Buffer
+		if armored {
+			assert.NoError(t, openpgp.ArmoredDetachSign(&signature, signer, strings.NewReader(content), nil))
+		} else {
+			assert.NoError(t, openpgp.DetachSign(&signature, signer, strings.NewReader(content), nil))
+		}
+		return signature.Bytes()
+	}
+
+	errs := downloadSigned(t, verifier, map[string][]byte{
+		"binary":    sign(entity, "binary", false),
+		"armored":   sign(entity, "armored", true),
+		"forged":    sign(entity, "other content", false),
+		"other key": sign(other, "other key", false),
+	}, "binary", "armored", "forged", "other key", "unsigned")
+
+	assert.NoError(t, errs["binary"])
+	assert.NoError(t, errs["armored"])
+	for _, content := range []string{"forged", "other key", "unsigned"} {
+		assert.Error(t, errs[content], content)
+	}
+	assert.Contains(t, errs["other key"].Error(), "unknown key")
+
+	_, err = NewPGPVerifier(strings.NewReader(""))
+	assert.Error(t, err)
+}
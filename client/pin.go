@@ -0,0 +1,76 @@
+package storclient
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/avast/hashutil-go"
+)
+
+type pinError struct {
+	sha        hashutil.Hash
+	method     string
+	statusCode int
+	status     string
+}
+
+func (err pinError) Error() string {
+	return fmt.Sprintf("%s pin of %s fail %d (%s)", err.method, err.sha, err.statusCode, err.status)
+}
+
+// Pin mark object for retention on stor (PUT <storage>/pin/<sha>?ttl=<seconds>)
+//
+// ttl 0 means pin without expiration
+func (client *StorClient) Pin(sha hashutil.Hash, ttl time.Duration) error {
+	pinURL := client.createPinURL(sha)
+	if ttl > 0 {
+		pinURL += "?ttl=" + strconv.FormatInt(int64(ttl/time.Second), 10)
+	}
+
+	return client.pinRequest(http.MethodPut, pinURL, sha)
+}
+
+// Unpin remove retention mark of object on stor (DELETE <storage>/pin/<sha>)
+func (client *StorClient) Unpin(sha hashutil.Hash) error {
+	return client.pinRequest(http.MethodDelete, client.createPinURL(sha), sha)
+}
+
+func (client *StorClient) createPinURL(sha hashutil.Hash) string {
+	storage := strings.TrimRight(client.storageUrl.String(), "/")
+	return fmt.Sprintf("%s/pin/%s", storage, sha)
+}
+
+func (client *StorClient) pinRequest(method, url string, sha hashutil.Hash) error {
+	return client.RetryEngine.Do(
+		sha,
+		func() error {
+			req, err := http.NewRequest(method, url, nil)
+			if err != nil {
+				return err
+			}
+
+			resp, err := client.newStdHTTPClient().Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				return pinError{sha: sha, method: method, statusCode: resp.StatusCode, status: resp.Status}
+			}
+
+			return nil
+		},
+		func(err error) bool {
+			// client errors (404, 400...) are not retryable
+			if e, ok := err.(pinError); ok && e.statusCode < 500 {
+				return false
+			}
+
+			return true
+		},
+	)
+}
@@ -0,0 +1,40 @@
+package storclient_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPin(t *testing.T) {
+	requests := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.String())
+		if r.URL.Path == "/pin/"+sha256Of("missing").String() {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "", storclient.StorClientOpts{})
+	assert.NoError(t, err)
+
+	sha := sha256Of("sample")
+	assert.NoError(t, client.Pin(sha, time.Hour))
+	assert.NoError(t, client.Pin(sha, 0))
+	assert.NoError(t, client.Unpin(sha))
+	assert.Error(t, client.Pin(sha256Of("missing"), 0))
+
+	assert.Equal(t, []string{
+		"PUT /pin/" + sha.String() + "?ttl=3600",
+		"PUT /pin/" + sha.String(),
+		"DELETE /pin/" + sha.String(),
+		"PUT /pin/" + sha256Of("missing").String(),
+	}, requests, "404 isn't retried")
+}
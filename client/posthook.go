@@ -0,0 +1,108 @@
+package storclient
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// PostDownloadHook is called after each successful download with sha, path and size of downloaded file
+// (path is empty for Devnull and Archive) - see StorClientOpts.PostDownloadHook
+type PostDownloadHook func(sha hashutil.Hash, path string, size int64)
+
+// PostDownloadCommand is data of template of NewCommandHook
+type PostDownloadCommand struct {
+	Sha  string
+	Path string
+	Size int64
+}
+
+// NewCommandHook return PostDownloadHook which runs external command - each space separated
+// argument of commandTemplate is text/template of PostDownloadCommand,
+// e.g. `analyze --sha {{.Sha}} {{.Path}}`; failed command is logged
+func NewCommandHook(commandTemplate string) (PostDownloadHook, error) {
+	fields := strings.Fields(commandTemplate)
+	if len(fields) == 0 {
+		return nil, errors.New("empty command of post download hook")
+	}
+
+	args := make([]*template.Template, len(fields))
+	for i, field := range fields {
+		tmpl, err := template.New("arg").Parse(field)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Parse of post download command argument %q fail", field)
+		}
+		args[i] = tmpl
+	}
+
+	return func(sha hashutil.Hash, path string, size int64) {
+		data := PostDownloadCommand{Sha: sha.String(), Path: path, Size: size}
+
+		argv := make([]string, len(args))
+		for i, tmpl := range args {
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				log.WithField("sha256", sha.String()).Errorf("Post download command fail: %s", err)
+				return
+			}
+			argv[i] = buf.String()
+		}
+
+		output, err := exec.Command(argv[0], argv[1:]...).CombinedOutput()
+		if err != nil {
+			log.WithField("sha256", sha.String()).Errorf("Post download command %s fail: %s\n%s", argv, err, output)
+		}
+	}, nil
+}
+
+// postDownloadPool run PostDownloadHook on dedicated goroutines (see StorClientOpts.PostDownloadWorkers)
+type postDownloadPool struct {
+	hook PostDownloadHook
+	jobs chan postDownloadJob
+	wg   sync.WaitGroup
+}
+
+type postDownloadJob struct {
+	sha  hashutil.Hash
+	path string
+	size int64
+}
+
+func newPostDownloadPool(hook PostDownloadHook, workers int) *postDownloadPool {
+	pool := &postDownloadPool{hook: hook, jobs: make(chan postDownloadJob, workers)}
+
+	pool.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer pool.wg.Done()
+			for job := range pool.jobs {
+				pool.hook(job.sha, job.path, job.size)
+			}
+		}()
+	}
+
+	return pool
+}
+
+// close wait for all queued hooks
+func (pool *postDownloadPool) close() {
+	close(pool.jobs)
+	pool.wg.Wait()
+}
+
+// postDownload call PostDownloadHook on worker goroutine or queue it to hook pool
+func (client *StorClient) postDownload(sha hashutil.Hash, path string, size int64) {
+	switch {
+	case client.PostDownloadHook == nil || client.DryRun:
+	case client.postDownloadPool != nil:
+		client.postDownloadPool.jobs <- postDownloadJob{sha: sha, path: path, size: size}
+	default:
+		client.PostDownloadHook(sha, path, size)
+	}
+}
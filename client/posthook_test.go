@@ -0,0 +1,87 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostDownloadHook(t *testing.T) {
+	contents := map[string]string{contentHash("a").String(): "a", contentHash("b").String(): "b"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := contents[path.Base(r.URL.Path)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	shas := []hashutil.Hash{contentHash("a"), contentHash("b"), contentHash("missing")}
+
+	for _, workers := range []int{0, 2} {
+		tmpDir, err := ioutil.TempDir("", "posthook")
+		assert.NoError(t, err)
+		defer os.RemoveAll(tmpDir)
+
+		var lock sync.Mutex
+		hooked := make(map[string]int64)
+		hook := func(sha hashutil.Hash, file string, size int64) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			assert.FileExists(t, file)
+			hooked[file] = size
+		}
+
+		client, err := New(*serverURL, tmpDir, StorClientOpts{Max: 2, RetryAttempts: 1, PostDownloadHook: hook, PostDownloadWorkers: workers})
+		assert.NoError(t, err)
+
+		client.Start()
+		for _, sha := range shas {
+			assert.NoError(t, client.Download(sha))
+		}
+		total := client.Wait()
+
+		assert.Equal(t, 2, total.Count)
+		assert.Len(t, hooked, 2, "hook is called for each successful download before Wait returns")
+		for file, size := range hooked {
+			assert.Equal(t, tmpDir, filepath.Dir(file))
+			assert.Equal(t, int64(1), size)
+		}
+	}
+}
+
+func TestCommandHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("touch isn't on windows")
+	}
+
+	tmpDir, err := ioutil.TempDir("", "posthook")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	hook, err := NewCommandHook("touch {{.Path}}.{{.Size}}")
+	assert.NoError(t, err)
+
+	path := filepath.Join(tmpDir, emptyHash.String())
+	hook(emptyHash, path, 10)
+	assert.FileExists(t, path+".10")
+
+	_, err = NewCommandHook(" ")
+	assert.Error(t, err)
+	_, err = NewCommandHook("touch {{.Path")
+	assert.Error(t, err)
+}
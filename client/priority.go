@@ -0,0 +1,221 @@
+package storclient
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/avast/hashutil-go"
+)
+
+// DownloadPriority add sha to download queue with priority - jobs with higher priority are
+// scheduled before jobs with lower priority, jobs with same priority in FIFO order
+// (Download uses priority 0)
+//
+// ctx bounds waiting for free place in (full) queue and download of sha like DownloadCtx
+func (client *StorClient) DownloadPriority(ctx context.Context, sha hashutil.Hash, priority int) error {
+	return client.push(ctx, downloadJob{sha: sha, priority: priority})
+}
+
+// priorityQueue is bounded queue of jobs ordered by priority (FIFO within same priority,
+// or round-robin across producers within same priority if fair)
+//
+// slots limits count of queued jobs and each queued job has one token in ready,
+// so worker which receives token always pops a job; closed is closed after last push
+type priorityQueue struct {
+	lock    sync.Mutex
+	pending map[int]*priorityLevel
+	fair    bool
+	slots   chan struct{}
+	ready   chan struct{}
+	closed  chan struct{}
+}
+
+// priorityLevel is queued jobs of one priority - FIFO per producer,
+// producers with queued jobs take turns (nil producer is plain Download)
+type priorityLevel struct {
+	turns []*Producer
+	jobs  map[*Producer][]downloadJob
+}
+
+func (level *priorityLevel) push(producer *Producer, job downloadJob) {
+	if len(level.jobs[producer]) == 0 {
+		level.turns = append(level.turns, producer)
+	}
+	level.jobs[producer] = append(level.jobs[producer], job)
+}
+
+// pop return first job of producer on turn, producer with more jobs goes to end of turns
+func (level *priorityLevel) pop() downloadJob {
+	producer := level.turns[0]
+	level.turns = level.turns[1:]
+
+	jobs := level.jobs[producer]
+	if len(jobs) == 1 {
+		delete(level.jobs, producer)
+	} else {
+		level.jobs[producer] = jobs[1:]
+		level.turns = append(level.turns, producer)
+	}
+
+	return jobs[0]
+}
+
+func newPriorityQueue(capacity int) *priorityQueue {
+	return &priorityQueue{
+		pending: make(map[int]*priorityLevel),
+		slots:   make(chan struct{}, capacity),
+		ready:   make(chan struct{}, capacity),
+		closed:  make(chan struct{}),
+	}
+}
+
+// push add job, it blocks while queue is full
+func (q *priorityQueue) push(ctx context.Context, job downloadJob) error {
+	if err := q.acquire(ctx, nil); err != nil {
+		return err
+	}
+
+	q.add(job)
+
+	return nil
+}
+
+// tryPush add job like push, but returns errQueueFull instead of waiting for free slot
+func (q *priorityQueue) tryPush(job downloadJob) error {
+	if err := q.tryAcquire(); err != nil {
+		return err
+	}
+
+	q.add(job)
+
+	return nil
+}
+
+// acquire wait for free slot, it fails with ctx.Err() if ctx is done
+// or with ErrQueueClosed if queue is closed before
+func (q *priorityQueue) acquire(ctx context.Context, closed <-chan struct{}) error {
+	select {
+	case q.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-closed:
+		return ErrQueueClosed
+	}
+}
+
+// tryAcquire take free slot or return errQueueFull
+func (q *priorityQueue) tryAcquire() error {
+	select {
+	case q.slots <- struct{}{}:
+		return nil
+	default:
+		return errQueueFull
+	}
+}
+
+// release return slot which wasn't used by add
+func (q *priorityQueue) release() {
+	<-q.slots
+}
+
+// add job to pending jobs, caller must hold slot
+func (q *priorityQueue) add(job downloadJob) {
+	var producer *Producer
+	if q.fair {
+		producer = job.producer
+	}
+
+	q.lock.Lock()
+	level, ok := q.pending[job.priority]
+	if !ok {
+		level = &priorityLevel{jobs: make(map[*Producer][]downloadJob)}
+		q.pending[job.priority] = level
+	}
+	level.push(producer, job)
+	q.lock.Unlock()
+
+	q.ready <- struct{}{}
+}
+
+// pop return job with highest priority, caller must receive token from ready before
+func (q *priorityQueue) pop() downloadJob {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	first := true
+	var priority int
+	for p := range q.pending {
+		if first || p > priority {
+			priority = p
+			first = false
+		}
+	}
+
+	level := q.pending[priority]
+	job := level.pop()
+	if len(level.turns) == 0 {
+		delete(q.pending, priority)
+	}
+
+	<-q.slots
+
+	return job
+}
+
+// tryPop return job with highest priority if any is queued
+func (q *priorityQueue) tryPop() (downloadJob, bool) {
+	select {
+	case <-q.ready:
+		return q.pop(), true
+	default:
+		return downloadJob{}, false
+	}
+}
+
+// peek return up to n jobs in order in which they will be popped, jobs stay queued
+func (q *priorityQueue) peek(n int) []downloadJob {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	priorities := make([]int, 0, len(q.pending))
+	for p := range q.pending {
+		priorities = append(priorities, p)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+
+	var jobs []downloadJob
+	for _, p := range priorities {
+		level := q.pending[p]
+		turns := append([]*Producer(nil), level.turns...)
+		popped := make(map[*Producer]int)
+		for len(turns) > 0 && len(jobs) < n {
+			producer := turns[0]
+			turns = turns[1:]
+
+			jobs = append(jobs, level.jobs[producer][popped[producer]])
+			popped[producer]++
+			if popped[producer] < len(level.jobs[producer]) {
+				turns = append(turns, producer)
+			}
+		}
+	}
+
+	return jobs
+}
+
+// len return count of queued jobs
+func (q *priorityQueue) len() int {
+	return len(q.ready)
+}
+
+// capacity return max count of queued jobs
+func (q *priorityQueue) capacity() int {
+	return cap(q.slots)
+}
+
+// close signal that no job is pushed anymore, must be called once
+func (q *priorityQueue) close() {
+	close(q.closed)
+}
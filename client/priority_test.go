@@ -0,0 +1,102 @@
+package storclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadPriority(t *testing.T) {
+	contents := map[string]string{}
+	shaOf := func(content string) hashutil.Hash {
+		sha := contentHash(content)
+		contents[sha.String()] = content
+		return sha
+	}
+	first, low, normal, high := shaOf("first"), shaOf("low"), shaOf("normal"), shaOf("high")
+
+	var lock sync.Mutex
+	var order []string
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content := contents[path.Base(r.URL.Path)]
+		lock.Lock()
+		order = append(order, content)
+		lock.Unlock()
+
+		if content == "first" {
+			close(started)
+			<-release
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{Max: 1, Devnull: true})
+	assert.NoError(t, err)
+	client.Start()
+
+	assert.NoError(t, client.Download(first))
+	<-started
+
+	assert.NoError(t, client.DownloadPriority(context.Background(), low, -1))
+	assert.NoError(t, client.Download(normal))
+	assert.NoError(t, client.DownloadPriority(context.Background(), high, 10))
+	close(release)
+
+	total := client.Wait()
+	assert.Equal(t, 4, total.Count)
+	assert.Equal(t, []string{"first", "high", "normal", "low"}, order)
+}
+
+func TestPriorityQueue(t *testing.T) {
+	queue := newPriorityQueue(10)
+
+	for _, job := range []downloadJob{
+		{sha: contentHash("a"), priority: -1},
+		{sha: contentHash("b"), priority: 5},
+		{sha: contentHash("c"), priority: -1},
+		{sha: contentHash("d")},
+	} {
+		assert.NoError(t, queue.push(context.Background(), job))
+	}
+	assert.Equal(t, 4, queue.len())
+
+	var peeked []hashutil.Hash
+	for _, job := range queue.peek(3) {
+		peeked = append(peeked, job.sha)
+	}
+	assert.Equal(t, []hashutil.Hash{contentHash("b"), contentHash("d"), contentHash("a")}, peeked)
+	assert.Equal(t, 4, queue.len(), "peek doesn't pop")
+
+	var popped []hashutil.Hash
+	for {
+		job, ok := queue.tryPop()
+		if !ok {
+			break
+		}
+		popped = append(popped, job.sha)
+	}
+
+	assert.Equal(t, []hashutil.Hash{contentHash("b"), contentHash("d"), contentHash("a"), contentHash("c")}, popped)
+	assert.Equal(t, 0, queue.len())
+}
+
+func TestPriorityQueueFull(t *testing.T) {
+	queue := newPriorityQueue(1)
+	assert.NoError(t, queue.push(context.Background(), downloadJob{sha: contentHash("a")}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, queue.push(ctx, downloadJob{sha: contentHash("b")}))
+}
@@ -0,0 +1,115 @@
+package storclient
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MirrorProbeOpts is configuration of probing of storage url and mirrors (see StorClientOpts.MirrorProbe)
+//
+// each mirror is probed by HEAD request, mirrors are ordered by success and latency
+// (the fastest healthy mirror first) - with MirrorFailover downloads start on the fastest healthy mirror
+type MirrorProbeOpts struct {
+	// path of probe request
+	// default ("") means root of mirror
+	Path string
+	// timeout of probe request, mirror without response in timeout is unhealthy
+	// default (0) is client Timeout
+	Timeout time.Duration
+	// period of re-probing while downloading
+	// default (0) means probe only on Start
+	Interval time.Duration
+}
+
+// mirrorProbe is result of probe of one mirror
+type mirrorProbe struct {
+	mirror  url.URL
+	healthy bool
+	latency time.Duration
+}
+
+// probeMirrors probe all mirrors of client and reorder them
+func (client *StorClient) probeMirrors() {
+	mirrors := client.root.mirrors.list()
+
+	probes := make([]mirrorProbe, len(mirrors))
+	done := make(chan struct{}, len(mirrors))
+	for i, mirror := range mirrors {
+		go func(i int, mirror url.URL) {
+			probes[i] = client.probeMirror(mirror)
+			done <- struct{}{}
+		}(i, mirror)
+	}
+	for range mirrors {
+		<-done
+	}
+
+	client.root.mirrors.reorder(orderMirrors(probes))
+}
+
+// probeMirror send HEAD request to mirror, any response except 5xx means healthy mirror
+func (client *StorClient) probeMirror(mirror url.URL) mirrorProbe {
+	probe := mirrorProbe{mirror: mirror}
+
+	u := mirror
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + strings.TrimLeft(client.MirrorProbe.Path, "/")
+
+	httpClient := &contextClient{Client: client.newStdHTTPClient(), ctx: client.ctx}
+	httpClient.Timeout = client.MirrorProbe.Timeout
+	if httpClient.Timeout == 0 {
+		httpClient.Timeout = client.Timeout
+	}
+
+	startTime := client.Clock.Now()
+	resp, err := httpClient.Head(u.String())
+	probe.latency = since(client.Clock, startTime)
+	if err != nil {
+		log.WithField("mirror", mirror.Host).Debugf("Probe of mirror fail: %s", err)
+		return probe
+	}
+	resp.Body.Close()
+
+	probe.healthy = resp.StatusCode < http.StatusInternalServerError
+	log.WithFields(log.Fields{
+		"mirror":  mirror.Host,
+		"status":  resp.StatusCode,
+		"latency": probe.latency,
+	}).Debug("Probe of mirror")
+
+	return probe
+}
+
+// orderMirrors return healthy mirrors by latency followed by unhealthy mirrors in original order
+func orderMirrors(probes []mirrorProbe) []url.URL {
+	sort.SliceStable(probes, func(i, j int) bool {
+		if probes[i].healthy != probes[j].healthy {
+			return probes[i].healthy
+		}
+		return probes[i].healthy && probes[i].latency < probes[j].latency
+	})
+
+	urls := make([]url.URL, len(probes))
+	for i, probe := range probes {
+		urls[i] = probe.mirror
+	}
+
+	return urls
+}
+
+// reprobeMirrors probe mirrors every interval until queue is closed
+func (client *StorClient) reprobeMirrors() {
+	for {
+		select {
+		case <-client.queue.jobs.closed:
+			return
+		case <-client.Clock.After(client.MirrorProbe.Interval):
+		}
+
+		client.probeMirrors()
+	}
+}
@@ -0,0 +1,76 @@
+package storclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirrorProbe(t *testing.T) {
+	var lock sync.Mutex
+	downloads := map[string]int{}
+	newServer := func(name string, status int, delay time.Duration) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				time.Sleep(delay)
+				w.WriteHeader(status)
+				return
+			}
+
+			lock.Lock()
+			downloads[name]++
+			lock.Unlock()
+			_, _ = w.Write([]byte("probe"))
+		}))
+	}
+
+	slow := newServer("slow", http.StatusOK, 100*time.Millisecond)
+	defer slow.Close()
+	broken := newServer("broken", http.StatusServiceUnavailable, 0)
+	defer broken.Close()
+	fast := newServer("fast", http.StatusNotFound, 0)
+	defer fast.Close()
+
+	slowURL, _ := url.Parse(slow.URL)
+	brokenURL, _ := url.Parse(broken.URL)
+	fastURL, _ := url.Parse(fast.URL)
+
+	client, err := New(*slowURL, os.TempDir(), StorClientOpts{
+		Max:         1,
+		Devnull:     true,
+		Mirrors:     []url.URL{*brokenURL, *fastURL},
+		MirrorProbe: &MirrorProbeOpts{Path: "/health"},
+	})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.Equal(t, []url.URL{*fastURL, *slowURL, *brokenURL}, client.root.mirrors.list(), "healthy by latency, unhealthy last")
+
+	assert.NoError(t, client.Download(contentHash("probe")))
+	total := client.Wait()
+
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, map[string]int{"fast": 1}, downloads, "download starts on the fastest mirror")
+}
+
+func TestOrderMirrors(t *testing.T) {
+	a, _ := url.Parse("http://a")
+	b, _ := url.Parse("http://b")
+	c, _ := url.Parse("http://c")
+	d, _ := url.Parse("http://d")
+
+	ordered := orderMirrors([]mirrorProbe{
+		{mirror: *a, healthy: false, latency: time.Millisecond},
+		{mirror: *b, healthy: true, latency: 30 * time.Millisecond},
+		{mirror: *c, healthy: false},
+		{mirror: *d, healthy: true, latency: 10 * time.Millisecond},
+	})
+
+	assert.Equal(t, []url.URL{*d, *b, *a, *c}, ordered)
+}
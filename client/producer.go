@@ -0,0 +1,37 @@
+package storclient
+
+import (
+	"context"
+
+	"github.com/avast/hashutil-go"
+)
+
+// Producer is token of one caller sharing client (see StorClient.NewProducer)
+//
+// with StorClientOpts.FairQueuing producers of same priority take turns - next job is taken
+// from next producer with queued jobs (plain Download jobs are one more producer),
+// so bulk producer doesn't starve other callers; without FairQueuing token has no effect
+type Producer struct {
+	client *StorClient
+	name   string
+}
+
+// NewProducer create producer token, each token has own turn
+func (client *StorClient) NewProducer(name string) *Producer {
+	return &Producer{client: client, name: name}
+}
+
+// Name return name of producer
+func (producer *Producer) Name() string {
+	return producer.name
+}
+
+// Download add sha to download queue on behalf of producer
+func (producer *Producer) Download(sha hashutil.Hash) error {
+	return producer.DownloadCtx(context.Background(), sha)
+}
+
+// DownloadCtx add sha to download queue on behalf of producer (see StorClient.DownloadCtx)
+func (producer *Producer) DownloadCtx(ctx context.Context, sha hashutil.Hash) error {
+	return producer.client.push(ctx, downloadJob{sha: sha, producer: producer})
+}
@@ -0,0 +1,93 @@
+package storclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFairQueuing(t *testing.T) {
+	contents := map[string]string{}
+	for _, content := range []string{"first", "bulk1", "bulk2", "bulk3", "other"} {
+		contents[contentHash(content).String()] = content
+	}
+
+	var lock sync.Mutex
+	var order []string
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content := contents[path.Base(r.URL.Path)]
+		lock.Lock()
+		order = append(order, content)
+		lock.Unlock()
+
+		if content == "first" {
+			close(started)
+			<-release
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{Max: 1, Devnull: true, FairQueuing: true})
+	assert.NoError(t, err)
+	client.Start()
+
+	bulk := client.NewProducer("bulk")
+	other := client.NewProducer("other")
+	assert.Equal(t, "bulk", bulk.Name())
+
+	assert.NoError(t, bulk.Download(contentHash("first")))
+	<-started
+
+	for _, content := range []string{"bulk1", "bulk2", "bulk3"} {
+		assert.NoError(t, bulk.Download(contentHash(content)))
+	}
+	assert.NoError(t, other.Download(contentHash("other")))
+	close(release)
+
+	total := client.Wait()
+	assert.Equal(t, 5, total.Count)
+	assert.Equal(t, []string{"first", "bulk1", "other", "bulk2", "bulk3"}, order, "producers take turns")
+}
+
+func TestPriorityQueueFairness(t *testing.T) {
+	a, b := &Producer{name: "a"}, &Producer{name: "b"}
+	jobs := []downloadJob{
+		{sha: contentHash("a1"), producer: a},
+		{sha: contentHash("a2"), producer: a},
+		{sha: contentHash("a3"), producer: a},
+		{sha: contentHash("b1"), producer: b},
+		{sha: contentHash("plain")},
+	}
+
+	pop := func(fair bool) []hashutil.Hash {
+		queue := newPriorityQueue(10)
+		queue.fair = fair
+		for _, job := range jobs {
+			assert.NoError(t, queue.push(context.Background(), job))
+		}
+
+		var popped []hashutil.Hash
+		for {
+			job, ok := queue.tryPop()
+			if !ok {
+				return popped
+			}
+			popped = append(popped, job.sha)
+		}
+	}
+
+	assert.Equal(t, []hashutil.Hash{contentHash("a1"), contentHash("b1"), contentHash("plain"), contentHash("a2"), contentHash("a3")}, pop(true))
+	assert.Equal(t, []hashutil.Hash{contentHash("a1"), contentHash("a2"), contentHash("a3"), contentHash("b1"), contentHash("plain")}, pop(false), "FIFO without fair queuing")
+}
@@ -0,0 +1,29 @@
+package storclient
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// proxyFunc return proxy of transport by Proxy and ProxyFromEnvironment options
+//
+// supported proxy schemes are http, https and socks5
+func proxyFunc(proxy *url.URL, fromEnvironment bool) (func(*http.Request) (*url.URL, error), error) {
+	if proxy != nil {
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, errors.Errorf("unsupported proxy scheme %q (supported are http, https and socks5)", proxy.Scheme)
+		}
+
+		return http.ProxyURL(proxy), nil
+	}
+
+	if fromEnvironment {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	return nil, nil
+}
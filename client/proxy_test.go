@@ -0,0 +1,56 @@
+package storclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyFunc(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "http://stor.domain.tld/abc", nil)
+
+	proxy, err := proxyFunc(nil, false)
+	assert.NoError(t, err)
+	assert.Nil(t, proxy, "without proxy")
+
+	for _, scheme := range []string{"http", "https", "socks5"} {
+		proxyURL := &url.URL{Scheme: scheme, Host: "proxy:1080"}
+		proxy, err := proxyFunc(proxyURL, true)
+		if assert.NoError(t, err, scheme) {
+			u, err := proxy(req)
+			assert.NoError(t, err)
+			assert.Equal(t, proxyURL, u, "explicit proxy has priority over environment")
+		}
+	}
+
+	_, err = proxyFunc(&url.URL{Scheme: "ftp", Host: "proxy"}, false)
+	assert.Error(t, err)
+
+	_, err = New(url.URL{}, "", StorClientOpts{Proxy: &url.URL{Scheme: "ftp", Host: "proxy"}})
+	assert.Error(t, err)
+}
+
+func TestProxy(t *testing.T) {
+	proxied := make([]string, 0)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	tmpDir := os.TempDir()
+
+	client, err := New(url.URL{Scheme: "http", Host: "stor.invalid"}, tmpDir, StorClientOpts{Devnull: true, RetryAttempts: 1, Proxy: proxyURL})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(emptyHash))
+	total := client.Wait()
+
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, []string{"http://stor.invalid/" + emptyHash.String()}, proxied)
+}
@@ -0,0 +1,115 @@
+package storclient
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// publisher records files reported as DOWN_OK which aren't durable yet (see StorClient.Publish)
+type publisher struct {
+	lock    sync.Mutex
+	pending []string
+	// serializes Publish calls
+	publishLock sync.Mutex
+}
+
+func (p *publisher) add(path string) {
+	if p == nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.pending = append(p.pending, path)
+}
+
+// Publish is barrier which makes all files reported as DOWN_OK before the call durable - content of each file
+// and its directory entry (rename) are fsynced, so after crash no file is missing or zero-length
+//
+// it requires StorClientOpts.PublishBarrier, it's safe to call it concurrently with downloads and after Wait;
+// files which fail to sync are kept for next Publish
+func (client *StorClient) Publish() error {
+	p := client.publisher
+	if p == nil {
+		return errors.New("publish barrier isn't enabled (see StorClientOpts.PublishBarrier)")
+	}
+
+	p.publishLock.Lock()
+	defer p.publishLock.Unlock()
+
+	p.lock.Lock()
+	paths := p.pending
+	p.pending = nil
+	p.lock.Unlock()
+
+	var failed []string
+	var firstErr error
+	dirs := make(map[string]struct{})
+	for _, path := range paths {
+		if err := syncPath(path); err != nil {
+			failed = append(failed, path)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		dirs[filepath.Dir(path)] = struct{}{}
+	}
+
+	for dir := range dirs {
+		if err := syncDir(dir); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if len(failed) > 0 {
+		p.lock.Lock()
+		p.pending = append(failed, p.pending...)
+		p.lock.Unlock()
+	}
+
+	if firstErr != nil {
+		return errors.Wrapf(firstErr, "Publish of %d of %d files fail", len(failed), len(paths))
+	}
+
+	return nil
+}
+
+// syncPath fsync content of file
+func syncPath(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+
+	err = file.Sync()
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+
+	return errors.Wrapf(err, "Sync of %s fail", path)
+}
+
+// syncDir fsync directory, so renames in it are durable (directories can't be synced on windows)
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	err = file.Sync()
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+
+	return errors.Wrapf(err, "Sync of directory %s fail", dir)
+}
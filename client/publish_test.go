@@ -0,0 +1,49 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublish(t *testing.T) {
+	// content of emptyHash
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	tmpDir, err := ioutil.TempDir("", "publish")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	client, err := New(*serverURL, tmpDir, StorClientOpts{PublishBarrier: true})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(emptyHash))
+	total := client.Wait()
+	assert.Equal(t, 1, total.Count)
+
+	assert.Equal(t, []string{filepath.Join(tmpDir, emptyHash.String())}, client.publisher.pending)
+	assert.NoError(t, client.Publish())
+	assert.Empty(t, client.publisher.pending)
+
+	// file which can't be synced is kept for next publish
+	missing := filepath.Join(tmpDir, "missing")
+	client.publisher.add(missing)
+	assert.Error(t, client.Publish())
+	assert.Equal(t, []string{missing}, client.publisher.pending)
+
+	withoutBarrier, err := New(*serverURL, tmpDir, StorClientOpts{})
+	assert.NoError(t, err)
+	assert.Error(t, withoutBarrier.Publish())
+
+	_, err = New(*serverURL, tmpDir, StorClientOpts{PublishBarrier: true, Devnull: true})
+	assert.Error(t, err)
+}
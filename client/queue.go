@@ -0,0 +1,134 @@
+package storclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrQueueClosed is returned by Download called after (or concurrently with) Wait
+var ErrQueueClosed = errors.New("download queue is closed")
+
+// capacity of download queue
+const downloadQueueCapacity = 1024
+
+// downloadQueue is input of download pool which can be safely closed
+// concurrently with pushing - sha is either pushed before close or refused
+type downloadQueue struct {
+	lock   sync.RWMutex
+	closed bool
+	count  int64
+	seqs   int64
+	// count of jobs refused by full queue (see tryPush)
+	rejected int64
+	// queued jobs ordered by priority
+	jobs *priorityQueue
+	// called under lock after each push
+	onPush func()
+	// called with submission order of job which wasn't queued (ctx is done or queue is full)
+	onDrop func(seq int64)
+}
+
+func newDownloadQueue(capacity int) *downloadQueue {
+	return &downloadQueue{jobs: newPriorityQueue(capacity)}
+}
+
+// push add job to queue, returns ErrQueueClosed if queue is closed (also while waiting
+// for free slot) or ctx.Err() if ctx is done before job is queued
+func (q *downloadQueue) push(ctx context.Context, job downloadJob) error {
+	return q.pushWith(job, func() error {
+		return q.jobs.acquire(ctx, q.jobs.closed)
+	})
+}
+
+// tryPush add job to queue like push, but returns errQueueFull instead of waiting for free slot
+func (q *downloadQueue) tryPush(job downloadJob) error {
+	err := q.pushWith(job, q.jobs.tryAcquire)
+	if err == errQueueFull {
+		atomic.AddInt64(&q.rejected, 1)
+	}
+
+	return err
+}
+
+// pushWith add job to slot taken by acquire - lock isn't held while acquire waits,
+// so close isn't blocked by full queue
+func (q *downloadQueue) pushWith(job downloadJob, acquire func() error) error {
+	var ok bool
+	q.whileOpen(func() {
+		ok = true
+		job.seq = atomic.AddInt64(&q.seqs, 1) - 1
+	})
+	if !ok {
+		return ErrQueueClosed
+	}
+
+	err := acquire()
+	if err == nil {
+		err = q.add(job)
+	}
+	if err != nil {
+		if q.onDrop != nil {
+			q.onDrop(job.seq)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// add job to acquired slot, slot is released if queue was closed meanwhile
+func (q *downloadQueue) add(job downloadJob) error {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	if q.closed {
+		q.jobs.release()
+		return ErrQueueClosed
+	}
+
+	q.jobs.add(job)
+
+	atomic.AddInt64(&q.count, 1)
+	if q.onPush != nil {
+		q.onPush()
+	}
+
+	return nil
+}
+
+// close refuse next pushes and signal workers,
+// so workers end after processing of all pushed shas
+func (q *downloadQueue) close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+
+	q.jobs.close()
+}
+
+// whileOpen call fn under lock if queue isn't closed yet,
+// so fn can start workers without race with close
+func (q *downloadQueue) whileOpen(fn func()) {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	if !q.closed {
+		fn()
+	}
+}
+
+// pushed return count of all pushed shas
+func (q *downloadQueue) pushed() int {
+	return int(atomic.LoadInt64(&q.count))
+}
+
+// rejections return count of jobs refused by full queue
+func (q *downloadQueue) rejections() int {
+	return int(atomic.LoadInt64(&q.rejected))
+}
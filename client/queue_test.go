@@ -0,0 +1,60 @@
+package storclient
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadQueue(t *testing.T) {
+	queue := newDownloadQueue(4)
+
+	assert.NoError(t, queue.push(context.Background(), downloadJob{sha: emptyHash}))
+	queue.close()
+	assert.Equal(t, ErrQueueClosed, queue.push(context.Background(), downloadJob{sha: emptyHash}))
+	queue.close()
+
+	assert.Equal(t, 1, queue.pushed())
+	job, ok := queue.jobs.tryPop()
+	assert.True(t, ok)
+	assert.True(t, job.sha.Equal(emptyHash))
+	_, ok = queue.jobs.tryPop()
+	assert.False(t, ok, "queue is empty")
+	_, ok = <-queue.jobs.closed
+	assert.False(t, ok, "queue is closed")
+}
+
+func TestDownloadConcurrentWithWait(t *testing.T) {
+	// downloads from empty url fail immediately
+	client, err := New(url.URL{}, "", StorClientOpts{Devnull: true, Max: 2, RetryAttempts: 1})
+	assert.NoError(t, err)
+
+	client.Start()
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	accepted := 0
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if client.Download(emptyHash) == nil {
+					lock.Lock()
+					accepted++
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+
+	total := client.Wait()
+	wg.Wait()
+
+	assert.Equal(t, accepted, total.expectedDownloadCount)
+	assert.Equal(t, accepted, total.Count+total.Skip+total.Failed())
+	assert.Equal(t, ErrQueueClosed, client.Download(emptyHash))
+}
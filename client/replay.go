@@ -0,0 +1,261 @@
+package storclient
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// exchange is one recorded request and response
+type exchange struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	// sha256 of response body (body is stored in <recording>.bodies directory)
+	BodySha256 string `json:"body_sha256,omitempty"`
+	Size       int64  `json:"size"`
+	// transport error (without response)
+	Error string `json:"error,omitempty"`
+	// Range header of request (resumed download)
+	Range string `json:"range,omitempty"`
+}
+
+// key of exchange - host isn't part of key, so recording can be replayed with other storage url
+func (e exchange) key() string {
+	if e.Range != "" {
+		return e.Method + " " + e.URL + " " + e.Range
+	}
+
+	return e.Method + " " + e.URL
+}
+
+func requestKey(req *http.Request) string {
+	return exchange{Method: req.Method, URL: req.URL.RequestURI(), Range: req.Header.Get("Range")}.key()
+}
+
+func bodiesDir(path string) string {
+	return path + ".bodies"
+}
+
+// Recorder record all requests and responses of client (see StorClientOpts.Recorder)
+//
+// exchanges (method, url, status, headers, sha256 and size of body) are written as JSON lines to file,
+// bodies are stored in <file>.bodies directory by their sha256, so recording can be replayed (see Replay)
+type Recorder struct {
+	lock sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+	dir  string
+}
+
+// NewRecorder create recording file (and directory of bodies)
+func NewRecorder(path string) (*Recorder, error) {
+	dir := bodiesDir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "Create of bodies directory %s fail", dir)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Create of recording %s fail", path)
+	}
+
+	return &Recorder{file: file, enc: json.NewEncoder(file), dir: dir}, nil
+}
+
+// Close recording file
+func (r *Recorder) Close() error {
+	return r.file.Close()
+}
+
+func (r *Recorder) write(e exchange) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.enc.Encode(e)
+}
+
+// recordingTransport record exchanges of next transport
+type recordingTransport struct {
+	next     http.RoundTripper
+	recorder *Recorder
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	e := exchange{Method: req.Method, URL: req.URL.RequestURI(), Range: req.Header.Get("Range")}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		e.Error = err.Error()
+		if recErr := t.recorder.write(e); recErr != nil {
+			return nil, recErr
+		}
+
+		return nil, err
+	}
+
+	e.Status = resp.StatusCode
+	e.Header = resp.Header.Clone()
+
+	temp, err := ioutil.TempFile(t.recorder.dir, "body_*.temp")
+	if err != nil {
+		resp.Body.Close()
+		return nil, errors.Wrap(err, "Create of recorded body fail")
+	}
+
+	hasher := sha256.New()
+	resp.Body = &recordingBody{ReadCloser: resp.Body, out: temp, hasher: hasher, exchange: e, recorder: t.recorder}
+
+	return resp, nil
+}
+
+// recordingBody store body and write exchange on close
+type recordingBody struct {
+	io.ReadCloser
+	out      *os.File
+	hasher   hash.Hash
+	exchange exchange
+	recorder *Recorder
+	once     sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if _, errWrite := b.out.Write(p[:n]); errWrite != nil {
+			return n, errors.Wrapf(errWrite, "Write of recorded body %s fail", b.out.Name())
+		}
+		b.hasher.Write(p[:n])
+		b.exchange.Size += int64(n)
+	}
+
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+
+	b.once.Do(func() {
+		b.exchange.BodySha256 = hex.EncodeToString(b.hasher.Sum(nil))
+
+		if closeErr := b.out.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+
+		if renameErr := os.Rename(b.out.Name(), filepath.Join(b.recorder.dir, b.exchange.BodySha256)); renameErr != nil && err == nil {
+			err = renameErr
+		}
+
+		if recErr := b.recorder.write(b.exchange); recErr != nil && err == nil {
+			err = recErr
+		}
+	})
+
+	return err
+}
+
+// Replay replay recorded exchanges instead of requests to stor (see StorClientOpts.Replay)
+//
+// responses are matched by method, url (without host) and Range header in recorded order,
+// so retries get same responses as in recording; request without recorded response fails
+type Replay struct {
+	lock      sync.Mutex
+	dir       string
+	exchanges map[string][]exchange
+}
+
+// LoadReplay load recording (see Recorder)
+func LoadReplay(path string) (*Replay, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Open of recording %s fail", path)
+	}
+	defer file.Close()
+
+	replay := &Replay{dir: bodiesDir(path), exchanges: make(map[string][]exchange)}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e exchange
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, errors.Wrapf(err, "Parse of recording %s fail", path)
+		}
+
+		replay.exchanges[e.key()] = append(replay.exchanges[e.key()], e)
+	}
+
+	return replay, scanner.Err()
+}
+
+// next return next recorded exchange of request (last one is repeated)
+func (r *Replay) next(key string) (exchange, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	exchanges := r.exchanges[key]
+	if len(exchanges) == 0 {
+		return exchange{}, false
+	}
+
+	if len(exchanges) > 1 {
+		r.exchanges[key] = exchanges[1:]
+	}
+
+	return exchanges[0], true
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replay) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(ioutil.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	e, ok := r.next(requestKey(req))
+	if !ok {
+		return nil, fmt.Errorf("replay: no recorded response of %s", requestKey(req))
+	}
+
+	if e.Error != "" {
+		return nil, errors.New(e.Error)
+	}
+
+	var body io.ReadCloser = http.NoBody
+	if e.Size > 0 {
+		file, err := os.Open(filepath.Join(r.dir, e.BodySha256))
+		if err != nil {
+			return nil, errors.Wrap(err, "replay: open of recorded body fail")
+		}
+		body = file
+	}
+
+	// each response has own header (callers may modify it)
+	header := e.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          body,
+		ContentLength: e.Size,
+		Request:       req,
+	}, nil
+}
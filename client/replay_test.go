@@ -0,0 +1,147 @@
+package storclient_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordReplay(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/"+sha256Of("first").String() {
+			w.Header().Set("X-Served-By", "node-1")
+			w.Write([]byte("first"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "replay")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	recording := filepath.Join(tmpDir, "batch.jsonl")
+
+	run := func(storageURL url.URL, opts storclient.StorClientOpts) storclient.TotalStat {
+		downloadDir, err := ioutil.TempDir(tmpDir, "download")
+		assert.NoError(t, err)
+
+		opts.RetryAttempts = 1
+		client, err := storclient.New(storageURL, downloadDir, opts)
+		assert.NoError(t, err)
+
+		client.Start()
+		assert.NoError(t, client.Download(sha256Of("first")))
+		assert.NoError(t, client.Download(sha256Of("missing")))
+		total := client.Wait()
+
+		if total.Count == 1 {
+			content, err := ioutil.ReadFile(filepath.Join(downloadDir, sha256Of("first").String()))
+			assert.NoError(t, err)
+			assert.Equal(t, "first", string(content))
+		}
+
+		return total
+	}
+
+	recorder, err := storclient.NewRecorder(recording)
+	assert.NoError(t, err)
+
+	serverURL, _ := url.Parse(server.URL)
+	recorded := run(*serverURL, storclient.StorClientOpts{Recorder: recorder})
+	assert.NoError(t, recorder.Close())
+	assert.Equal(t, 2, requests)
+
+	replay, err := storclient.LoadReplay(recording)
+	assert.NoError(t, err)
+
+	server.Close()
+	replayed := run(url.URL{Scheme: "http", Host: "stor.invalid"}, storclient.StorClientOpts{Replay: replay})
+
+	assert.Equal(t, 2, requests, "replay doesn't touch stor")
+	assert.Equal(t, 1, replayed.Count)
+	assert.Equal(t, recorded.Count, replayed.Count)
+	assert.Equal(t, recorded.Failed(), replayed.Failed())
+	assert.Equal(t, recorded.Size, replayed.Size)
+}
+
+func TestReplayUnknownRequest(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "replay")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	recording := filepath.Join(tmpDir, "empty.jsonl")
+	recorder, err := storclient.NewRecorder(recording)
+	assert.NoError(t, err)
+	assert.NoError(t, recorder.Close())
+
+	replay, err := storclient.LoadReplay(recording)
+	assert.NoError(t, err)
+
+	_, err = (&http.Client{Transport: replay}).Get("http://stor.invalid/abc")
+	assert.Error(t, err)
+}
+
+func TestReplayRange(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "replay")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	recording := filepath.Join(tmpDir, "ranged.jsonl")
+	assert.NoError(t, os.MkdirAll(recording+".bodies", 0755))
+	var lines []byte
+	for _, exchange := range []struct{ rng, status, body string }{
+		{status: "200", body: "0123456789"},
+		{rng: "bytes=4-", status: "206", body: "456789"},
+	} {
+		sum := sha256.Sum256([]byte(exchange.body))
+		bodySha := hex.EncodeToString(sum[:])
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(recording+".bodies", bodySha), []byte(exchange.body), 0644))
+		lines = append(lines, `{"method":"GET","url":"/abc","range":"`+exchange.rng+`","status":`+exchange.status+`,"header":{"X-Served-By":["node-1"]},"body_sha256":"`+bodySha+`","size":`+strconv.Itoa(len(exchange.body))+"}\n"...)
+	}
+	assert.NoError(t, ioutil.WriteFile(recording, lines, 0644))
+
+	replay, err := storclient.LoadReplay(recording)
+	assert.NoError(t, err)
+	client := &http.Client{Transport: replay}
+
+	get := func(rng string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, "http://stor.invalid/abc", nil)
+		assert.NoError(t, err)
+		if rng != "" {
+			req.Header.Set("Range", rng)
+		}
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, resp.Body.Close())
+		return resp, string(body)
+	}
+
+	resp, body := get("bytes=4-")
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "456789", body, "resumed attempt gets recorded range")
+	resp.Header.Set("X-Served-By", "changed")
+
+	// last recorded exchange is repeated
+	resp, _ = get("bytes=4-")
+	assert.Equal(t, "node-1", resp.Header.Get("X-Served-By"), "each response has own header")
+
+	resp, body = get("")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "0123456789", body)
+}
@@ -0,0 +1,174 @@
+package storclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type uploadError struct {
+	sha        hashutil.Hash
+	statusCode int
+	status     string
+}
+
+func (err uploadError) Error() string {
+	return fmt.Sprintf("Upload of %s fail %d (%s)", err.sha, err.statusCode, err.status)
+}
+
+// Replicate stream objects from source stor to dest stor
+//
+// objects are verified during streaming (once) and never touch local disk,
+// objects which already exist on dest are skipped.
+// Replication runs in source.Max goroutines with retry policy of source.
+func Replicate(source, dest *StorClient, shas []hashutil.Hash) TotalStat {
+	input := make(chan hashutil.Hash)
+	output := make(chan DownStat, len(shas))
+
+	var wg sync.WaitGroup
+	for id := 0; id < source.Max; id++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for sha := range input {
+				output <- replicateWithRetry(id, source, dest, sha)
+			}
+		}(id)
+	}
+
+	for _, sha := range shas {
+		input <- sha
+	}
+	close(input)
+	wg.Wait()
+	close(output)
+
+	total := TotalStat{expectedDownloadCount: len(shas)}
+	for stat := range output {
+		total.Size += stat.Size
+		total.Duration += stat.Duration
+		if stat.Status == DOWN_SKIP {
+			total.Skip++
+		} else if stat.Status == DOWN_OK {
+			total.Count++
+		} else {
+			total.Fail++
+		}
+	}
+
+	return total
+}
+
+func replicateWithRetry(id int, source, dest *StorClient, sha hashutil.Hash) DownStat {
+	logger := log.WithFields(log.Fields{
+		"worker": id,
+		"sha256": sha.String(),
+	})
+
+	exists, err := existsOnStor(dest.newStdHTTPClient(), dest.createStorURL(sha))
+	if err != nil {
+		logger.Warningf("Existence check on destination fail: %s", err)
+	} else if exists {
+		logger.Debug("Object exists on destination - skip replication")
+		return DownStat{Status: DOWN_SKIP}
+	}
+
+	startTime := source.Clock.Now()
+
+	var size int64
+	err = source.RetryEngine.Do(
+		sha,
+		func() error {
+			var err error
+			size, err = replicate(source.httpClientFunc(), dest.newStdHTTPClient(), source.createStorURL(sha), dest.createStorURL(sha), sha)
+			return err
+		},
+		func(err error) bool {
+			logger.Debugf("Replication attempt fail: %s", err)
+
+			if e, ok := err.(StatusError); ok && e.StatusCode == 404 {
+				return false
+			}
+
+			return true
+		},
+	)
+
+	if err != nil {
+		logger.Errorf("Replication of %s fail: %s", sha, err)
+		return DownStat{Status: DOWN_FAIL}
+	}
+
+	logger.Debugf("Replicated %s", sha)
+	return DownStat{Size: size, Duration: since(source.Clock, startTime), Status: DOWN_OK}
+}
+
+// replicate stream one object from sourceURL to destURL (PUT)
+// upload is aborted if downloaded object doesn't match sha
+func replicate(source httpClient, dest *http.Client, sourceURL, destURL string, sha hashutil.Hash) (int64, error) {
+	pipeReader, pipeWriter := io.Pipe()
+
+	type downloadResult struct {
+		succ successDownload
+		err  error
+	}
+
+	downloaded := make(chan downloadResult, 1)
+	go func() {
+		succ, err := downloadFileToWriter(source, sourceURL, pipeWriter, sha)
+		// StatusError isn't comparable, net/http compare errors, so wrap it
+		pipeWriter.CloseWithError(errors.Wrap(err, "Download for replication fail"))
+		downloaded <- downloadResult{succ, err}
+	}()
+
+	req, err := http.NewRequest(http.MethodPut, destURL, pipeReader)
+	if err != nil {
+		pipeReader.CloseWithError(err)
+		<-downloaded
+		return 0, err
+	}
+
+	resp, err := dest.Do(req)
+	// unblock download if upload ends before whole body is read
+	pipeReader.CloseWithError(errors.New("upload ends"))
+	result := <-downloaded
+	if result.err != nil {
+		if err == nil {
+			resp.Body.Close()
+		}
+		return 0, result.err
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, uploadError{sha: sha, statusCode: resp.StatusCode, status: resp.Status}
+	}
+
+	return result.succ.size, nil
+}
+
+// existsOnStor check existence of object via HEAD request
+func existsOnStor(httpClient *http.Client, url string) (bool, error) {
+	resp, err := httpClient.Head(url)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("HEAD %s fail %d (%s)", url, resp.StatusCode, resp.Status)
+	}
+}
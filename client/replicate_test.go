@@ -0,0 +1,81 @@
+package storclient_test
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func sha256Of(content string) hashutil.Hash {
+	sum := sha256.Sum256([]byte(content))
+	hash, _ := hashutil.BytesToHash(sha256.New(), sum[:])
+	return hash
+}
+
+func TestReplicate(t *testing.T) {
+	objects := map[string]string{
+		sha256Of("first").String():  "first",
+		sha256Of("second").String(): "second",
+	}
+
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := objects[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer source.Close()
+
+	var lock sync.Mutex
+	uploaded := map[string]string{sha256Of("second").String(): "second"}
+	dest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		sha := strings.TrimPrefix(r.URL.Path, "/")
+		switch r.Method {
+		case http.MethodHead:
+			if _, ok := uploaded[sha]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case http.MethodPut:
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				// aborted upload
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			uploaded[sha] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer dest.Close()
+
+	sourceURL, _ := url.Parse(source.URL)
+	destURL, _ := url.Parse(dest.URL)
+
+	sourceClient, err := storclient.New(*sourceURL, "", storclient.StorClientOpts{RetryAttempts: 1})
+	assert.NoError(t, err)
+	destClient, err := storclient.New(*destURL, "", storclient.StorClientOpts{})
+	assert.NoError(t, err)
+
+	total := storclient.Replicate(sourceClient, destClient, []hashutil.Hash{sha256Of("first"), sha256Of("second"), sha256Of("missing")})
+
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, 1, total.Skip)
+	assert.Equal(t, 1, total.Failed())
+	assert.Equal(t, int64(len("first")), total.Size)
+	assert.Equal(t, "first", uploaded[sha256Of("first").String()])
+	assert.NotContains(t, uploaded, sha256Of("missing").String())
+}
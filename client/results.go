@@ -0,0 +1,141 @@
+package storclient
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// DownloadResult is result of one download (or upload)
+type DownloadResult struct {
+	Sha hashutil.Hash
+	// final path of file (empty for devnull, dry run and archive output or if path isn't known)
+	Path     string
+	Size     int64
+	Duration time.Duration
+	Status   DownloadStatus
+	// underlying error of failed download
+	Err error
+	// captured response headers of last attempt (see StorClientOpts.CaptureHeaders)
+	Header http.Header
+	// sha exists on server (set in DryRun mode only)
+	Exists bool
+	// response headers of HEAD request (DOWN_METADATA only)
+	Metadata http.Header
+	// file is taken from local cache (see StorClientOpts.Cache)
+	Cached bool
+	// alternate identifier under which sha was downloaded (see StorClientOpts.AliasResolver),
+	// empty if sha was downloaded under own identity
+	Alias hashutil.Hash
+	// sha of bundle which contains this object (see DownloadBundle)
+	Bundle hashutil.Hash
+	// content was downloaded from trusted mirror without verification (see StorClientOpts.InsecureMirrors)
+	Unverified bool
+
+	// submission order of job
+	seq int64
+	// id of worker which processed job
+	worker int
+	// count of retried attempts
+	retries int
+}
+
+// Results return channel with result of each download
+//
+// must be called before Start, channel is closed by Wait after end of all downloads;
+// caller must read channel until is closed, otherwise workers block
+func (client *StorClient) Results() <-chan DownloadResult {
+	if client.results == nil {
+		client.results = make(chan DownloadResult, client.Max)
+	}
+
+	return client.results
+}
+
+func (client *StorClient) sendResult(result DownloadResult) {
+	if result.Status == DOWN_OK && result.Path != "" {
+		client.publisher.add(result.Path)
+	}
+	client.resultWriter.write(result)
+
+	if client.results != nil {
+		client.results <- result
+	}
+}
+
+// downloadResultJSON is JSON form of DownloadResult (one line of StorClientOpts.ResultWriter)
+type downloadResultJSON struct {
+	Sha        string      `json:"sha"`
+	Path       string      `json:"path,omitempty"`
+	Size       int64       `json:"size"`
+	Duration   float64     `json:"duration"`
+	Status     string      `json:"status"`
+	Error      string      `json:"error,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Exists     bool        `json:"exists,omitempty"`
+	Metadata   http.Header `json:"metadata,omitempty"`
+	Cached     bool        `json:"cached,omitempty"`
+	Alias      string      `json:"alias,omitempty"`
+	Unverified bool        `json:"unverified,omitempty"`
+	Bundle     string      `json:"bundle,omitempty"`
+}
+
+// MarshalJSON serialize result with sha in hex, duration in seconds and status and error as strings
+func (result DownloadResult) MarshalJSON() ([]byte, error) {
+	out := downloadResultJSON{
+		Sha:        result.Sha.String(),
+		Path:       result.Path,
+		Size:       result.Size,
+		Duration:   result.Duration.Seconds(),
+		Status:     result.Status.String(),
+		Header:     result.Header,
+		Exists:     result.Exists,
+		Metadata:   result.Metadata,
+		Cached:     result.Cached,
+		Unverified: result.Unverified,
+	}
+	if !result.Bundle.IsEmpty() {
+		out.Bundle = result.Bundle.String()
+	}
+	if !result.Alias.IsEmpty() {
+		out.Alias = result.Alias.String()
+	}
+	if result.Err != nil {
+		out.Error = result.Err.Error()
+	}
+
+	return json.Marshal(out)
+}
+
+// resultWriter write results as NDJSON, it's safe for concurrent use
+type resultWriter struct {
+	lock sync.Mutex
+	enc  *json.Encoder
+}
+
+func newResultWriter(w io.Writer) *resultWriter {
+	if w == nil {
+		return nil
+	}
+
+	return &resultWriter{enc: json.NewEncoder(w)}
+}
+
+// write result as one JSON line, nil resultWriter is noop
+func (r *resultWriter) write(result DownloadResult) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.enc.Encode(result); err != nil {
+		log.WithField("sha256", result.Sha.String()).Warningf("Write of result fail: %s", err)
+	}
+}
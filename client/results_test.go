@@ -0,0 +1,114 @@
+package storclient_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// startFirstStor serve content "first" by its sha, other shas are 404
+func startFirstStor() (*url.URL, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+sha256Of("first").String() {
+			w.Write([]byte("first"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+
+	serverURL, _ := url.Parse(server.URL)
+
+	return serverURL, server.Close
+}
+
+// tempDir create temp dir with prefix, returned func removes it
+func tempDir(t *testing.T, prefix string) (string, func()) {
+	dir, err := ioutil.TempDir("", prefix)
+	assert.NoError(t, err)
+
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestResults(t *testing.T) {
+	serverURL, stop := startFirstStor()
+	defer stop()
+
+	tmpDir, cleanup := tempDir(t, "results")
+	defer cleanup()
+
+	client, err := storclient.New(*serverURL, tmpDir, storclient.StorClientOpts{Max: 2, RetryAttempts: 1})
+	assert.NoError(t, err)
+
+	results := client.Results()
+	client.Start()
+
+	collected := make(map[string]storclient.DownloadResult)
+	done := make(chan struct{})
+	go func() {
+		for result := range results {
+			collected[result.Sha.String()] = result
+		}
+		close(done)
+	}()
+
+	assert.NoError(t, client.Download(sha256Of("first")))
+	assert.NoError(t, client.Download(sha256Of("missing")))
+	client.Wait()
+	<-done
+
+	assert.Len(t, collected, 2)
+
+	ok := collected[sha256Of("first").String()]
+	assert.Equal(t, storclient.DOWN_OK, ok.Status)
+	assert.Equal(t, filepath.Join(tmpDir, sha256Of("first").String()), ok.Path)
+	assert.Equal(t, int64(len("first")), ok.Size)
+	assert.NoError(t, ok.Err)
+
+	fail := collected[sha256Of("missing").String()]
+	assert.Equal(t, storclient.DOWN_FAIL, fail.Status)
+	assert.Error(t, fail.Err)
+}
+
+func TestResultWriter(t *testing.T) {
+	serverURL, stop := startFirstStor()
+	defer stop()
+
+	out := &bytes.Buffer{}
+	client, err := storclient.New(*serverURL, os.TempDir(), storclient.StorClientOpts{Max: 2, RetryAttempts: 1, Devnull: true, ResultWriter: out})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(sha256Of("first")))
+	assert.NoError(t, client.Download(sha256Of("missing")))
+	client.Wait()
+
+	lines := map[string]map[string]interface{}{}
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines[line["sha"].(string)] = line
+	}
+	assert.Len(t, lines, 2)
+
+	ok := lines[sha256Of("first").String()]
+	assert.Equal(t, "ok", ok["status"])
+	assert.Equal(t, float64(len("first")), ok["size"])
+	assert.NotContains(t, ok, "error")
+
+	fail := lines[sha256Of("missing").String()]
+	assert.Equal(t, "fail", fail["status"])
+	assert.Contains(t, fail["error"], "404")
+}
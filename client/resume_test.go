@@ -0,0 +1,180 @@
+package storclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadResume(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	sum := sha256.Sum256([]byte(content))
+	sha, _ := hashutil.BytesToHash(sha256.New(), sum[:])
+
+	var ranges []string
+	ignoreRange := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if ignoreRange {
+			r.Header.Del("Range")
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader([]byte(content)))
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "resume")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	target, err := pathutil.New(tmpDir, sha.String())
+	assert.NoError(t, err)
+	temp := filepath.Join(tmpDir, sha.String()+".temp")
+
+	newClient := func() httpClient {
+		return &contextClient{Client: &http.Client{}, ctx: context.Background()}
+	}
+
+	download := func(partial string) (int64, error) {
+		ranges = nil
+		assert.NoError(t, ioutil.WriteFile(temp, []byte(partial), 0644))
+		defer os.Remove(target.Canonpath())
+
+		size, err := downloadFileViaTempFile(newClient(), target, server.URL, sha)
+		if err == nil {
+			downloaded, readErr := ioutil.ReadFile(target.Canonpath())
+			assert.NoError(t, readErr)
+			assert.Equal(t, content, string(downloaded))
+		}
+
+		return size, err
+	}
+
+	t.Run("resume from offset", func(t *testing.T) {
+		size, err := download(content[:300])
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(content)), size)
+		assert.Equal(t, []string{"bytes=300-"}, ranges)
+	})
+
+	t.Run("server ignores range", func(t *testing.T) {
+		ignoreRange = true
+		defer func() { ignoreRange = false }()
+
+		size, err := download(content[:300])
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(content)), size)
+	})
+
+	t.Run("corrupted temp file is removed", func(t *testing.T) {
+		_, err := download("corrupted")
+		assert.Error(t, err)
+		assert.False(t, fileExists(temp), "temp file is removed")
+
+		size, err := download("")
+		assert.NoError(t, err, "next attempt downloads from start")
+		assert.Equal(t, int64(len(content)), size)
+		assert.Equal(t, []string{""}, ranges)
+	})
+
+	t.Run("temp file longer than object", func(t *testing.T) {
+		_, err := download(content + "tail")
+		assert.Error(t, err)
+		assert.False(t, fileExists(temp), "temp file is removed")
+	})
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestDownloadResumeIfRange(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	sum := sha256.Sum256([]byte(content))
+	sha, _ := hashutil.BytesToHash(sha256.New(), sum[:])
+
+	var ifRanges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifRanges = append(ifRanges, r.Header.Get("If-Range"))
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader([]byte(content)))
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "ifrange")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	target, err := pathutil.New(tmpDir, sha.String())
+	assert.NoError(t, err)
+	temp := filepath.Join(tmpDir, sha.String()+".temp")
+
+	newClient := func() httpClient {
+		return &contextClient{Client: &http.Client{}, ctx: context.Background()}
+	}
+
+	t.Run("changed object is downloaded from start", func(t *testing.T) {
+		ifRanges = nil
+		assert.NoError(t, ioutil.WriteFile(temp, []byte("stale content of previous version"), 0644))
+		assert.NoError(t, writeValidator(temp, `"v1"`))
+		defer os.Remove(target.Canonpath())
+
+		size, err := downloadFileViaTempFile(newClient(), target, server.URL, sha)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(content)), size)
+		assert.Equal(t, []string{`"v1"`}, ifRanges)
+		assert.Equal(t, "", readValidator(temp), "validator is removed with temp file")
+	})
+
+	t.Run("unchanged object is resumed", func(t *testing.T) {
+		ifRanges = nil
+		assert.NoError(t, ioutil.WriteFile(temp, []byte(content[:300]), 0644))
+		assert.NoError(t, writeValidator(temp, `"v2"`))
+		defer os.Remove(target.Canonpath())
+
+		size, err := downloadFileViaTempFile(newClient(), target, server.URL, sha)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(content)), size)
+		assert.Equal(t, []string{`"v2"`}, ifRanges)
+	})
+
+	t.Run("validator of fresh download is stored", func(t *testing.T) {
+		os.Remove(temp)
+		defer os.Remove(temp)
+
+		tempPath, err := pathutil.New(temp)
+		assert.NoError(t, err)
+		_, err = downloadFile(newClient(), tempPath, server.URL, sha, true)
+		assert.NoError(t, err)
+		assert.Equal(t, `"v2"`, readValidator(temp))
+		removeValidator(temp)
+	})
+
+	t.Run("stream fails if object changed", func(t *testing.T) {
+		v, err := newVerification(nil, sha)
+		assert.NoError(t, err)
+		s := &streamDownload{w: ioutil.Discard, verification: v, written: 300, validator: `"v1"`}
+
+		noRange := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v2"`)
+			w.Write([]byte(content))
+		}))
+		defer noRange.Close()
+
+		err = s.download(newClient(), noRange.URL, sha)
+		_, ok := err.(shaMismatchError)
+		assert.True(t, ok, "%v", err)
+	})
+}
@@ -0,0 +1,111 @@
+package storclient
+
+import (
+	"errors"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/avast/retry-go"
+)
+
+// RetryEngine is retry loop of one download
+//
+// Do calls attempt until it succeeds or engine gives up and returns error of download.
+// retryIf reports if error of attempt is retryable (e.g. 404 from stor isn't)
+// and must be called for each failed attempt, because it can switch source
+// of next attempt (S3 -> stor fallback)
+//
+// Do is called from worker goroutines, so it must be safe for concurrent use
+type RetryEngine interface {
+	Do(sha hashutil.Hash, attempt func() error, retryIf func(error) bool) error
+}
+
+// ErrMaxElapsedTime is returned if download exceeds StorClientOpts.MaxElapsedTime
+var ErrMaxElapsedTime = errors.New("max elapsed time of download exceeded")
+
+// defaultRetryEngine is retry (via retry-go) configured by RetryAttempts,
+// RetryDelay, RetryBackoff, RetryMaxDelay, RetryJitter, OnRetry and Clock
+type defaultRetryEngine struct {
+	client *StorClient
+}
+
+func (engine defaultRetryEngine) Do(sha hashutil.Hash, attempt func() error, retryIf func(error) bool) error {
+	client := engine.client
+
+	startTime := client.Clock.Now()
+
+	var n uint
+	var lastErr error
+	// retries stopped on retryable error (not on e.g. 404 or cancel)
+	exhausted := false
+	err := retry.Do(
+		func() error {
+			if n > 0 {
+				delay := client.retryDelay(n)
+				if client.MaxElapsedTime > 0 && since(client.Clock, startTime)+delay >= client.MaxElapsedTime {
+					return ErrMaxElapsedTime
+				}
+				if client.OnRetry != nil {
+					client.OnRetry(sha, n, lastErr, delay)
+				}
+
+				select {
+				case <-client.Clock.After(delay):
+				case <-client.ctx.Done():
+					return client.ctx.Err()
+				}
+			}
+			n++
+
+			lastErr = attempt()
+			return lastErr
+		},
+		retry.RetryIf(func(err error) bool {
+			retried := err != ErrMaxElapsedTime && client.ctx.Err() == nil && retryIf(err)
+			exhausted = retried || err == ErrMaxElapsedTime
+			return retried
+		}),
+		// delays between attempts are done via client.Clock (see retryDelay)
+		retry.Delay(0),
+		retry.Attempts(client.RetryAttempts),
+		retry.Units(1),
+	)
+
+	if attemptErrors, ok := err.(retry.Error); ok {
+		return newRetryError(attemptErrors, exhausted)
+	}
+
+	return err
+}
+
+// retryDelay return delay before retry attempt (attempt >= 1)
+// by RetryBackoff, capped by RetryMaxDelay and shortened by random RetryJitter
+func (client *StorClient) retryDelay(attempt uint) time.Duration {
+	delay := backoffDelay(client.RetryBackoff, client.RetryDelay, client.RetryMaxDelay, attempt)
+	if client.RetryJitter > 0 {
+		delay -= time.Duration(client.RetryJitter * client.jitter.float64() * float64(delay))
+	}
+
+	return delay
+}
+
+// lastAttemptError return error of last failed attempt if err is RetryError or error of retry-go
+// (custom engines can return error of attempt directly)
+func lastAttemptError(err error) error {
+	if retryErr, ok := err.(RetryError); ok && len(retryErr.Attempts) > 0 {
+		return retryErr.Unwrap()
+	}
+
+	attemptErrors, ok := err.(retry.Error)
+	if !ok {
+		return err
+	}
+
+	for i := len(attemptErrors) - 1; i >= 0; i-- {
+		if attemptErrors[i] != nil {
+			return attemptErrors[i]
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,112 @@
+package storclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// oneShotRetryEngine never retry, only count calls
+type oneShotRetryEngine struct {
+	lock  sync.Mutex
+	calls int
+}
+
+func (engine *oneShotRetryEngine) Do(sha hashutil.Hash, attempt func() error, retryIf func(error) bool) error {
+	engine.lock.Lock()
+	engine.calls++
+	engine.lock.Unlock()
+
+	err := attempt()
+	if err != nil {
+		retryIf(err)
+	}
+
+	return err
+}
+
+func TestCustomRetryEngine(t *testing.T) {
+	engine := &oneShotRetryEngine{}
+
+	httpClientTouch := 0
+	httpClient := func() httpClient {
+		httpClientTouch++
+		return &clientMock{statusCode: 500, status: "Something bad"}
+	}
+
+	downloadWorkersTest(t, StorClientOpts{RetryEngine: engine}, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
+		assert.Equal(t, DOWN_FAIL, stat[0].Status)
+	})
+
+	assert.Equal(t, 1, engine.calls)
+	assert.Equal(t, 1, httpClientTouch)
+}
+
+func TestDefaultRetryEngineRetryIf(t *testing.T) {
+	client, err := New(url.URL{}, "", StorClientOpts{RetryAttempts: 5, Clock: &fakeClock{}})
+	assert.NoError(t, err)
+
+	attempts := 0
+	err = client.RetryEngine.Do(emptyHash, func() error {
+		attempts++
+		return StatusError{Sha: emptyHash, StatusCode: 404, Status: "Not found"}
+	}, func(err error) bool {
+		return false
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts, "not retryable error stops retry loop")
+}
+
+func TestMaxElapsedTime(t *testing.T) {
+	clock := &fakeClock{}
+
+	httpClient := func() httpClient { return &clientMock{statusCode: 500, status: "Something bad"} }
+	opts := StorClientOpts{RetryAttempts: 10, RetryDelay: time.Minute, MaxElapsedTime: 5 * time.Minute, Clock: clock}
+	downloadWorkersTest(t, opts, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
+		assert.Equal(t, DOWN_FAIL, stat[0].Status)
+	})
+
+	// 1m + 2m, next 4m delay exceeds 5m ceiling
+	assert.Equal(t, []time.Duration{time.Minute, 2 * time.Minute}, clock.sleeps)
+}
+
+func TestLimitRequestTime(t *testing.T) {
+	client, err := New(url.URL{}, "", StorClientOpts{MaxElapsedTime: time.Minute})
+	assert.NoError(t, err)
+
+	hc := &http.Client{Timeout: time.Hour}
+	client.limitRequestTime(hc, time.Now().Add(-30*time.Second))
+	assert.InDelta(t, float64(30*time.Second), float64(hc.Timeout), float64(time.Second))
+}
+
+func TestRequestTimeout(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "10")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+	serverURL, _ := url.Parse(server.URL)
+
+	client, err := New(*serverURL, "", StorClientOpts{RequestTimeout: 100 * time.Millisecond, RetryAttempts: 2, RetryDelay: time.Millisecond})
+	assert.NoError(t, err)
+
+	start := time.Now()
+	_, err = client.DownloadBytes(emptyHash)
+	assert.Error(t, err, "hung body fails")
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+	_, err = New(*serverURL, "", StorClientOpts{RequestTimeout: -1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,206 @@
+package storclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+)
+
+// DefaultS3Region is region of S3Source without Region
+const DefaultS3Region = "us-east-1"
+
+// emptyPayloadHash is sha256 of empty body of GET and HEAD requests
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Source is S3 (or S3-compatible e.g. MinIO) bucket with objects keyed by sha (<prefix>/<sha>),
+// requests are signed by AWS Signature Version 4 if AccessKeyID is set
+type S3Source struct {
+	Bucket string
+	// key prefix of objects (without trailing slash)
+	Prefix string
+	// default ("") is us-east-1
+	Region string
+	// S3 endpoint e.g. https://minio.domain.tld
+	// default (nil) is https://s3.<region>.amazonaws.com
+	Endpoint *url.URL
+	// bucket is part of path (<endpoint>/<bucket>/<key>), usual for MinIO,
+	// otherwise bucket is part of host (<bucket>.<endpoint host>)
+	PathStyle bool
+	// credentials, default ("") means anonymous requests
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// time of signing, default (nil) is time.Now
+	now func() time.Time
+}
+
+// ParseS3Source parse s3://bucket/prefix url (credentials, region and endpoint have to be set on result)
+func ParseS3Source(raw string) (*S3Source, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "s3" || u.Host == "" {
+		return nil, errors.Errorf("%s isn't s3://bucket/prefix url", raw)
+	}
+
+	return &S3Source{Bucket: u.Host, Prefix: strings.Trim(u.Path, "/")}, nil
+}
+
+func (source *S3Source) region() string {
+	if source.Region == "" {
+		return DefaultS3Region
+	}
+
+	return source.Region
+}
+
+// bucketURL return url of bucket by endpoint and path style
+func (source *S3Source) bucketURL() url.URL {
+	endpoint := url.URL{Scheme: "https", Host: fmt.Sprintf("s3.%s.amazonaws.com", source.region())}
+	if source.Endpoint != nil {
+		endpoint = *source.Endpoint
+	}
+	endpoint.Path = strings.TrimRight(endpoint.Path, "/")
+
+	if source.PathStyle {
+		endpoint.Path += "/" + source.Bucket
+	} else {
+		endpoint.Host = source.Bucket + "." + endpoint.Host
+	}
+
+	return endpoint
+}
+
+// ObjectURL return url of object <prefix>/<sha>
+func (source *S3Source) ObjectURL(sha hashutil.Hash) (string, error) {
+	u := source.bucketURL()
+	if source.Prefix != "" {
+		u.Path += "/" + source.Prefix
+	}
+	u.Path += "/" + sha.String()
+
+	return u.String(), nil
+}
+
+// Transport sign requests to bucket if credentials are set
+func (source *S3Source) Transport(next http.RoundTripper) http.RoundTripper {
+	if source.AccessKeyID == "" {
+		return next
+	}
+
+	return &s3SigningTransport{next: next, source: source, host: source.bucketURL().Host}
+}
+
+// s3SigningTransport sign requests to host of bucket, other requests (stor) are untouched
+type s3SigningTransport struct {
+	next   http.RoundTripper
+	source *S3Source
+	host   string
+}
+
+func (t *s3SigningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.next.RoundTrip(req)
+	}
+
+	now := time.Now
+	if t.source.now != nil {
+		now = t.source.now
+	}
+
+	signed := req.Clone(req.Context())
+	t.source.sign(signed, now())
+
+	return t.next.RoundTrip(signed)
+}
+
+// sign add AWS Signature Version 4 headers to request without body
+func (source *S3Source) sign(req *http.Request, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if source.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", source.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, source.region(), "s3", "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + source.SecretAccessKey)
+	for _, part := range []string{date, source.region(), "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		source.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+// canonicalQuery return query sorted by key and value with AWS escaping (space is %20)
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, awsEscape(key)+"="+awsEscape(value))
+		}
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+func awsEscape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
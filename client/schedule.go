@@ -0,0 +1,292 @@
+package storclient
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ScheduleWindow is time-of-day profile of bandwidth and concurrency (see StorClientOpts.Schedule)
+//
+// Start and End are offsets from local midnight, window with End before Start wraps over midnight
+// (e.g. 22:00-06:00)
+type ScheduleWindow struct {
+	Start time.Duration
+	End   time.Duration
+	// max download rate (bytes per second) of all workers together in window
+	// default (0) means without limit
+	MaxBytesPerSec int64
+	// max count of concurrent downloads in window
+	// default (0) is StorClientOpts.Max
+	Workers int
+}
+
+// contains return true if time of day (offset from midnight) is in window
+func (window ScheduleWindow) contains(offset time.Duration) bool {
+	if window.Start <= window.End {
+		return offset >= window.Start && offset < window.End
+	}
+
+	return offset >= window.Start || offset < window.End
+}
+
+func (window ScheduleWindow) String() string {
+	return fmt.Sprintf("%s-%s", formatTimeOfDay(window.Start), formatTimeOfDay(window.End))
+}
+
+// ParseScheduleWindow parse window in format "HH:MM-HH:MM[,rate=BYTES_PER_SEC][,workers=N]"
+// e.g. "09:00-17:00,rate=1048576,workers=2"
+func ParseScheduleWindow(s string) (ScheduleWindow, error) {
+	parts := strings.Split(s, ",")
+
+	bounds := strings.SplitN(parts[0], "-", 2)
+	if len(bounds) != 2 {
+		return ScheduleWindow{}, errors.Errorf("schedule window %s isn't HH:MM-HH:MM", s)
+	}
+
+	var window ScheduleWindow
+	var err error
+	if window.Start, err = parseTimeOfDay(bounds[0]); err != nil {
+		return ScheduleWindow{}, errors.Wrapf(err, "schedule window %s", s)
+	}
+	if window.End, err = parseTimeOfDay(bounds[1]); err != nil {
+		return ScheduleWindow{}, errors.Wrapf(err, "schedule window %s", s)
+	}
+
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return ScheduleWindow{}, errors.Errorf("schedule window %s: %s isn't key=value", s, part)
+		}
+
+		switch kv[0] {
+		case "rate":
+			window.MaxBytesPerSec, err = strconv.ParseInt(kv[1], 10, 64)
+		case "workers":
+			window.Workers, err = strconv.Atoi(kv[1])
+		default:
+			return ScheduleWindow{}, errors.Errorf("schedule window %s: unknown key %s (rate, workers)", s, kv[0])
+		}
+		if err != nil {
+			return ScheduleWindow{}, errors.Wrapf(err, "schedule window %s", s)
+		}
+	}
+
+	return window, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.Errorf("%s isn't HH:MM", s)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatTimeOfDay(offset time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(offset/time.Hour), int(offset%time.Hour/time.Minute))
+}
+
+// timeOfDay return offset of t from its local midnight
+func timeOfDay(t time.Time) time.Duration {
+	hour, min, sec := t.Clock()
+	return time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second + time.Duration(t.Nanosecond())
+}
+
+// schedule applies bandwidth and concurrency of active window to global bucket and worker gate
+type schedule struct {
+	windows []ScheduleWindow
+	// rate and workers outside of windows
+	defaultRate    int64
+	defaultWorkers int
+
+	bucket *tokenBucket
+	gate   *concurrencyGate
+	// index of active window (-1 outside of windows)
+	active int
+}
+
+func newSchedule(clock Clock, windows []ScheduleWindow, rate int64, workers int) (*schedule, error) {
+	if len(windows) == 0 {
+		return nil, nil
+	}
+
+	for _, window := range windows {
+		if window.Start < 0 || window.Start >= 24*time.Hour || window.End < 0 || window.End >= 24*time.Hour || window.Start == window.End {
+			return nil, errors.Errorf("schedule window %s isn't valid time of day range", window)
+		}
+		if window.MaxBytesPerSec < 0 || window.Workers < 0 || window.Workers > workers {
+			return nil, errors.Errorf("schedule window %s: rate %d or workers %d out of range (0 - %d workers)", window, window.MaxBytesPerSec, window.Workers, workers)
+		}
+	}
+
+	s := &schedule{
+		windows:        windows,
+		defaultRate:    rate,
+		defaultWorkers: workers,
+		bucket:         &tokenBucket{clock: clock, last: clock.Now()},
+		gate:           newConcurrencyGate(),
+		active:         -2,
+	}
+	s.apply(clock.Now())
+
+	return s, nil
+}
+
+// window return index of first window containing t (-1 outside of windows)
+func (s *schedule) window(t time.Time) int {
+	offset := timeOfDay(t)
+	for i, window := range s.windows {
+		if window.contains(offset) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// apply set limits of window active at t, it returns true if window changed
+func (s *schedule) apply(t time.Time) bool {
+	active := s.window(t)
+	if active == s.active {
+		return false
+	}
+	s.active = active
+
+	rate, workers := s.defaultRate, s.defaultWorkers
+	name := "default"
+	if active >= 0 {
+		window := s.windows[active]
+		rate, workers, name = window.MaxBytesPerSec, window.Workers, window.String()
+		if workers == 0 {
+			workers = s.defaultWorkers
+		}
+	}
+
+	s.bucket.setRate(rate)
+	s.gate.setLimit(workers)
+	log.WithFields(log.Fields{
+		"window":  name,
+		"rate":    rate,
+		"workers": workers,
+	}).Info("Schedule window applied")
+
+	return true
+}
+
+// next return duration from t to nearest start or end of window
+func (s *schedule) next(t time.Time) time.Duration {
+	offset := timeOfDay(t)
+	next := 24 * time.Hour
+	for _, window := range s.windows {
+		for _, bound := range []time.Duration{window.Start, window.End} {
+			d := bound - offset
+			if d <= 0 {
+				d += 24 * time.Hour
+			}
+			if d < next {
+				next = d
+			}
+		}
+	}
+
+	return next
+}
+
+// runSchedule apply active window at each bound of windows until queue is closed
+func (client *StorClient) runSchedule() {
+	for {
+		select {
+		case <-client.queue.jobs.closed:
+			return
+		case <-client.Clock.After(client.schedule.next(client.Clock.Now())):
+		}
+
+		client.schedule.apply(client.Clock.Now())
+	}
+}
+
+// concurrencyGate limits count of workers processing jobs at once, limit can be changed at any time
+type concurrencyGate struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+}
+
+func newConcurrencyGate() *concurrencyGate {
+	gate := &concurrencyGate{}
+	gate.cond = sync.NewCond(&gate.lock)
+
+	return gate
+}
+
+func (gate *concurrencyGate) setLimit(limit int) {
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+
+	gate.limit = limit
+	gate.cond.Broadcast()
+}
+
+// gateSlot is permission of one worker to process jobs
+type gateSlot struct {
+	gate *concurrencyGate
+	held bool
+}
+
+// slot return (not held) slot of worker, slot of nil schedule is without limit
+func (s *schedule) slot() *gateSlot {
+	if s == nil {
+		return &gateSlot{}
+	}
+
+	return &gateSlot{gate: s.gate}
+}
+
+// wait keep slot if it is within limit, otherwise release it and wait for free one
+func (slot *gateSlot) wait() {
+	gate := slot.gate
+	if gate == nil {
+		return
+	}
+
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+
+	if slot.held {
+		if gate.active <= gate.limit {
+			return
+		}
+		gate.active--
+		slot.held = false
+		gate.cond.Broadcast()
+	}
+
+	for gate.active >= gate.limit {
+		gate.cond.Wait()
+	}
+	gate.active++
+	slot.held = true
+}
+
+// release give slot back (worker ends)
+func (slot *gateSlot) release() {
+	gate := slot.gate
+	if gate == nil || !slot.held {
+		return
+	}
+
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+
+	gate.active--
+	slot.held = false
+	gate.cond.Broadcast()
+}
@@ -0,0 +1,119 @@
+package storclient
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScheduleWindow(t *testing.T) {
+	window, err := ParseScheduleWindow("09:00-17:30,rate=1048576,workers=2")
+	assert.NoError(t, err)
+	assert.Equal(t, ScheduleWindow{Start: 9 * time.Hour, End: 17*time.Hour + 30*time.Minute, MaxBytesPerSec: 1048576, Workers: 2}, window)
+	assert.Equal(t, "09:00-17:30", window.String())
+
+	window, err = ParseScheduleWindow("22:00-06:00")
+	assert.NoError(t, err)
+	assert.Equal(t, ScheduleWindow{Start: 22 * time.Hour, End: 6 * time.Hour}, window)
+
+	for _, invalid := range []string{"09:00", "9-17", "09:00-17:00,rate", "09:00-17:00,speed=1", "09:00-17:00,workers=x"} {
+		_, err := ParseScheduleWindow(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestScheduleWindowContains(t *testing.T) {
+	day := ScheduleWindow{Start: 9 * time.Hour, End: 17 * time.Hour}
+	assert.False(t, day.contains(8*time.Hour))
+	assert.True(t, day.contains(9*time.Hour))
+	assert.False(t, day.contains(17*time.Hour))
+
+	night := ScheduleWindow{Start: 22 * time.Hour, End: 6 * time.Hour}
+	assert.True(t, night.contains(23*time.Hour))
+	assert.True(t, night.contains(time.Hour))
+	assert.False(t, night.contains(12*time.Hour))
+}
+
+func TestScheduleApply(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2019, time.January, 1, 8, 0, 0, 0, time.UTC)}
+	windows := []ScheduleWindow{
+		{Start: 9 * time.Hour, End: 17 * time.Hour, MaxBytesPerSec: 100, Workers: 2},
+		{Start: 0, End: 6 * time.Hour},
+	}
+
+	s, err := newSchedule(clock, windows, 1000, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, s.active)
+	assert.Equal(t, int64(1000), s.bucket.burst())
+	assert.Equal(t, 8, s.gate.limit)
+	assert.Equal(t, time.Hour, s.next(clock.Now()))
+
+	clock.Sleep(time.Hour)
+	assert.True(t, s.apply(clock.Now()))
+	assert.Equal(t, int64(100), s.bucket.burst())
+	assert.Equal(t, 2, s.gate.limit)
+	assert.Equal(t, 8*time.Hour, s.next(clock.Now()))
+
+	clock.Sleep(time.Minute)
+	assert.False(t, s.apply(clock.Now()), "same window")
+
+	clock.Sleep(16 * time.Hour)
+	assert.True(t, s.apply(clock.Now()))
+	assert.Equal(t, 1, s.active)
+	assert.Equal(t, int64(0), s.bucket.burst(), "without limit")
+	assert.Equal(t, 8, s.gate.limit, "default workers")
+	assert.Equal(t, time.Duration(0), s.bucket.take(1<<30))
+
+	_, err = newSchedule(clock, []ScheduleWindow{{Start: time.Hour, End: time.Hour}}, 0, 8)
+	assert.Error(t, err)
+	_, err = newSchedule(clock, []ScheduleWindow{{Start: time.Hour, End: 2 * time.Hour, Workers: 9}}, 0, 8)
+	assert.Error(t, err)
+
+	s, err = newSchedule(clock, nil, 0, 8)
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+}
+
+func TestConcurrencyGate(t *testing.T) {
+	s := &schedule{gate: newConcurrencyGate()}
+	s.gate.setLimit(1)
+
+	first := s.slot()
+	first.wait()
+
+	second := s.slot()
+	acquired := make(chan struct{})
+	go func() {
+		second.wait()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("slot over limit is acquired")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.gate.setLimit(2)
+	<-acquired
+
+	// lowered limit is applied when worker asks for next job
+	s.gate.setLimit(1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first.wait()
+	}()
+	second.release()
+	wg.Wait()
+	first.release()
+
+	assert.Equal(t, 0, s.gate.active)
+
+	var unlimited *schedule
+	unlimited.slot().wait()
+	unlimited.slot().release()
+}
@@ -0,0 +1,67 @@
+package storclient
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ShutdownOnSignal wire OS signals (default os.Interrupt) to graceful shutdown of client
+//
+// first signal stops enqueueing (Download returns ErrQueueClosed) and already queued downloads finish,
+// second signal aborts in-flight downloads immediately and rest of queue fails,
+// so Wait returns partial TotalStat
+//
+// call it after Start (or StartCtx); returned channel is closed on first signal
+// (e.g. for stop of reading input), returned func stops handling of signals
+func (client *StorClient) ShutdownOnSignal(signals ...os.Signal) (<-chan struct{}, func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt}
+	}
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, signals...)
+
+	stopping, stop := client.shutdownOn(sigs)
+
+	return stopping, func() {
+		signal.Stop(sigs)
+		stop()
+	}
+}
+
+func (client *StorClient) shutdownOn(sigs <-chan os.Signal) (<-chan struct{}, func()) {
+	stopping := make(chan struct{})
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		select {
+		case sig := <-sigs:
+			log.Infof("%s - stop enqueueing and wait to queued downloads (repeat to abort)", sig)
+			close(stopping)
+			client.queue.close()
+		case <-done:
+			return
+		}
+
+		select {
+		case sig := <-sigs:
+			log.Warningf("%s - abort downloads", sig)
+			client.cancel()
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return stopping, func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package storclient
+
+import (
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownOn(t *testing.T) {
+	client, err := New(url.URL{}, "", StorClientOpts{})
+	assert.NoError(t, err)
+	client.Start()
+
+	sigs := make(chan os.Signal)
+	stopping, stop := client.shutdownOn(sigs)
+	defer stop()
+
+	sigs <- os.Interrupt
+	<-stopping
+
+	assert.Equal(t, ErrQueueClosed, client.Download(emptyHash), "first signal stops enqueueing")
+	assert.NoError(t, client.ctx.Err(), "in-flight downloads continue")
+
+	sigs <- os.Interrupt
+	select {
+	case <-client.ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("second signal doesn't abort downloads")
+	}
+
+	client.Wait()
+}
+
+func TestShutdownOnStop(t *testing.T) {
+	client, err := New(url.URL{}, "", StorClientOpts{})
+	assert.NoError(t, err)
+
+	sigs := make(chan os.Signal, 1)
+	stopping, stop := client.shutdownOn(sigs)
+	stop()
+	stop()
+
+	sigs <- os.Interrupt
+	select {
+	case <-stopping:
+		t.Fatal("signal is handled after stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
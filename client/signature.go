@@ -0,0 +1,137 @@
+package storclient
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+)
+
+// DefaultSignatureSuffix is default suffix of detached signature (<object url><suffix>)
+const DefaultSignatureSuffix = ".sig"
+
+// maxSignatureSize limits read of signature file
+const maxSignatureSize = 64 * 1024
+
+// ErrSignature is returned (wrapped) if detached signature of object doesn't verify,
+// download with wrong signature isn't retried
+var ErrSignature = errors.New("signature verification failed")
+
+// SignatureVerifier verify detached signature of object (see StorClientOpts.SignatureVerifier)
+//
+// signature is content of <object url><suffix> fetched from same place as object,
+// signed message is digest of object (raw bytes of sha) - content of object is checked against sha
+type SignatureVerifier interface {
+	Verify(sha hashutil.Hash, signature []byte) error
+}
+
+// ContentSignatureVerifier is SignatureVerifier of signature made over content of object (e.g. minisign or PGP),
+// Verify is called with fetched signature (e.g. check of key id) and content is checked by verifier of Content
+// during download, before final rename - content which doesn't match signature is wrong (download is retried)
+type ContentSignatureVerifier interface {
+	SignatureVerifier
+	// Content return verifier of content of object signed by signature
+	Content(signature []byte) (Verifier, error)
+}
+
+// SignatureVerifierFunc is adapter of function to SignatureVerifier
+type SignatureVerifierFunc func(sha hashutil.Hash, signature []byte) error
+
+// Verify call f(sha, signature)
+func (f SignatureVerifierFunc) Verify(sha hashutil.Hash, signature []byte) error {
+	return f(sha, signature)
+}
+
+// ed25519Verifier verify Ed25519 signature (raw 64 bytes or base64) of digest
+type ed25519Verifier struct {
+	publicKey ed25519.PublicKey
+}
+
+// NewEd25519Verifier return verifier of Ed25519 signatures (raw or base64 encoded) made by publicKey
+func NewEd25519Verifier(publicKey ed25519.PublicKey) (SignatureVerifier, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, errors.Errorf("Ed25519 public key has %d bytes, expected %d", len(publicKey), ed25519.PublicKeySize)
+	}
+
+	return ed25519Verifier{publicKey: publicKey}, nil
+}
+
+func (v ed25519Verifier) Verify(sha hashutil.Hash, signature []byte) error {
+	if len(signature) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+		if err != nil {
+			return errors.Wrap(ErrSignature, "signature isn't raw or base64 Ed25519 signature")
+		}
+		signature = decoded
+	}
+
+	if !ed25519.Verify(v.publicKey, sha.ToBytes(), signature) {
+		return errors.Wrapf(ErrSignature, "Ed25519 signature of %s", sha)
+	}
+
+	return nil
+}
+
+// verifySignature fetch detached signature of object from url and verify it,
+// missing signature is StatusError (404 falls back from source to stor like object)
+func (client *StorClient) verifySignature(httpClient httpClient, url string, sha hashutil.Hash) (err error) {
+	resp, err := httpClient.Get(url + client.SignatureSuffix)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); err == nil {
+			err = errClose
+		}
+	}()
+
+	if resp.StatusCode != 200 {
+		return StatusError{Sha: sha, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	signature, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSignatureSize))
+	if err != nil {
+		return err
+	}
+
+	if err := client.SignatureVerifier.Verify(sha, signature); err != nil {
+		return err
+	}
+
+	if verifier, ok := client.SignatureVerifier.(ContentSignatureVerifier); ok {
+		return verifyContentBy(httpClient, verifier, signature)
+	}
+
+	return nil
+}
+
+// verifyContentBy add verifier of content signed by signature to verifier of (wrapped) contextClient
+func verifyContentBy(httpClient httpClient, verifier ContentSignatureVerifier, signature []byte) error {
+	c := findContextClient(httpClient)
+	if c == nil {
+		return errors.New("verification of content signature isn't supported by http client")
+	}
+
+	content, err := verifier.Content(signature)
+	if err != nil {
+		return errors.Wrapf(ErrSignature, "%s", err)
+	}
+
+	factory := c.verifier
+	if factory == nil {
+		factory = HashVerifier
+	}
+	c.verifier = AllVerifiers(factory, func(hashutil.Hash) (Verifier, error) {
+		return content, nil
+	})
+
+	return nil
+}
+
+func isSignatureError(err error) bool {
+	return errors.Cause(err) == ErrSignature
+}
@@ -0,0 +1,131 @@
+package storclient
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignature(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	signed, forged, unsigned := contentHash("signed"), contentHash("forged"), contentHash("unsigned")
+	contents := map[string]string{signed.String(): "signed", forged.String(): "forged", unsigned.String(): "unsigned"}
+	signatures := map[string][]byte{
+		signed.String(): ed25519.Sign(privateKey, signed.ToBytes()),
+		forged.String(): []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signed.ToBytes()))),
+	}
+
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Base(r.URL.Path)
+		requests[name]++
+
+		if strings.HasSuffix(name, ".sig") {
+			signature, ok := signatures[strings.TrimSuffix(name, ".sig")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(signature)
+			return
+		}
+		_, _ = w.Write([]byte(contents[name]))
+	}))
+	defer server.Close()
+
+	verifier, err := NewEd25519Verifier(publicKey)
+	assert.NoError(t, err)
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{Max: 1, Devnull: true, RetryAttempts: 3, RetryDelay: 1, SignatureVerifier: verifier})
+	assert.NoError(t, err)
+
+	results := client.Results()
+	client.Start()
+	for _, sha := range []string{"signed", "forged", "unsigned"} {
+		assert.NoError(t, client.Download(contentHash(sha)))
+	}
+
+	errs := map[string]error{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range results {
+			errs[contents[result.Sha.String()]] = result.Err
+		}
+	}()
+
+	total := client.Wait()
+	<-done
+
+	assert.Equal(t, 1, total.Count)
+	assert.NoError(t, errs["signed"])
+	assert.Contains(t, errs["forged"].Error(), ErrSignature.Error())
+	assert.Error(t, errs["unsigned"])
+
+	assert.Equal(t, 1, requests[signed.String()])
+	assert.Equal(t, 1, requests[forged.String()+".sig"], "wrong signature isn't retried")
+	assert.Zero(t, requests[forged.String()], "object with wrong signature isn't downloaded")
+	assert.Zero(t, requests[unsigned.String()])
+
+	_, err = NewEd25519Verifier(publicKey[:10])
+	assert.Error(t, err)
+}
+
+// downloadSigned download contents (to devnull) with signatures (by content) verified by verifier,
+// return error of download by content
+func downloadSigned(t *testing.T, verifier SignatureVerifier, signatures map[string][]byte, contents ...string) map[string]error {
+	byName := make(map[string]string)
+	for _, content := range contents {
+		byName[contentHash(content).String()] = content
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Base(r.URL.Path)
+		if strings.HasSuffix(name, ".sig") {
+			signature, ok := signatures[byName[strings.TrimSuffix(name, ".sig")]]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(signature)
+			return
+		}
+		_, _ = w.Write([]byte(byName[name]))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, "", StorClientOpts{Devnull: true, RetryAttempts: 1, SignatureVerifier: verifier})
+	assert.NoError(t, err)
+
+	results := client.Results()
+	client.Start()
+	for _, content := range contents {
+		assert.NoError(t, client.Download(contentHash(content)))
+	}
+
+	errs := make(map[string]error)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range results {
+			errs[byName[result.Sha.String()]] = result.Err
+		}
+	}()
+
+	client.Wait()
+	<-done
+
+	return errs
+}
@@ -0,0 +1,96 @@
+package storclient
+
+import (
+	"net/http"
+	"time"
+)
+
+// Snapshot is consistent view of progress of client (see StorClient.Snapshot)
+type Snapshot struct {
+	Time time.Time `json:"time"`
+	// count of shas waiting in queue
+	Queued int `json:"queued"`
+	// count of downloads in progress
+	Active int `json:"active"`
+	// count of processed shas (sum of Downloaded, Skipped, Failed and Metadata)
+	Done       int `json:"done"`
+	Downloaded int `json:"downloaded"`
+	Skipped    int `json:"skipped"`
+	Failed     int `json:"failed"`
+	Metadata   int `json:"metadata"`
+	// count of shas refused by full queue (see StorClient.TryDownload)
+	Backpressure int `json:"backpressure"`
+	// downloads are paused (see StorClient.Pause)
+	Paused bool `json:"paused"`
+	// size of downloaded files
+	BytesDone int64 `json:"bytes_done"`
+	// BytesDone plus expected size (Content-Length) of downloads in progress
+	BytesKnownTotal int64 `json:"bytes_known_total"`
+	// time since Start
+	Elapsed time.Duration `json:"elapsed"`
+	// estimated time to processing of queued and active shas by average rate so far,
+	// 0 means nothing remains or estimate isn't known yet (nothing is done)
+	ETA time.Duration `json:"eta"`
+}
+
+// Snapshot return current progress of client, all numbers are taken at once
+// (under one lock), so they are consistent with each other
+//
+// it's safe to call Snapshot concurrently (e.g. from UI goroutine) after Start
+func (client *StorClient) Snapshot() Snapshot {
+	p := &client.progress
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := client.Clock.Now()
+	snapshot := Snapshot{
+		Time:            now,
+		Queued:          client.queue.jobs.len(),
+		Active:          len(p.active),
+		Done:            p.processed,
+		Downloaded:      p.downloaded,
+		Skipped:         p.skipped,
+		Failed:          p.failed,
+		Metadata:        p.metadata,
+		Backpressure:    client.queue.rejections(),
+		Paused:          client.Paused(),
+		BytesDone:       p.bytesDone,
+		BytesKnownTotal: p.bytesDone,
+	}
+
+	for _, size := range p.active {
+		if size > 0 {
+			snapshot.BytesKnownTotal += size
+		}
+	}
+
+	if !p.startTime.IsZero() {
+		snapshot.Elapsed = now.Sub(p.startTime)
+	}
+
+	if remaining := snapshot.Queued + snapshot.Active; remaining > 0 && snapshot.Done > 0 {
+		snapshot.ETA = time.Duration(float64(snapshot.Elapsed) / float64(snapshot.Done) * float64(remaining))
+	}
+
+	return snapshot
+}
+
+// progressClient is httpClient which records expected size of download (see Snapshot)
+type progressClient struct {
+	httpClient
+	progress *progress
+	sha      string
+}
+
+func (c *progressClient) Get(url string) (*http.Response, error) {
+	resp, err := c.httpClient.Get(url)
+	if resp != nil && resp.StatusCode == http.StatusOK && resp.ContentLength >= 0 {
+		c.progress.expect(c.sha, resp.ContentLength)
+	}
+
+	return resp, err
+}
+
+func (c *progressClient) unwrap() httpClient {
+	return c.httpClient
+}
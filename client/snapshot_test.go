@@ -0,0 +1,77 @@
+package storclient_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, "/") {
+		case sha256Of("first").String():
+			w.Write([]byte("first"))
+		case sha256Of("slow").String():
+			w.Header().Set("Content-Length", "4")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-release
+			w.Write([]byte("slow"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	storUrl, _ := url.Parse(server.URL)
+
+	tmpDir, err := ioutil.TempDir("", "snapshot")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	client, err := storclient.New(*storUrl, tmpDir, storclient.StorClientOpts{Max: 2, RetryAttempts: 1})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(sha256Of("slow")))
+	assert.NoError(t, client.Download(sha256Of("first")))
+	assert.NoError(t, client.Download(sha256Of("missing")))
+
+	var snapshot storclient.Snapshot
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		snapshot = client.Snapshot()
+		if snapshot.Done == 2 && snapshot.Active == 1 && snapshot.BytesKnownTotal == 9 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Equal(t, 0, snapshot.Queued)
+	assert.Equal(t, 1, snapshot.Active)
+	assert.Equal(t, 2, snapshot.Done)
+	assert.Equal(t, 1, snapshot.Downloaded)
+	assert.Equal(t, 1, snapshot.Failed)
+	assert.Equal(t, int64(len("first")), snapshot.BytesDone)
+	assert.Equal(t, int64(len("first")+len("slow")), snapshot.BytesKnownTotal)
+	assert.True(t, snapshot.Elapsed > 0)
+	assert.True(t, snapshot.ETA > 0, "ETA of active download")
+
+	close(release)
+	client.Wait()
+
+	snapshot = client.Snapshot()
+	assert.Equal(t, 0, snapshot.Active)
+	assert.Equal(t, 3, snapshot.Done)
+	assert.Equal(t, 2, snapshot.Downloaded)
+	assert.Equal(t, int64(len("first")+len("slow")), snapshot.BytesKnownTotal)
+	assert.Equal(t, time.Duration(0), snapshot.ETA)
+}
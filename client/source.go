@@ -0,0 +1,43 @@
+package storclient
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"text/template"
+
+	"github.com/avast/hashutil-go"
+)
+
+// Source is place of objects tried before stor (see StorClientOpts.Source),
+// 404 from source means fallback to stor; downloads from source have same verification,
+// retries and stats as downloads from stor
+type Source interface {
+	// ObjectURL return url of object with sha
+	ObjectURL(sha hashutil.Hash) (string, error)
+	// Transport wrap transport of client, e.g. for signing of requests to source
+	// (source without special needs return next)
+	Transport(next http.RoundTripper) http.RoundTripper
+}
+
+// templateSource is S3 bucket available by plain HTTP with path from template (see StorClientOpts.S3URL)
+type templateSource struct {
+	url  *url.URL
+	tmpl *template.Template
+}
+
+func (source templateSource) ObjectURL(sha hashutil.Hash) (string, error) {
+	var pathBytes bytes.Buffer
+	shaStr := sha.String()
+	params := struct{ Sha, FirstShaByte, SecondShaByte, ThirdShaByte string }{shaStr, shaStr[0:2], shaStr[2:4], shaStr[4:6]}
+	if err := source.tmpl.Execute(&pathBytes, params); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/%s", source.url.String(), pathBytes.String()), nil
+}
+
+func (source templateSource) Transport(next http.RoundTripper) http.RoundTripper {
+	return next
+}
@@ -0,0 +1,159 @@
+package storclient
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// progress is continuously updated state of processing, it's safe for concurrent use
+type progress struct {
+	lock        sync.Mutex
+	startTime   time.Time
+	lastSuccess time.Time
+	processed   int
+	failed      int
+	downloaded  int
+	skipped     int
+	metadata    int
+	bytesDone   int64
+	// started downloads with expected size (-1 if isn't known yet)
+	active map[string]int64
+}
+
+func (p *progress) start(now time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.startTime = now
+}
+
+// started record start of download of sha
+// elapsed return time since start (0 if isn't started)
+func (p *progress) elapsed(now time.Time) time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.startTime.IsZero() {
+		return 0
+	}
+
+	return now.Sub(p.startTime)
+}
+
+func (p *progress) started(sha string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.active == nil {
+		p.active = make(map[string]int64)
+	}
+	p.active[sha] = -1
+}
+
+// expect record expected size of started download of sha
+func (p *progress) expect(sha string, size int64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if _, ok := p.active[sha]; ok {
+		p.active[sha] = size
+	}
+}
+
+func (p *progress) update(stat DownStat, now time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.processed++
+	switch stat.Status {
+	case DOWN_OK:
+		p.lastSuccess = now
+		p.downloaded++
+		p.bytesDone += stat.Size
+	case DOWN_FAIL:
+		p.failed++
+	case DOWN_SKIP:
+		p.skipped++
+	case DOWN_METADATA:
+		p.metadata++
+	}
+
+	// skipped sha can be downloading in other worker
+	if stat.Status != DOWN_SKIP {
+		delete(p.active, stat.sha)
+	}
+}
+
+// StatusFileContent is JSON content of status file (see StorClientOpts.StatusFile)
+type StatusFileContent struct {
+	Time        time.Time  `json:"time"`
+	LastSuccess *time.Time `json:"last_success"`
+	QueueDepth  int        `json:"queue_depth"`
+	Processed   int        `json:"processed"`
+	Failed      int        `json:"failed"`
+	FailureRate float64    `json:"failure_rate"`
+}
+
+func (client *StorClient) statusFileContent() StatusFileContent {
+	client.progress.lock.Lock()
+	defer client.progress.lock.Unlock()
+
+	content := StatusFileContent{
+		Time:       client.Clock.Now(),
+		QueueDepth: client.queue.jobs.len(),
+		Processed:  client.progress.processed,
+		Failed:     client.progress.failed,
+	}
+
+	if !client.progress.lastSuccess.IsZero() {
+		lastSuccess := client.progress.lastSuccess
+		content.LastSuccess = &lastSuccess
+	}
+
+	if content.Processed > 0 {
+		content.FailureRate = float64(content.Failed) / float64(content.Processed)
+	}
+
+	return content
+}
+
+// writeStatusFile write status file atomically (via temp file and rename)
+func (client *StorClient) writeStatusFile() error {
+	content, err := json.Marshal(client.statusFileContent())
+	if err != nil {
+		return err
+	}
+
+	temp := client.StatusFile + ".temp"
+	if err := ioutil.WriteFile(temp, content, 0644); err != nil {
+		return errors.Wrapf(err, "Write status file %s fail", temp)
+	}
+
+	return errors.Wrapf(os.Rename(temp, client.StatusFile), "Rename status file %s fail", temp)
+}
+
+// statusFileWriter write status file every StatusInterval until stop is closed
+func (client *StorClient) statusFileWriter(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	for {
+		if err := client.writeStatusFile(); err != nil {
+			log.Warning(err)
+		}
+
+		select {
+		case <-client.Clock.After(client.StatusInterval):
+		case <-stop:
+			if err := client.writeStatusFile(); err != nil {
+				log.Warning(err)
+			}
+			return
+		}
+	}
+}
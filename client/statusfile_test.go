@@ -0,0 +1,47 @@
+package storclient
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusFile(t *testing.T) {
+	tempdir, err := pathutil.NewTempDir(pathutil.TempOpt{})
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, tempdir.RemoveTree())
+	}()
+
+	statusFile := filepath.Join(tempdir.Canonpath(), "status.json")
+
+	client, err := New(url.URL{}, tempdir.Canonpath(), StorClientOpts{StatusFile: statusFile})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultStatusInterval, client.StatusInterval)
+
+	now := time.Now()
+	client.progress.update(DownStat{Status: DOWN_OK}, now)
+	client.progress.update(DownStat{Status: DOWN_FAIL}, now)
+	client.progress.update(DownStat{Status: DOWN_SKIP}, now)
+	client.progress.update(DownStat{Status: DOWN_FAIL}, now)
+
+	assert.NoError(t, client.writeStatusFile())
+
+	content, err := ioutil.ReadFile(statusFile)
+	assert.NoError(t, err)
+
+	var status StatusFileContent
+	assert.NoError(t, json.Unmarshal(content, &status))
+	assert.Equal(t, 4, status.Processed)
+	assert.Equal(t, 2, status.Failed)
+	assert.Equal(t, 0.5, status.FailureRate)
+	if assert.NotNil(t, status.LastSuccess) {
+		assert.True(t, now.Equal(*status.LastSuccess))
+	}
+}
@@ -0,0 +1,50 @@
+package storclient
+
+import (
+	"io"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+)
+
+// DownloadStream return reader of content of sha streamed from stor (see DownloadTo), nothing is written to disk
+//
+// content is verified at its end - Read returns io.EOF only for content matching sha, otherwise
+// error of download; Close before end aborts download and returns nil, Close after all content
+// was read (without EOF) waits for verification and returns its error
+func (client *StorClient) DownloadStream(sha hashutil.Hash) (io.ReadCloser, error) {
+	if len(sha.ToBytes()) != client.HashAlgorithm.Size() {
+		return nil, errors.Wrapf(ErrInvalidHash, "%s isn't %s hash", sha, client.HashAlgorithm)
+	}
+
+	pr, pw := io.Pipe()
+	stream := &downloadStream{PipeReader: pr, done: make(chan struct{})}
+
+	go func() {
+		defer close(stream.done)
+
+		_, stream.err = client.DownloadTo(sha, pw)
+		pw.CloseWithError(stream.err)
+	}()
+
+	return stream, nil
+}
+
+// downloadStream is reader of DownloadStream
+type downloadStream struct {
+	*io.PipeReader
+	done chan struct{}
+	err  error
+}
+
+func (s *downloadStream) Close() error {
+	s.PipeReader.Close()
+	<-s.done
+
+	if errors.Cause(lastAttemptError(s.err)) == io.ErrClosedPipe {
+		// aborted by Close
+		return nil
+	}
+
+	return s.err
+}
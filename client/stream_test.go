@@ -0,0 +1,71 @@
+package storclient
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadStream(t *testing.T) {
+	content := strings.Repeat("0123456789", 100000)
+	corrupted := contentHash("corrupted")
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if strings.TrimPrefix(r.URL.Path, "/") == corrupted.String() {
+			w.Write([]byte("something else"))
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{RetryAttempts: 3, RetryDelay: 1})
+	assert.NoError(t, err)
+
+	t.Run("verified content", func(t *testing.T) {
+		stream, err := client.DownloadStream(contentHash(content))
+		assert.NoError(t, err)
+
+		downloaded, err := ioutil.ReadAll(stream)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(downloaded))
+		assert.NoError(t, stream.Close())
+	})
+
+	t.Run("corrupted content fails at end", func(t *testing.T) {
+		stream, err := client.DownloadStream(corrupted)
+		assert.NoError(t, err)
+
+		downloaded, err := ioutil.ReadAll(stream)
+		assert.Error(t, err)
+		assert.Equal(t, "something else", string(downloaded))
+		assert.Error(t, stream.Close())
+	})
+
+	t.Run("close before end aborts download", func(t *testing.T) {
+		requests = 0
+		stream, err := client.DownloadStream(contentHash(content))
+		assert.NoError(t, err)
+
+		_, err = io.ReadFull(stream, make([]byte, 10))
+		assert.NoError(t, err)
+		assert.NoError(t, stream.Close())
+		assert.Equal(t, 1, requests, "write to closed stream isn't retried")
+	})
+
+	md5Client, err := New(*serverURL, os.TempDir(), StorClientOpts{HashAlgorithm: MD5})
+	assert.NoError(t, err)
+	_, err = md5Client.DownloadStream(contentHash(content))
+	assert.Equal(t, ErrInvalidHash, errors.Cause(err))
+}
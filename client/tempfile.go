@@ -0,0 +1,74 @@
+package storclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+)
+
+// tempFiles is naming and place of temp (staging) files of downloads
+// (see StorClientOpts.TempDir, TempPrefix and TempSuffix)
+type tempFiles struct {
+	// dir of temp files, empty means next to final file
+	dir    string
+	prefix string
+	suffix string
+}
+
+// path return temp file of download of expectedSha to final path,
+// kind distinguishes temp files of different ways of download (e.g. ".parts")
+func (temp tempFiles) path(final pathutil.Path, expectedSha hashutil.Hash, kind string) (pathutil.Path, error) {
+	dir := temp.dir
+	if dir == "" {
+		dir = final.Parent().Canonpath()
+	}
+
+	suffix := temp.suffix
+	if suffix == "" {
+		suffix = DefaultTempSuffix
+	}
+
+	return pathutil.New(dir, fmt.Sprintf("%s%s%s%s", temp.prefix, expectedSha, kind, suffix))
+}
+
+// stagingOf return temp files of downloads of tenant - TempDir is used only for download dir of client,
+// tenants stage files next to final files (their dirs can be on other filesystem)
+func (client *StorClient) stagingOf(tenant *Tenant) tempFiles {
+	staging := client.staging
+	if tenant.downloadDir != client.downloadDir {
+		staging.dir = ""
+	}
+
+	return staging
+}
+
+// checkSameFilesystem check that file from tempDir can be renamed to downloadDir (rename is atomic
+// only inside one filesystem), both dirs are created if they don't exist
+func checkSameFilesystem(tempDir, downloadDir string) error {
+	for _, dir := range []string{tempDir, downloadDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	probe, err := ioutil.TempFile(tempDir, "probe")
+	if err != nil {
+		return err
+	}
+	if err := probe.Close(); err != nil {
+		return err
+	}
+
+	renamed := filepath.Join(downloadDir, filepath.Base(probe.Name()))
+	if err := os.Rename(probe.Name(), renamed); err != nil {
+		_ = os.Remove(probe.Name())
+		return errors.Wrapf(err, "temp dir %s must be on same filesystem as download dir %s", tempDir, downloadDir)
+	}
+
+	return os.Remove(renamed)
+}
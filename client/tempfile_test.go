@@ -0,0 +1,72 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTempFilesPath(t *testing.T) {
+	final, err := pathutil.New("/data", "ab", emptyHash.String())
+	assert.NoError(t, err)
+
+	temp, err := tempFiles{}.path(final, emptyHash, "")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("/data", "ab", emptyHash.String()+".temp"), temp.Canonpath())
+
+	temp, err = tempFiles{dir: "/staging", prefix: ".", suffix: ".part"}.path(final, emptyHash, ".parts")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("/staging", "."+emptyHash.String()+".parts.part"), temp.Canonpath())
+}
+
+func TestTempDir(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tempdir")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	downloadDir, stagingDir := filepath.Join(tmpDir, "download"), filepath.Join(tmpDir, "staging")
+	sha := contentHash("content")
+
+	var staged, visible []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		staged = dirNames(t, stagingDir)
+		visible = dirNames(t, downloadDir)
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, downloadDir, StorClientOpts{RetryAttempts: 1, TempDir: stagingDir, TempPrefix: ".", TempSuffix: ".part"})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(sha))
+	total := client.Wait()
+
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, []string{"." + sha.String() + ".part"}, staged, "download is staged in temp dir")
+	assert.Empty(t, visible, "download dir doesn't see temp file")
+	assert.Equal(t, []string{sha.String()}, dirNames(t, downloadDir))
+	assert.Empty(t, dirNames(t, stagingDir))
+
+	_, err = New(*serverURL, downloadDir, StorClientOpts{TempDir: stagingDir, TempSuffix: "/x"})
+	assert.Error(t, err)
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names
+}
@@ -0,0 +1,109 @@
+package storclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/avast/hashutil-go"
+)
+
+// Tenant is derived client which shares worker pool and connection pool with parent StorClient,
+// but downloads to own directory from own storage url with own request headers (e.g. credentials)
+//
+// all other options (suffix, S3, retry...) are inherited from parent
+// and downloads of tenant are counted in TotalStat of parent
+type Tenant struct {
+	parent           *StorClient
+	downloadDir      string
+	mirrors          *storMirrors
+	header           http.Header
+	currentDownloads DedupSet
+}
+
+// downloadJob is one item of download queue
+type downloadJob struct {
+	sha hashutil.Hash
+	// nil means parent client
+	tenant *Tenant
+	// path of file for upload (sha isn't set for upload)
+	uploadPath string
+	// nil means plain job without batch
+	batch *Batch
+	// higher is served first, equal priorities in FIFO order
+	priority int
+	// nil means plain job without producer token (see StorClientOpts.FairQueuing)
+	producer *Producer
+	// downloaded object is bundle of objects (see DownloadBundle)
+	bundle bool
+	// submission order (assigned by queue)
+	seq int64
+	// ctx of DownloadCtx, its cancel aborts download of job
+	ctx context.Context
+}
+
+// cancelable return true if job has own ctx which can be canceled (see DownloadCtx)
+func (job downloadJob) cancelable() bool {
+	return job.ctx != nil && job.ctx.Done() != nil
+}
+
+// err return error of done client ctx or of done ctx of job
+func (job downloadJob) err(clientCtx context.Context) error {
+	if err := clientCtx.Err(); err != nil {
+		return err
+	}
+	if job.ctx != nil {
+		return job.ctx.Err()
+	}
+
+	return nil
+}
+
+// WithTenant create derived client (see Tenant)
+func (client *StorClient) WithTenant(downloadDir string, storageUrl url.URL, header http.Header) *Tenant {
+	if client.authTransport != nil {
+		client.authTransport.allow(storageUrl)
+	}
+
+	return &Tenant{
+		parent:           client,
+		downloadDir:      downloadDir,
+		mirrors:          newStorMirrors(storageUrl, nil, MirrorFailover),
+		header:           header,
+		currentDownloads: client.tenantDedupSet(),
+	}
+}
+
+// tenantDedupSet return own in-memory DedupSet of tenant
+func (client *StorClient) tenantDedupSet() DedupSet {
+	if client.Overwrite {
+		return newOverwriteDedupSet(&currentDownloads{})
+	}
+
+	return &currentDownloads{}
+}
+
+// Download add sha to download queue of parent client
+func (tenant *Tenant) Download(sha hashutil.Hash) error {
+	return tenant.DownloadCtx(context.Background(), sha)
+}
+
+// DownloadCtx add sha to download queue of parent client (see StorClient.DownloadCtx)
+func (tenant *Tenant) DownloadCtx(ctx context.Context, sha hashutil.Hash) error {
+	return tenant.parent.push(ctx, downloadJob{sha: sha, tenant: tenant})
+}
+
+// httpClientFunc return http client of parent with tenant headers
+func (tenant *Tenant) httpClientFunc() httpClient {
+	return tenant.parent.buildHTTPClient(tenant.header)
+}
+
+// newRootTenant return tenant which represents client itself
+func (client *StorClient) newRootTenant() *Tenant {
+	return &Tenant{
+		parent:           client,
+		downloadDir:      client.downloadDir,
+		mirrors:          newStorMirrors(client.storageUrl, client.Mirrors, client.MirrorPolicy),
+		currentDownloads: client.currentDownloads,
+	}
+}
@@ -0,0 +1,53 @@
+package storclient_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenant(t *testing.T) {
+	sample := "sample"
+	sha := sha256Of(sample)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/tenant/") && r.Header.Get("Authorization") != "Bearer tenant" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(sample))
+	}))
+	defer server.Close()
+
+	parentDir, err := ioutil.TempDir("", "parent")
+	assert.NoError(t, err)
+	defer os.RemoveAll(parentDir)
+	tenantDir, err := ioutil.TempDir("", "tenant")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tenantDir)
+
+	serverURL, _ := url.Parse(server.URL)
+	tenantURL, _ := url.Parse(server.URL + "/tenant")
+
+	client, err := storclient.New(*serverURL, parentDir, storclient.StorClientOpts{RetryAttempts: 1})
+	assert.NoError(t, err)
+
+	tenant := client.WithTenant(tenantDir, *tenantURL, http.Header{"Authorization": []string{"Bearer tenant"}})
+
+	client.Start()
+	assert.NoError(t, client.Download(sha))
+	assert.NoError(t, tenant.Download(sha))
+	total := client.Wait()
+
+	assert.Equal(t, 2, total.Count, "same sha is downloaded to both directories")
+	assert.FileExists(t, filepath.Join(parentDir, sha.String()))
+	assert.FileExists(t, filepath.Join(tenantDir, sha.String()))
+}
@@ -0,0 +1,159 @@
+package storclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// tokenBucket limits rate of bytes - tokens are refilled continuously up to burst (one second of rate),
+// consumed tokens can go to debt which is paid by waiting
+type tokenBucket struct {
+	lock   sync.Mutex
+	clock  Clock
+	rate   int64
+	tokens int64
+	last   time.Time
+}
+
+// newTokenBucket return bucket limited to rate bytes per second or nil for rate <= 0 (without limit)
+func newTokenBucket(clock Clock, rate int64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &tokenBucket{clock: clock, rate: rate, tokens: rate, last: clock.Now()}
+}
+
+// take consume n tokens and return how long caller has to wait
+func (b *tokenBucket) take(n int64) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.rate <= 0 {
+		return 0
+	}
+
+	now := b.clock.Now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		// last is advanced only by time of whole tokens, so fraction isn't lost by small reads
+		refill := int64(elapsed.Seconds() * float64(b.rate))
+		b.tokens += refill
+		if b.tokens >= b.rate {
+			b.tokens = b.rate
+			b.last = now
+		} else {
+			b.last = b.last.Add(time.Duration(float64(refill) / float64(b.rate) * float64(time.Second)))
+		}
+	}
+
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(float64(-b.tokens) / float64(b.rate) * float64(time.Second))
+}
+
+// setRate change rate of bucket (rate <= 0 means without limit), burst is refilled to new rate
+func (b *tokenBucket) setRate(rate int64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.rate = rate
+	b.tokens = rate
+	b.last = b.clock.Now()
+}
+
+// burst return max count of tokens at once (0 means without limit)
+func (b *tokenBucket) burst() int64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.rate <= 0 {
+		return 0
+	}
+
+	return b.rate
+}
+
+// throttledClient limits rate of reading of response bodies by buckets (global and per worker)
+type throttledClient struct {
+	httpClient
+	ctx     context.Context
+	clock   Clock
+	buckets []*tokenBucket
+}
+
+func (c *throttledClient) Get(url string) (*http.Response, error) {
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.Body = &throttledBody{ReadCloser: resp.Body, client: c}
+
+	return resp, nil
+}
+
+func (c *throttledClient) unwrap() httpClient {
+	return c.httpClient
+}
+
+type throttledBody struct {
+	io.ReadCloser
+	client *throttledClient
+}
+
+func (b *throttledBody) Read(p []byte) (int, error) {
+	// read at most one burst of the slowest bucket at once
+	for _, bucket := range b.client.buckets {
+		if burst := bucket.burst(); burst > 0 && int64(len(p)) > burst {
+			p = p[:burst]
+		}
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if n <= 0 {
+		return n, err
+	}
+
+	var wait time.Duration
+	for _, bucket := range b.client.buckets {
+		if d := bucket.take(int64(n)); d > wait {
+			wait = d
+		}
+	}
+
+	if wait > 0 {
+		select {
+		case <-b.client.clock.After(wait):
+		case <-b.client.ctx.Done():
+			return n, b.client.ctx.Err()
+		}
+	}
+
+	return n, err
+}
+
+// throttle wrap httpClient by rate limits (global bucket of client and bucket of worker)
+func (client *StorClient) throttle(httpClient httpClient, workerBucket *tokenBucket) httpClient {
+	buckets := make([]*tokenBucket, 0, 2)
+	for _, bucket := range []*tokenBucket{client.globalBucket, workerBucket} {
+		if bucket != nil {
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	if len(buckets) == 0 {
+		return httpClient
+	}
+
+	return &throttledClient{httpClient: httpClient, ctx: client.ctx, clock: client.Clock, buckets: buckets}
+}
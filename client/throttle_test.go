@@ -0,0 +1,69 @@
+package storclient
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)}
+
+	assert.Nil(t, newTokenBucket(clock, 0), "without limit")
+
+	bucket := newTokenBucket(clock, 100)
+	assert.Equal(t, time.Duration(0), bucket.take(100), "burst of one second is free")
+	assert.Equal(t, 500*time.Millisecond, bucket.take(50))
+
+	clock.Sleep(500 * time.Millisecond)
+	assert.Equal(t, time.Duration(0), bucket.take(0), "debt is paid")
+
+	clock.Sleep(10 * time.Second)
+	assert.Equal(t, time.Duration(0), bucket.take(100))
+	assert.Equal(t, time.Second, bucket.take(100), "tokens are refilled up to burst only")
+}
+
+func TestThrottledClientSmallReads(t *testing.T) {
+	start := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	client := &StorClient{ctx: context.Background()}
+	client.Clock = clock
+
+	// each read refills fraction of token only
+	httpClient := client.throttle(&bodyClientMock{body: strings.Repeat("x", 300)}, newTokenBucket(clock, 3))
+
+	resp, err := httpClient.Get("http://blabla")
+	assert.NoError(t, err)
+	body, err := ioutil.ReadAll(iotest.OneByteReader(resp.Body))
+	assert.NoError(t, err)
+	assert.Len(t, body, 300)
+
+	elapsed := clock.Now().Sub(start)
+	assert.True(t, elapsed >= 98*time.Second && elapsed <= 100*time.Second, "297 bytes over burst by 3B/s take 99s, not %s", elapsed)
+}
+
+func TestThrottledClient(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	client := &StorClient{ctx: context.Background()}
+	client.Clock = clock
+	client.globalBucket = newTokenBucket(clock, 100)
+
+	workerBucket := newTokenBucket(clock, 50)
+	httpClient := client.throttle(&bodyClientMock{body: strings.Repeat("x", 300)}, workerBucket)
+
+	resp, err := httpClient.Get("http://blabla")
+	assert.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Len(t, body, 300)
+
+	start := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 5*time.Second, clock.Now().Sub(start), "300 bytes by slower (worker) limit 50B/s with 50B burst")
+
+	assert.Equal(t, &bodyClientMock{body: strings.Repeat("x", 300)}, (&StorClient{}).throttle(&bodyClientMock{body: strings.Repeat("x", 300)}, nil), "without limits")
+}
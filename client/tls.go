@@ -0,0 +1,51 @@
+package storclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// buildTLSConfig return TLS config of transport - base config (cloned) extended
+// by CA bundle and client certificate (mutual TLS) loaded from PEM files
+//
+// nil is returned if nothing is set (default TLS of transport)
+func buildTLSConfig(base *tls.Config, caFile, certFile, keyFile string) (*tls.Config, error) {
+	if base == nil && caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Read of CA bundle %s fail", caFile)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("CA bundle %s doesn't contain any PEM certificate", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("both client certificate and key files must be set")
+		}
+
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Load of client certificate %s (key %s) fail", certFile, keyFile)
+		}
+		config.Certificates = append(config.Certificates, cert)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,96 @@
+package storclient_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeClientCert generate self-signed client certificate and write it (and key) as PEM to dir
+func writeClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "stor-client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	assert.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	return cert, certFile, keyFile
+}
+
+func TestMutualTLS(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tls")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	clientCert, certFile, keyFile := writeClientCert(t, tmpDir)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(tmpDir, "ca.pem")
+	assert.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+
+	serverURL, _ := url.Parse(server.URL)
+
+	download := func(opts storclient.StorClientOpts) storclient.TotalStat {
+		opts.Devnull = true
+		opts.RetryAttempts = 1
+		client, err := storclient.New(*serverURL, tmpDir, opts)
+		assert.NoError(t, err)
+
+		client.Start()
+		assert.NoError(t, client.Download(sha256Of("first")))
+		return client.Wait()
+	}
+
+	assert.Equal(t, 1, download(storclient.StorClientOpts{TLSCAFile: caFile, TLSCertFile: certFile, TLSKeyFile: keyFile}).Count)
+	assert.Equal(t, 1, download(storclient.StorClientOpts{TLSCAFile: caFile}).Failed(), "without client certificate")
+	assert.Equal(t, 1, download(storclient.StorClientOpts{TLSCertFile: certFile, TLSKeyFile: keyFile}).Failed(), "unknown CA of server")
+
+	_, err = storclient.New(*serverURL, tmpDir, storclient.StorClientOpts{TLSCertFile: certFile})
+	assert.Error(t, err, "key is missing")
+	_, err = storclient.New(*serverURL, tmpDir, storclient.StorClientOpts{TLSCAFile: keyFile})
+	assert.Error(t, err, "CA bundle without certificate")
+}
@@ -0,0 +1,227 @@
+package storclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrTLSPolicy is cause of error of connection which doesn't satisfy TLSPolicy
+var ErrTLSPolicy = errors.New("TLS policy violation")
+
+// TLSPolicy is allowed TLS versions and cipher suites of connections to stor (and S3)
+//
+// policy is applied to TLS configuration and each connection is checked again,
+// connection which doesn't satisfy policy fails with error caused by ErrTLSPolicy
+type TLSPolicy struct {
+	// min allowed version e.g. tls.VersionTLS12
+	// default (0) means default min version of crypto/tls
+	MinVersion uint16
+	// max allowed version
+	// default (0) means default max version of crypto/tls
+	MaxVersion uint16
+	// allowed cipher suites of TLS 1.0-1.2 (cipher suites of TLS 1.3 aren't configurable)
+	// default (nil) means default cipher suites of crypto/tls
+	CipherSuites []uint16
+}
+
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "1.0",
+	tls.VersionTLS11: "1.1",
+	tls.VersionTLS12: "1.2",
+	tls.VersionTLS13: "1.3",
+}
+
+// cipher suites of TLS 1.0-1.2 by name (for CLI and error messages)
+var tlsCipherSuiteNames = map[string]uint16{
+	"TLS_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384": tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":   tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":  tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":    tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_RSA_WITH_AES_128_CBC_SHA256":         tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA":     tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_RSA_WITH_3DES_EDE_CBC_SHA":           tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+}
+
+// ParseTLSVersion parse TLS version in format 1.0, 1.1, 1.2 or 1.3
+func ParseTLSVersion(s string) (uint16, error) {
+	for version, name := range tlsVersionNames {
+		if name == s {
+			return version, nil
+		}
+	}
+
+	return 0, errors.Errorf("unsupported TLS version %q", s)
+}
+
+// ParseTLSCipherSuite parse name of cipher suite e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+func ParseTLSCipherSuite(s string) (uint16, error) {
+	if suite, ok := tlsCipherSuiteNames[strings.ToUpper(s)]; ok {
+		return suite, nil
+	}
+
+	return 0, errors.Errorf("unsupported TLS cipher suite %q", s)
+}
+
+func tlsVersionName(version uint16) string {
+	if name, ok := tlsVersionNames[version]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("0x%04x", version)
+}
+
+func tlsCipherSuiteName(suite uint16) string {
+	for name, id := range tlsCipherSuiteNames {
+		if id == suite {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("0x%04x", suite)
+}
+
+func (policy *TLSPolicy) validate() error {
+	for _, version := range []uint16{policy.MinVersion, policy.MaxVersion} {
+		if _, ok := tlsVersionNames[version]; version != 0 && !ok {
+			return errors.Errorf("unsupported TLS version 0x%04x in TLS policy", version)
+		}
+	}
+
+	if policy.MinVersion != 0 && policy.MaxVersion != 0 && policy.MinVersion > policy.MaxVersion {
+		return errors.Errorf("min TLS version %s is greater than max TLS version %s", tlsVersionName(policy.MinVersion), tlsVersionName(policy.MaxVersion))
+	}
+
+	return nil
+}
+
+// apply return clone of config (or new config) restricted by policy
+func (policy *TLSPolicy) apply(config *tls.Config) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+
+	if policy.MinVersion != 0 {
+		config.MinVersion = policy.MinVersion
+	}
+	if policy.MaxVersion != 0 {
+		config.MaxVersion = policy.MaxVersion
+	}
+	if len(policy.CipherSuites) > 0 {
+		config.CipherSuites = policy.CipherSuites
+	}
+
+	return config
+}
+
+// check return error caused by ErrTLSPolicy if connection doesn't satisfy policy
+func (policy *TLSPolicy) check(state *tls.ConnectionState) error {
+	if policy.MinVersion != 0 && state.Version < policy.MinVersion {
+		return errors.Wrapf(ErrTLSPolicy, "TLS version %s is lower than %s", tlsVersionName(state.Version), tlsVersionName(policy.MinVersion))
+	}
+	if policy.MaxVersion != 0 && state.Version > policy.MaxVersion {
+		return errors.Wrapf(ErrTLSPolicy, "TLS version %s is greater than %s", tlsVersionName(state.Version), tlsVersionName(policy.MaxVersion))
+	}
+
+	if len(policy.CipherSuites) > 0 && state.Version != tls.VersionTLS13 {
+		for _, suite := range policy.CipherSuites {
+			if suite == state.CipherSuite {
+				return nil
+			}
+		}
+		return errors.Wrapf(ErrTLSPolicy, "TLS cipher suite %s isn't allowed", tlsCipherSuiteName(state.CipherSuite))
+	}
+
+	return nil
+}
+
+func (policy *TLSPolicy) String() string {
+	s := fmt.Sprintf("TLS %s-%s", tlsVersionName(policy.MinVersion), tlsVersionName(policy.MaxVersion))
+	if policy.MinVersion == 0 {
+		s = fmt.Sprintf("TLS up to %s", tlsVersionName(policy.MaxVersion))
+	}
+	if policy.MaxVersion == 0 {
+		s = fmt.Sprintf("TLS %s or newer", tlsVersionName(policy.MinVersion))
+	}
+	if policy.MinVersion == 0 && policy.MaxVersion == 0 {
+		s = "TLS"
+	}
+
+	if len(policy.CipherSuites) > 0 {
+		names := make([]string, 0, len(policy.CipherSuites))
+		for _, suite := range policy.CipherSuites {
+			names = append(names, tlsCipherSuiteName(suite))
+		}
+		s += " with " + strings.Join(names, ", ")
+	}
+
+	return s
+}
+
+// TLS alerts of failed negotiation of version or cipher suite
+const (
+	tlsAlertHandshakeFailure tls.AlertError = 40
+	tlsAlertProtocolVersion  tls.AlertError = 70
+)
+
+// isTLSNegotiationError return true if err is TLS alert protocol_version or handshake_failure,
+// other TLS errors (e.g. untrusted certificate) aren't caused by policy
+func isTLSNegotiationError(err error) bool {
+	var alertErr tls.AlertError
+	if errors.As(err, &alertErr) {
+		return alertErr == tlsAlertProtocolVersion || alertErr == tlsAlertHandshakeFailure
+	}
+
+	// alert sent by peer is net.OpError of unexported alert type of crypto/tls
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "remote error" {
+		alert := opErr.Err.Error()
+		return alert == tlsAlertProtocolVersion.Error() || alert == tlsAlertHandshakeFailure.Error()
+	}
+
+	return false
+}
+
+// tlsPolicyTransport check TLS connections of responses by policy
+// and turn TLS negotiation errors to policy errors
+type tlsPolicyTransport struct {
+	next   http.RoundTripper
+	policy *TLSPolicy
+}
+
+func (t *tlsPolicyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		if isTLSNegotiationError(err) {
+			return nil, errors.Wrapf(ErrTLSPolicy, "TLS handshake with %s fail (policy %s): %s", req.URL.Host, t.policy, err)
+		}
+		return nil, err
+	}
+
+	if resp.TLS != nil {
+		if err := t.policy.check(resp.TLS); err != nil {
+			resp.Body.Close()
+			return nil, errors.Wrapf(err, "Connection to %s (policy %s)", req.URL.Host, t.policy)
+		}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,85 @@
+package storclient_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSPolicy(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+
+	trusted := server.Client().Transport.(*http.Transport).TLSClientConfig
+	download := func(tlsConfig *tls.Config, policy *storclient.TLSPolicy) (storclient.TotalStat, error) {
+		client, err := storclient.New(*serverURL, os.TempDir(), storclient.StorClientOpts{Devnull: true, RetryAttempts: 1, TLS: tlsConfig, TLSPolicy: policy})
+		assert.NoError(t, err)
+
+		results := client.Results()
+		client.Start()
+		assert.NoError(t, client.Download(sha256Of("first")))
+
+		var resultErr error
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for result := range results {
+				resultErr = result.Err
+			}
+		}()
+
+		total := client.Wait()
+		<-done
+
+		return total, resultErr
+	}
+
+	total, err := download(trusted, &storclient.TLSPolicy{MinVersion: tls.VersionTLS12, CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total.Count)
+
+	total, err = download(trusted, &storclient.TLSPolicy{MinVersion: tls.VersionTLS13})
+	assert.Equal(t, 1, total.Failed())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), storclient.ErrTLSPolicy.Error())
+	}
+
+	// untrusted certificate isn't policy violation
+	total, err = download(&tls.Config{}, &storclient.TLSPolicy{MinVersion: tls.VersionTLS12})
+	assert.Equal(t, 1, total.Failed())
+	if assert.Error(t, err) {
+		assert.NotContains(t, err.Error(), storclient.ErrTLSPolicy.Error())
+		assert.Contains(t, err.Error(), "certificate")
+	}
+
+	_, err = storclient.New(*serverURL, os.TempDir(), storclient.StorClientOpts{TLSPolicy: &storclient.TLSPolicy{MinVersion: tls.VersionTLS13, MaxVersion: tls.VersionTLS12}})
+	assert.Error(t, err, "min is greater than max")
+	_, err = storclient.New(*serverURL, os.TempDir(), storclient.StorClientOpts{TLSPolicy: &storclient.TLSPolicy{MinVersion: 0x1234}})
+	assert.Error(t, err, "unknown version")
+}
+
+func TestParseTLSPolicy(t *testing.T) {
+	version, err := storclient.ParseTLSVersion("1.2")
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), version)
+	_, err = storclient.ParseTLSVersion("1.4")
+	assert.Error(t, err)
+
+	suite, err := storclient.ParseTLSCipherSuite("tls_ecdhe_rsa_with_aes_128_gcm_sha256")
+	assert.NoError(t, err)
+	assert.Equal(t, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, suite)
+	_, err = storclient.ParseTLSCipherSuite("TLS_NULL")
+	assert.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package storclient
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/avast/hashutil-go"
+)
+
+// Tracer start spans of batch and download attempts (see StorClientOpts.Tracer)
+//
+// it's adapter of tracing library of caller, so stor-client doesn't depend on it, e.g. for OpenTelemetry:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, storclient.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name)
+//		return ctx, otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) SetAttribute(key string, value interface{}) {
+//		s.SetAttributes(attribute.String(key, fmt.Sprint(value)))
+//	}
+//
+//	opts.Tracer = otelTracer{provider.Tracer("stor-client")}
+type Tracer interface {
+	// Start start span named name as child of span in ctx,
+	// returned context (with new span) must be derived from ctx
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is one traced operation
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+const (
+	// BatchSpanName is name of span from Start to Wait
+	BatchSpanName = "stor-client.batch"
+	// AttemptSpanName is name of span of one download attempt (child of BatchSpanName)
+	AttemptSpanName = "stor-client.attempt"
+)
+
+// tracing keep span of batch
+type tracing struct {
+	// context of batch span (client.ctx without Tracer)
+	ctx   context.Context
+	batch Span
+}
+
+// startBatchSpan start span of whole batch (see Start)
+func (client *StorClient) startBatchSpan() {
+	client.tracing.ctx = client.ctx
+	if client.Tracer == nil {
+		return
+	}
+
+	client.tracing.ctx, client.tracing.batch = client.Tracer.Start(client.ctx, BatchSpanName)
+}
+
+// endBatchSpan end span of batch with its totals (see Wait)
+func (client *StorClient) endBatchSpan(total TotalStat) {
+	span := client.tracing.batch
+	if span == nil {
+		return
+	}
+
+	span.SetAttribute("stor.expected", total.expectedDownloadCount)
+	span.SetAttribute("stor.downloaded", total.Count)
+	span.SetAttribute("stor.skipped", total.Skip)
+	span.SetAttribute("stor.failed", total.Fail)
+	span.SetAttribute("stor.bytes", total.Size)
+	span.End()
+}
+
+// traceAttempt wrap attempt of download of sha in span with sha, attempt number, status code of last response
+// and downloaded bytes, attempt gets func which binds requests of http client to span
+func (client *StorClient) traceAttempt(sha hashutil.Hash, attempts *int, size *int64, attempt func(trace func(httpClient) httpClient) error) func() error {
+	if client.Tracer == nil {
+		return func() error {
+			return attempt(func(httpClient httpClient) httpClient { return httpClient })
+		}
+	}
+
+	return func() error {
+		ctx, span := client.Tracer.Start(client.tracing.ctx, AttemptSpanName)
+		*size = 0
+
+		var traced *tracingClient
+		err := attempt(func(httpClient httpClient) httpClient {
+			if c := findContextClient(httpClient); c != nil {
+				c.ctx = ctx
+			}
+			traced = &tracingClient{httpClient: httpClient}
+			return traced
+		})
+
+		span.SetAttribute("stor.sha", sha.String())
+		span.SetAttribute("stor.attempt", *attempts)
+		span.SetAttribute("stor.bytes", *size)
+		if traced != nil && traced.statusCode != 0 {
+			span.SetAttribute("http.status_code", traced.statusCode)
+		}
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+
+		return err
+	}
+}
+
+// tracingClient is httpClient which keeps status code of last response
+type tracingClient struct {
+	httpClient
+	statusCode int
+}
+
+func (c *tracingClient) Get(url string) (*http.Response, error) {
+	resp, err := c.httpClient.Get(url)
+	if resp != nil {
+		c.statusCode = resp.StatusCode
+	}
+
+	return resp, err
+}
+
+func (c *tracingClient) unwrap() httpClient {
+	return c.httpClient
+}
@@ -0,0 +1,129 @@
+package storclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+type spanKey struct{}
+
+// recordingTracer keeps all started spans
+type recordingTracer struct {
+	lock  sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	lock       sync.Mutex
+	name       string
+	parent     *recordedSpan
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, storclient.Span) {
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attributes: make(map[string]interface{})}
+
+	t.lock.Lock()
+	t.spans = append(t.spans, span)
+	t.lock.Unlock()
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.attributes[key] = value
+}
+
+func (s *recordedSpan) RecordError(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.err = err
+}
+
+func (s *recordedSpan) End() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.ended = true
+}
+
+// spanTransport check that request is made in span of attempt
+type spanTransport struct {
+	t *testing.T
+}
+
+func (tr spanTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	span, ok := req.Context().Value(spanKey{}).(*recordedSpan)
+	if assert.True(tr.t, ok, "request is in span") {
+		assert.Equal(tr.t, storclient.AttemptSpanName, span.name)
+	}
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestTracer(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	tracer := &recordingTracer{}
+	client, err := storclient.New(*serverURL, "", storclient.StorClientOpts{
+		Devnull:      true,
+		RetryDelay:   time.Millisecond,
+		Tracer:       tracer,
+		RoundTripper: spanTransport{t: t},
+	})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(sha256Of("content")))
+	total := client.Wait()
+	assert.True(t, total.Status())
+
+	if !assert.Len(t, tracer.spans, 3) {
+		return
+	}
+
+	batch, first, second := tracer.spans[0], tracer.spans[1], tracer.spans[2]
+	assert.Equal(t, storclient.BatchSpanName, batch.name)
+	assert.True(t, batch.ended)
+	assert.Equal(t, 1, batch.attributes["stor.downloaded"])
+
+	for i, attempt := range []*recordedSpan{first, second} {
+		assert.Equal(t, storclient.AttemptSpanName, attempt.name)
+		assert.Equal(t, batch, attempt.parent)
+		assert.True(t, attempt.ended)
+		assert.Equal(t, i+1, attempt.attributes["stor.attempt"])
+		assert.Equal(t, sha256Of("content").String(), attempt.attributes["stor.sha"])
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, first.attributes["http.status_code"])
+	assert.Error(t, first.err)
+
+	assert.Equal(t, http.StatusOK, second.attributes["http.status_code"])
+	assert.Equal(t, int64(len("content")), second.attributes["stor.bytes"])
+	assert.NoError(t, second.err)
+}
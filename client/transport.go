@@ -0,0 +1,100 @@
+package storclient
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// TransportOpts is tuning of http transport of client (see StorClientOpts.Transport)
+type TransportOpts struct {
+	// max count of connections per host (dialing, active and idle)
+	// default (0) means without limit
+	MaxConnsPerHost int
+	// max count of idle (keep-alive) connections per host
+	// default (0) is StorClientOpts.Max, so each worker keeps its connection
+	MaxIdleConnsPerHost int
+	// max count of idle connections of all hosts
+	// default (0) is StorClientOpts.Max
+	MaxIdleConns int
+	// time after which idle connection is closed
+	// default (0) is StorClientOpts.Timeout
+	IdleConnTimeout time.Duration
+	// try HTTP/2 also with custom TLS config or proxy (HTTP/2 is otherwise used only with default TLS config)
+	ForceAttemptHTTP2 bool
+	// max time of TLS handshake
+	// default (0) means without limit
+	TLSHandshakeTimeout time.Duration
+	// max time of waiting for response headers after request is written
+	// default (0) means without limit
+	ResponseHeaderTimeout time.Duration
+	// new connection for each request
+	DisableKeepAlives bool
+}
+
+// apply set explicitly configured options to transport
+func (opts TransportOpts) apply(transport *http.Transport) {
+	if opts.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = opts.MaxConnsPerHost
+	}
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.MaxIdleConns > 0 {
+		transport.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	if opts.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = opts.TLSHandshakeTimeout
+	}
+	if opts.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = opts.ResponseHeaderTimeout
+	}
+	transport.ForceAttemptHTTP2 = opts.ForceAttemptHTTP2
+	transport.DisableKeepAlives = opts.DisableKeepAlives
+}
+
+// validateCustomTransport reject options of built-in transport if custom http client or round tripper is set
+func validateCustomTransport(opts StorClientOpts) error {
+	if opts.HTTPClient == nil && opts.RoundTripper == nil {
+		return nil
+	}
+
+	if opts.HTTPClient != nil && opts.RoundTripper != nil {
+		return errors.New("http client and round tripper can't be used together")
+	}
+
+	if opts.Proxy != nil || opts.ProxyFromEnvironment || opts.TLS != nil || opts.TLSCAFile != "" || opts.TLSCertFile != "" ||
+		opts.TLSKeyFile != "" || opts.TLSPolicy != nil || opts.Transport != (TransportOpts{}) || opts.WarmUpConnections > 0 {
+		return errors.New("custom http client or round tripper can't be used with proxy, TLS, transport or warm-up options")
+	}
+
+	return nil
+}
+
+// baseRoundTripper return transport under all wrappers of client - custom one (see StorClientOpts.HTTPClient
+// and StorClientOpts.RoundTripper) or built-in one
+func (client *StorClient) baseRoundTripper() http.RoundTripper {
+	switch {
+	case client.RoundTripper != nil:
+		return client.RoundTripper
+	case client.HTTPClient != nil && client.HTTPClient.Transport != nil:
+		return client.HTTPClient.Transport
+	case client.HTTPClient != nil:
+		return http.DefaultTransport
+	}
+
+	return client.transport
+}
+
+// closeIdleConnections close idle connections of base transport (if it supports it)
+func (client *StorClient) closeIdleConnections() {
+	if closer, ok := client.baseRoundTripper().(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+	if client.grpc != nil {
+		client.grpc.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,81 @@
+package storclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransportOpts(t *testing.T) {
+	client, err := New(url.URL{}, "", StorClientOpts{Max: 16})
+	assert.NoError(t, err)
+	assert.Equal(t, 16, client.transport.MaxIdleConnsPerHost, "each worker keeps its connection")
+	assert.Equal(t, 0, client.transport.MaxConnsPerHost)
+	assert.False(t, client.transport.ForceAttemptHTTP2)
+
+	client, err = New(url.URL{}, "", StorClientOpts{Max: 16, Transport: TransportOpts{
+		MaxConnsPerHost:       32,
+		MaxIdleConnsPerHost:   8,
+		MaxIdleConns:          64,
+		IdleConnTimeout:       time.Minute,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+		DisableKeepAlives:     true,
+	}})
+	assert.NoError(t, err)
+
+	transport := client.transport
+	assert.Equal(t, 32, transport.MaxConnsPerHost)
+	assert.Equal(t, 8, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 64, transport.MaxIdleConns)
+	assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.Equal(t, 5*time.Second, transport.TLSHandshakeTimeout)
+	assert.Equal(t, 10*time.Second, transport.ResponseHeaderTimeout)
+	assert.True(t, transport.DisableKeepAlives)
+}
+
+// countingTransport count requests of next transport
+type countingTransport struct {
+	next     http.RoundTripper
+	requests int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.requests, 1)
+	return t.next.RoundTrip(req)
+}
+
+func TestCustomHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	roundTripper := &countingTransport{next: http.DefaultTransport}
+	httpClient := &http.Client{Transport: &countingTransport{next: http.DefaultTransport}}
+
+	for _, opts := range []StorClientOpts{{RoundTripper: roundTripper}, {HTTPClient: httpClient}} {
+		client, err := New(*serverURL, "", opts)
+		assert.NoError(t, err)
+
+		content, err := client.DownloadBytes(emptyHash)
+		assert.NoError(t, err)
+		assert.Empty(t, content)
+	}
+
+	assert.Equal(t, int32(1), roundTripper.requests)
+	assert.Equal(t, int32(1), httpClient.Transport.(*countingTransport).requests)
+
+	_, err := New(*serverURL, "", StorClientOpts{RoundTripper: roundTripper, HTTPClient: httpClient})
+	assert.Error(t, err)
+	_, err = New(*serverURL, "", StorClientOpts{RoundTripper: roundTripper, TLSPolicy: &TLSPolicy{}})
+	assert.Error(t, err)
+	_, err = New(*serverURL, "", StorClientOpts{HTTPClient: httpClient, Transport: TransportOpts{ForceAttemptHTTP2: true}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package storclient
+
+import (
+	"net/url"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// unverifiedClient is httpClient of attempt on mirror trusted by
+// StorClientOpts.InsecureMirrors - downloaded content isn't verified
+type unverifiedClient struct {
+	httpClient
+}
+
+func (c *unverifiedClient) unwrap() httpClient {
+	return c.httpClient
+}
+
+// skipsVerification return true if httpClient is client of trusted mirror
+func skipsVerification(httpClient httpClient) bool {
+	for {
+		if _, ok := httpClient.(*unverifiedClient); ok {
+			return true
+		}
+
+		wrapped, ok := httpClient.(wrappedHTTPClient)
+		if !ok {
+			return false
+		}
+		httpClient = wrapped.unwrap()
+	}
+}
+
+// trustedMirrors is set of mirrors (scheme://host) without verification of content
+type trustedMirrors map[string]bool
+
+func newTrustedMirrors(mirrors []url.URL) trustedMirrors {
+	if len(mirrors) == 0 {
+		return nil
+	}
+
+	trusted := make(trustedMirrors, len(mirrors))
+	for _, mirror := range mirrors {
+		key := mirror.Scheme + "://" + mirror.Host
+		log.Warningf("INSECURE: hash verification of downloads from %s is skipped", key)
+		trusted[key] = true
+	}
+
+	return trusted
+}
+
+// skipVerification wrap httpClient of attempt on url of trusted mirror, so content isn't verified,
+// returns true if verification is skipped
+func (trusted trustedMirrors) skipVerification(httpClient httpClient, rawurl string) (httpClient, bool) {
+	if !trusted[mirrorKey(rawurl)] {
+		return httpClient, false
+	}
+
+	return &unverifiedClient{httpClient: httpClient}, true
+}
@@ -0,0 +1,57 @@
+package storclient_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsecureMirrors(t *testing.T) {
+	// mirror serves content which doesn't match sha
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("corrupted"))
+	}))
+	defer server.Close()
+	storUrl, _ := url.Parse(server.URL)
+
+	download := func(opts storclient.StorClientOpts) (storclient.DownloadResult, storclient.TotalStat) {
+		tmpDir, err := ioutil.TempDir("", "unverified")
+		assert.NoError(t, err)
+		defer os.RemoveAll(tmpDir)
+
+		opts.RetryAttempts = 1
+		client, err := storclient.New(*storUrl, tmpDir, opts)
+		assert.NoError(t, err)
+		results := client.Results()
+
+		client.Start()
+		assert.NoError(t, client.Download(sha256Of("first")))
+		// one result fits to buffer of results
+		total := client.Wait()
+
+		return <-results, total
+	}
+
+	result, total := download(storclient.StorClientOpts{})
+	assert.Equal(t, storclient.DOWN_FAIL, result.Status)
+	assert.False(t, result.Unverified)
+
+	result, total = download(storclient.StorClientOpts{InsecureMirrors: []url.URL{*storUrl}})
+	assert.Equal(t, storclient.DOWN_OK, result.Status)
+	assert.True(t, result.Unverified)
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, 1, total.Unverified)
+
+	otherUrl, _ := url.Parse("http://other.mirror")
+	result, _ = download(storclient.StorClientOpts{InsecureMirrors: []url.URL{*otherUrl}})
+	assert.Equal(t, storclient.DOWN_FAIL, result.Status, "only trusted mirror isn't verified")
+
+	_, err := storclient.New(*storUrl, "", storclient.StorClientOpts{InsecureMirrors: []url.URL{*storUrl}, VerifyAfterRename: storclient.VerifyFull})
+	assert.Error(t, err)
+}
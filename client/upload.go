@@ -0,0 +1,138 @@
+package storclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Upload add file to queue for upload to stor
+//
+// worker hashes the file, checks existence of object on stor (existing objects are skipped)
+// and PUTs it to <storage>/<sha> with same retry and stats as downloads
+func (client *StorClient) Upload(path string) error {
+	return client.UploadCtx(context.Background(), path)
+}
+
+// UploadCtx add file to queue for upload to stor (see Upload and DownloadCtx)
+func (client *StorClient) UploadCtx(ctx context.Context, path string) error {
+	if err := client.queue.push(ctx, downloadJob{uploadPath: path}); err != nil {
+		return err
+	}
+
+	client.events.emit(Event{Event: EventQueued, Path: path})
+
+	return nil
+}
+
+func (client *StorClient) uploadWorkerJob(id int, seq int64, path string, uploadedFilesStat chan<- DownStat) {
+	logger := log.WithFields(log.Fields{
+		"worker": id,
+		"path":   path,
+	})
+
+	sha, size, err := hashFile(path, client.HashAlgorithm)
+	if err != nil {
+		logger.Errorf("Hash of %s fail: %s", path, err)
+		client.sendStat(uploadedFilesStat, DownloadResult{seq: seq, worker: id, Sha: sha, Path: path, Status: DOWN_FAIL, Err: err})
+		return
+	}
+	logger = logger.WithField("sha256", sha.String())
+
+	url := client.createStorURL(sha)
+
+	exists, err := existsOnStor(client.newStdHTTPClient(), url)
+	if err != nil {
+		logger.Warningf("Existence check fail: %s", err)
+	} else if exists {
+		logger.Debug("Object exists on stor - skip upload")
+		client.sendStat(uploadedFilesStat, DownloadResult{seq: seq, worker: id, Sha: sha, Path: path, Status: DOWN_SKIP})
+		return
+	}
+
+	client.events.emit(Event{Event: EventStarted, Sha: sha.String(), Path: path})
+
+	startTime := client.Clock.Now()
+	err = client.RetryEngine.Do(
+		sha,
+		func() error {
+			attemptStartTime := client.Clock.Now()
+			err := uploadFile(client.ctx, client.newStdHTTPClient(), url, path, size, sha)
+			if err == nil {
+				client.mirrors.record(url, size, since(client.Clock, attemptStartTime), nil)
+			} else {
+				client.mirrors.record(url, 0, since(client.Clock, attemptStartTime), err)
+			}
+
+			return err
+		},
+		func(err error) bool {
+			logger.Debugf("Upload attempt fail: %s", err)
+
+			// client errors are not retryable
+			if e, ok := err.(uploadError); ok && e.statusCode >= 400 && e.statusCode < 500 {
+				return false
+			}
+
+			return true
+		},
+	)
+
+	if err != nil {
+		logger.Errorf("Error upload %s: %s", path, err)
+		client.sendStat(uploadedFilesStat, DownloadResult{seq: seq, worker: id, Sha: sha, Path: path, Status: DOWN_FAIL, Err: err})
+		return
+	}
+
+	logger.Debugf("Uploaded %s", path)
+	client.sendStat(uploadedFilesStat, DownloadResult{seq: seq, worker: id, Sha: sha, Path: path, Size: size, Duration: since(client.Clock, startTime), Status: DOWN_OK})
+}
+
+// hashFile return hash (of given algorithm) and size of file
+func hashFile(path string, algorithm HashAlgorithm) (hashutil.Hash, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return hashutil.Hash{}, 0, err
+	}
+	defer file.Close()
+
+	hasher := algorithm.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return hashutil.Hash{}, 0, errors.Wrapf(err, "Read of %s fail", path)
+	}
+
+	sha, err := hashutil.BytesToHash(algorithm.New(), hasher.Sum(nil))
+	return sha, size, err
+}
+
+func uploadFile(ctx context.Context, httpClient *http.Client, url, path string, size int64, sha hashutil.Hash) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return uploadError{sha: sha, statusCode: resp.StatusCode, status: resp.Status}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package storclient_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpload(t *testing.T) {
+	var lock sync.Mutex
+	stored := map[string]string{
+		"/" + sha256Of("existing").String(): "existing",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch r.Method {
+		case http.MethodHead:
+			if _, ok := stored[r.URL.Path]; !ok {
+				http.NotFound(w, r)
+			}
+		case http.MethodPut:
+			if strings.HasSuffix(r.URL.Path, sha256Of("forbidden").String()) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			stored[r.URL.Path] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir, cleanup := tempDir(t, "upload")
+	defer cleanup()
+
+	for _, content := range []string{"first", "existing", "forbidden"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, content), []byte(content), 0644))
+	}
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := storclient.New(*serverURL, "", storclient.StorClientOpts{Max: 2, RetryAttempts: 2})
+	assert.NoError(t, err)
+
+	client.Start()
+	for _, file := range []string{"first", "existing", "forbidden", "missing"} {
+		assert.NoError(t, client.Upload(filepath.Join(tmpDir, file)))
+	}
+	total := client.Wait()
+
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, int64(len("first")), total.Size)
+	assert.Equal(t, 1, total.Skip)
+	assert.Equal(t, 2, total.Failed())
+	assert.Equal(t, "first", stored["/"+sha256Of("first").String()])
+	assert.NotContains(t, stored, "/"+sha256Of("forbidden").String())
+}
@@ -0,0 +1,156 @@
+package storclient
+
+import (
+	"hash"
+	"io"
+	"io/ioutil"
+
+	"github.com/avast/hashutil-go"
+)
+
+// Verifier check content of one download (see StorClientOpts.Verifier)
+type Verifier interface {
+	// NewWriter return writer to which whole content is written,
+	// content written to previous writer is dropped (download is restarted)
+	NewWriter() io.Writer
+	// Verify check content written to last writer against expected hash,
+	// error means wrong content - partial file is removed and download is retried
+	Verify(expected hashutil.Hash) error
+}
+
+// VerifierFactory create verifier of one download
+type VerifierFactory func(expected hashutil.Hash) (Verifier, error)
+
+// HashVerifier is default verifier - hash of content (algorithm by size of expected hash) must be expected hash
+func HashVerifier(expected hashutil.Hash) (Verifier, error) {
+	hasher, err := newHasherOf(expected)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hashVerifier{hasher: hasher}, nil
+}
+
+type hashVerifier struct {
+	hasher hash.Hash
+}
+
+func (v *hashVerifier) NewWriter() io.Writer {
+	v.hasher.Reset()
+	return v.hasher
+}
+
+func (v *hashVerifier) Verify(expected hashutil.Hash) error {
+	algorithm, _ := hashAlgorithmBySize(v.hasher.Size())
+	downSha, err := hashutil.BytesToHash(algorithm.New(), v.hasher.Sum(nil))
+	if err != nil {
+		return err
+	}
+
+	if !downSha.Equal(expected) {
+		return shaMismatchError{expected: expected, downloaded: downSha}
+	}
+
+	return nil
+}
+
+// AllVerifiers combine verifiers - content must pass all of them (e.g. HashVerifier and HMAC check)
+func AllVerifiers(factories ...VerifierFactory) VerifierFactory {
+	return func(expected hashutil.Hash) (Verifier, error) {
+		verifiers := make(allVerifiers, 0, len(factories))
+		for _, factory := range factories {
+			verifier, err := factory(expected)
+			if err != nil {
+				return nil, err
+			}
+			verifiers = append(verifiers, verifier)
+		}
+
+		return verifiers, nil
+	}
+}
+
+type allVerifiers []Verifier
+
+func (verifiers allVerifiers) NewWriter() io.Writer {
+	writers := make([]io.Writer, len(verifiers))
+	for i, verifier := range verifiers {
+		writers[i] = verifier.NewWriter()
+	}
+
+	return io.MultiWriter(writers...)
+}
+
+func (verifiers allVerifiers) Verify(expected hashutil.Hash) error {
+	for _, verifier := range verifiers {
+		if err := verifier.Verify(expected); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verification is verifier of one download with its current writer,
+// nil verification means content isn't checked
+type verification struct {
+	verifier Verifier
+	w        io.Writer
+}
+
+func newVerification(factory VerifierFactory, expected hashutil.Hash) (*verification, error) {
+	if factory == nil {
+		factory = HashVerifier
+	}
+
+	verifier, err := factory(expected)
+	if err != nil {
+		return nil, err
+	}
+
+	return &verification{verifier: verifier, w: verifier.NewWriter()}, nil
+}
+
+// newVerificationOf return verification by verifier of client behind httpClient (HashVerifier by default),
+// nil for client of trusted mirror (see StorClientOpts.InsecureMirrors)
+func newVerificationOf(httpClient httpClient, expected hashutil.Hash) (*verification, error) {
+	if skipsVerification(httpClient) {
+		return nil, nil
+	}
+
+	var factory VerifierFactory
+	if c := findContextClient(httpClient); c != nil {
+		factory = c.verifier
+	}
+
+	return newVerification(factory, expected)
+}
+
+func (v *verification) writer() io.Writer {
+	if v == nil {
+		return ioutil.Discard
+	}
+
+	return v.w
+}
+
+// restart drop written content
+func (v *verification) restart() {
+	if v != nil {
+		v.w = v.verifier.NewWriter()
+	}
+}
+
+// verify check written content, failure of verifier is shaMismatchError (content is wrong)
+func (v *verification) verify(expected hashutil.Hash) error {
+	if v == nil {
+		return nil
+	}
+
+	err := v.verifier.Verify(expected)
+	if _, ok := err.(shaMismatchError); err != nil && !ok {
+		return shaMismatchError{expected: expected, reason: err.Error()}
+	}
+
+	return err
+}
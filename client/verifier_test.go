@@ -0,0 +1,92 @@
+package storclient
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// sizeVerifier check only size of content
+type sizeVerifier struct {
+	expected int64
+	written  int64
+}
+
+func (v *sizeVerifier) NewWriter() io.Writer {
+	v.written = 0
+	return v
+}
+
+func (v *sizeVerifier) Write(p []byte) (int, error) {
+	v.written += int64(len(p))
+	return len(p), nil
+}
+
+func (v *sizeVerifier) Verify(hashutil.Hash) error {
+	if v.written != v.expected {
+		return errors.New("wrong size")
+	}
+	return nil
+}
+
+func sizeVerifierOf(size int64) VerifierFactory {
+	return func(hashutil.Hash) (Verifier, error) {
+		return &sizeVerifier{expected: size}, nil
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	download := func(sha hashutil.Hash, verifier VerifierFactory) (TotalStat, string) {
+		tmpDir, err := ioutil.TempDir("", "verifier")
+		assert.NoError(t, err)
+
+		client, err := New(*serverURL, tmpDir, StorClientOpts{Max: 1, RetryAttempts: 2, RetryDelay: 1, Verifier: verifier})
+		assert.NoError(t, err)
+
+		client.Start()
+		assert.NoError(t, client.Download(sha))
+		return client.Wait(), tmpDir
+	}
+
+	total, dir := download(contentHash("other"), sizeVerifierOf(int64(len("content"))))
+	defer os.RemoveAll(dir)
+	assert.Equal(t, 1, total.Count, "size-only verifier replaces hash check")
+
+	total, dir = download(contentHash("content"), AllVerifiers(HashVerifier, sizeVerifierOf(3)))
+	defer os.RemoveAll(dir)
+	assert.Equal(t, 0, total.Count, "content must pass all verifiers")
+	temps, _ := filepath.Glob(filepath.Join(dir, "*.temp"))
+	assert.Empty(t, temps, "content which fails verifier is removed")
+
+	total, dir = download(contentHash("content"), AllVerifiers(HashVerifier, sizeVerifierOf(int64(len("content")))))
+	defer os.RemoveAll(dir)
+	assert.Equal(t, 1, total.Count)
+}
+
+func TestVerificationResume(t *testing.T) {
+	v, err := newVerification(nil, contentHash("content"))
+	assert.NoError(t, err)
+
+	_, _ = v.writer().Write([]byte("garbage"))
+	v.restart()
+	_, _ = v.writer().Write([]byte("content"))
+	assert.NoError(t, v.verify(contentHash("content")))
+
+	var nilVerification *verification
+	assert.NoError(t, nilVerification.verify(contentHash("content")))
+}
@@ -0,0 +1,99 @@
+package storclient
+
+import (
+	"io"
+	"os"
+
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+)
+
+// VerifyMode is check of final file after rename (see StorClientOpts.VerifyAfterRename)
+type VerifyMode int
+
+const (
+	// VerifyNone - final file isn't checked (default)
+	VerifyNone VerifyMode = iota
+	// VerifySize - size of final file is checked
+	VerifySize
+	// VerifyFull - final file is re-hashed (paranoid mode)
+	VerifyFull
+)
+
+// verifyFile check final file of download, wrong file is removed (and download is retried)
+func verifyFile(path string, size int64, expectedSha hashutil.Hash, mode VerifyMode) (err error) {
+	if mode == VerifyNone {
+		return nil
+	}
+
+	defer func() {
+		if err != nil {
+			if remErr := os.Remove(path); remErr != nil && !os.IsNotExist(remErr) {
+				err = errors.Wrapf(remErr, "Cleanup of wrong file %s fail", path)
+			}
+		}
+	}()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrapf(err, "Verify of %s fail", path)
+	}
+
+	if info.Size() != size {
+		return errors.Errorf("Verify of %s fail: size after rename is %d, downloaded %d", path, info.Size(), size)
+	}
+
+	if mode == VerifySize {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "Verify of %s fail", path)
+	}
+	defer file.Close()
+
+	hasher, err := newHasherOf(expectedSha)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(hasher, file); err != nil {
+		return errors.Wrapf(err, "Verify of %s fail", path)
+	}
+
+	sha, err := hashutil.BytesToHash(hasher, hasher.Sum(nil))
+	if err != nil {
+		return err
+	}
+
+	if !sha.Equal(expectedSha) {
+		return errors.Errorf("Verify of %s fail: sha after rename is %s", path, sha)
+	}
+
+	return nil
+}
+
+// verifyExisting report if existing file has content of expectedSha,
+// file with wrong content is removed (so it's downloaded again)
+func verifyExisting(path string, expectedSha hashutil.Hash) (ok bool, err error) {
+	algorithm, ok := hashAlgorithmBySize(len(expectedSha.ToBytes()))
+	if !ok {
+		return false, errors.Wrapf(ErrInvalidHash, "unsupported size of hash %s", expectedSha)
+	}
+
+	sha, _, err := hashFile(path, algorithm)
+	if err != nil {
+		return false, errors.Wrapf(err, "Verify of existing %s fail", path)
+	}
+
+	if sha.Equal(expectedSha) {
+		return true, nil
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return false, errors.Wrapf(err, "Cleanup of wrong existing file %s fail", path)
+	}
+
+	return false, nil
+}
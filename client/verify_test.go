@@ -0,0 +1,85 @@
+package storclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "verify")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, emptyHash.String())
+	write := func(content string) {
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+
+	write("")
+	assert.NoError(t, verifyFile(path, 0, emptyHash, VerifySize))
+	assert.NoError(t, verifyFile(path, 0, emptyHash, VerifyFull))
+
+	assert.Error(t, verifyFile(path, 10, emptyHash, VerifySize), "truncated file")
+	assert.False(t, fileExists(path), "wrong file is removed")
+
+	write("xxx")
+	assert.NoError(t, verifyFile(path, 3, emptyHash, VerifySize), "size is ok")
+	assert.Error(t, verifyFile(path, 3, emptyHash, VerifyFull), "content is wrong")
+	assert.False(t, fileExists(path), "wrong file is removed")
+
+	assert.NoError(t, verifyFile(path, 3, emptyHash, VerifyNone), "without check")
+}
+
+func TestVerifyExisting(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "verify")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	valid, truncated := contentHash("valid"), contentHash("truncated")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, valid.String()), []byte("valid"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, truncated.String()), []byte("trunc"), 0644))
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if path.Base(r.URL.Path) == truncated.String() {
+			_, _ = w.Write([]byte("truncated"))
+			return
+		}
+		_, _ = w.Write([]byte("valid"))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, tmpDir, StorClientOpts{Max: 1, RetryAttempts: 1, VerifyExisting: true})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.NoError(t, client.Download(valid))
+	assert.NoError(t, client.Download(truncated))
+	total := client.Wait()
+
+	assert.Equal(t, 1, total.Skip, "valid existing file is skipped")
+	assert.Equal(t, 1, total.Count, "truncated existing file is downloaded again")
+	assert.Equal(t, 1, requests)
+
+	content, err := ioutil.ReadFile(filepath.Join(tmpDir, truncated.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, "truncated", string(content))
+
+	ok, err := verifyExisting(filepath.Join(tmpDir, valid.String()), truncated)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, fileExists(filepath.Join(tmpDir, valid.String())), "wrong file is removed")
+
+	_, err = New(*serverURL, tmpDir, StorClientOpts{VerifyExisting: true, EncryptionKey: make([]byte, 16)})
+	assert.Error(t, err)
+}
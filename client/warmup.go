@@ -0,0 +1,68 @@
+package storclient
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// max size of drained body of warm-up response, connection with longer body isn't reused
+const warmUpDrainLimit = 64 * 1024
+
+// warmUp open WarmUpConnections connections (TCP and TLS handshakes) to stor (and S3) host,
+// so first downloads reuse idle connections
+//
+// all requests to host are in flight at the same time (bodies are held until all responses arrive),
+// so each request uses own connection; failures are only logged
+func (client *StorClient) warmUp() {
+	if client.WarmUpConnections <= 0 || client.Replay != nil {
+		return
+	}
+
+	hosts := append([]url.URL{client.storageUrl}, client.Mirrors...)
+	if client.S3URL != nil {
+		hosts = append(hosts, *client.S3URL)
+	}
+
+	for _, host := range hosts {
+		client.warmUpHost(host)
+	}
+}
+
+func (client *StorClient) warmUpHost(host url.URL) {
+	host.Path = "/"
+	host.RawQuery = ""
+
+	var wg sync.WaitGroup
+	responses := make(chan *http.Response, client.WarmUpConnections)
+	for i := 0; i < client.WarmUpConnections; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			httpClient := &contextClient{Client: client.newStdHTTPClient(), ctx: client.ctx}
+			httpClient.Timeout = client.Timeout
+			resp, err := httpClient.Get(host.String())
+			if err != nil {
+				log.WithField("host", host.Host).Debugf("Warm-up of connection fail: %s", err)
+				return
+			}
+			responses <- resp
+		}()
+	}
+	wg.Wait()
+	close(responses)
+
+	opened := 0
+	for resp := range responses {
+		_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, warmUpDrainLimit))
+		resp.Body.Close()
+		opened++
+	}
+
+	log.WithField("host", host.Host).Debugf("Warm-up opened %d connections", opened)
+}
@@ -0,0 +1,51 @@
+package storclient
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarmUp(t *testing.T) {
+	var lock sync.Mutex
+	connections := 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			return
+		}
+		_, _ = w.Write([]byte("warm"))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			lock.Lock()
+			connections++
+			lock.Unlock()
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	opened := func() int {
+		lock.Lock()
+		defer lock.Unlock()
+		return connections
+	}
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := New(*serverURL, os.TempDir(), StorClientOpts{Max: 2, Devnull: true, WarmUpConnections: 3})
+	assert.NoError(t, err)
+
+	client.Start()
+	assert.Equal(t, 3, opened(), "connections are open before first download")
+
+	assert.NoError(t, client.Download(contentHash("warm")))
+	total := client.Wait()
+	assert.Equal(t, 1, total.Count)
+	assert.Equal(t, 3, opened(), "download reuses warmed-up connection")
+}
@@ -0,0 +1,75 @@
+package storclient
+
+import "time"
+
+// WorkerStat is statistics of downloads processed by one worker
+type WorkerStat struct {
+	// count of downloaded files
+	Count int `json:"count"`
+	// count of skipped files
+	Skip int `json:"skip"`
+	// count of failed files
+	Failures int `json:"failures"`
+	// downloaded bytes
+	Size int64 `json:"size"`
+	// sum of durations of downloaded and failed files
+	Latency time.Duration `json:"latency"`
+	// count of retried attempts
+	Retries int `json:"retries"`
+}
+
+// MeanLatency return mean duration of downloaded or failed file
+func (stat WorkerStat) MeanLatency() time.Duration {
+	if stat.Count+stat.Failures == 0 {
+		return 0
+	}
+
+	return stat.Latency / time.Duration(stat.Count+stat.Failures)
+}
+
+func (stat WorkerStat) merge(other WorkerStat) WorkerStat {
+	return WorkerStat{
+		Count:    stat.Count + other.Count,
+		Skip:     stat.Skip + other.Skip,
+		Failures: stat.Failures + other.Failures,
+		Size:     stat.Size + other.Size,
+		Latency:  stat.Latency + other.Latency,
+		Retries:  stat.Retries + other.Retries,
+	}
+}
+
+// record add stat of one processed sha to worker
+func (stat WorkerStat) record(downStat DownStat) WorkerStat {
+	switch downStat.Status {
+	case DOWN_SKIP:
+		stat.Skip++
+		return stat
+	case DOWN_FAIL:
+		stat.Failures++
+	default:
+		stat.Count++
+		if downStat.Status != DOWN_METADATA {
+			stat.Size += downStat.Size
+		}
+	}
+	stat.Latency += downStat.Duration
+	stat.Retries += downStat.retries
+
+	return stat
+}
+
+func mergeWorkerStats(a, b map[int]WorkerStat) map[int]WorkerStat {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	merged := make(map[int]WorkerStat, len(a)+len(b))
+	for id, stat := range a {
+		merged[id] = merged[id].merge(stat)
+	}
+	for id, stat := range b {
+		merged[id] = merged[id].merge(stat)
+	}
+
+	return merged
+}
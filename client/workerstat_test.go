@@ -0,0 +1,34 @@
+package storclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerStat(t *testing.T) {
+	stat := WorkerStat{}.
+		record(DownStat{Status: DOWN_OK, Size: 10, Duration: time.Second, retries: 1}).
+		record(DownStat{Status: DOWN_FAIL, Duration: 3 * time.Second, retries: 2}).
+		record(DownStat{Status: DOWN_SKIP})
+
+	assert.Equal(t, WorkerStat{Count: 1, Skip: 1, Failures: 1, Size: 10, Latency: 4 * time.Second, Retries: 3}, stat)
+	assert.Equal(t, 2*time.Second, stat.MeanLatency())
+
+	merged := TotalStat{Workers: map[int]WorkerStat{0: stat}}.Merge(TotalStat{Workers: map[int]WorkerStat{0: stat, 1: stat}})
+	assert.Equal(t, 2, merged.Workers[0].Count)
+	assert.Equal(t, 1, merged.Workers[1].Count)
+}
+
+func TestWorkerStatRetries(t *testing.T) {
+	httpClient := func() httpClient { return &clientMock{statusCode: 500, status: "Something bad"} }
+	opts := StorClientOpts{RetryAttempts: 3, Clock: &fakeClock{}}
+	downloadWorkersTest(t, opts, httpClient, []hashutil.Hash{emptyHash}, 1, func(tempdir pathutil.Path, stat []DownStat) {
+		assert.Equal(t, DOWN_FAIL, stat[0].Status)
+		assert.Equal(t, 2, stat[0].retries)
+		assert.Equal(t, 0, stat[0].worker)
+	})
+}
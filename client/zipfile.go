@@ -0,0 +1,363 @@
+package storclient
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/JaSei/pathutil-go"
+	"github.com/avast/hashutil-go"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ZipEncryption is encryption of password-protected zip of downloaded file (see StorClientOpts.ZipPassword)
+type ZipEncryption int
+
+const (
+	// ZipCrypto - traditional PKWARE encryption, weak but supported by all unzip tools (default)
+	ZipCrypto ZipEncryption = iota
+	// ZipAES - WinZip AES-256 encryption (AE-1), supported by 7-Zip, WinZip and others
+	ZipAES
+)
+
+// DefaultZipPassword is password of samples by "infected" convention
+const DefaultZipPassword = "infected"
+
+const (
+	zipMethodAES      = 99
+	zipExtraAES       = 0x9901
+	zipFlagEncrypted  = 0x1
+	zipCryptoHeaderLn = 12
+	zipAESSaltLen     = 16
+	zipAESKeyLen      = 32
+	zipAESMacLen      = 10
+	zipAESIterations  = 1000
+)
+
+// zipName return name of zip of download (filename is path of downloaded file in download dir)
+func zipName(filename string) string {
+	return filename + ".zip"
+}
+
+// downloadFileZipped download to password-protected zip <sha>.zip.temp (see tempFiles) with one member
+// named by final file (without .zip), plaintext never touches disk and its hash is verified
+// before rename to filepath
+//
+// zip temp file can't be resumed - each attempt starts from scratch
+func downloadFileZipped(httpClient httpClient, filepath pathutil.Path, url string, expectedSha hashutil.Hash, password string, encryption ZipEncryption, attrs fileAttrs, staging tempFiles) (size int64, err error) {
+	tempfile, err := staging.path(filepath, expectedSha, ".zip")
+	if err != nil {
+		return 0, errors.Wrap(err, "Construct of temp file path fail")
+	}
+	temppath := tempfile.Canonpath()
+
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, StatusError{Sha: expectedSha, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	v, err := newVerificationOf(httpClient, expectedSha)
+	if err != nil {
+		return 0, err
+	}
+
+	lastModified, err := getLastModifiedTime(resp)
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := os.OpenFile(temppath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Create of tempfile %s fail", temppath)
+	}
+	defer func() {
+		if out != nil {
+			out.Close()
+		}
+		if err != nil {
+			os.Remove(temppath)
+		}
+	}()
+
+	archive := zip.NewWriter(out)
+	name := strings.TrimSuffix(path.Base(filepath.String()), ".zip")
+	member, err := createEncryptedMember(archive, name, lastModified, password, encryption)
+	if err != nil {
+		return 0, err
+	}
+
+	succ, err := copyAndVerify(resp, errWriter{w: member}, v, expectedSha)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = archive.Close(); err != nil {
+		return 0, writeError{err: err}
+	}
+
+	err = out.Close()
+	out = nil
+	if err != nil {
+		return 0, err
+	}
+
+	if err = attrs.apply(temppath); err != nil {
+		return 0, err
+	}
+
+	if err = os.Rename(temppath, filepath.Canonpath()); err != nil {
+		return 0, errors.Wrapf(err, "Rename temp %s to final path %s fail", temppath, filepath)
+	}
+
+	if err = os.Chtimes(filepath.Canonpath(), succ.lastModified, succ.lastModified); err != nil {
+		return 0, errors.Wrapf(err, "Chtimes(%s, %s) fail", filepath.Canonpath(), succ.lastModified.String())
+	}
+
+	return succ.size, nil
+}
+
+// createEncryptedMember add member deflated and encrypted by password to archive
+func createEncryptedMember(archive *zip.Writer, name string, modified time.Time, password string, encryption ZipEncryption) (io.Writer, error) {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Flags: zipFlagEncrypted}
+	if !modified.IsZero() {
+		header.SetModTime(modified)
+	}
+
+	switch encryption {
+	case ZipCrypto:
+		// writer of archive/zip always uses data descriptor, so header of encryption
+		// is checked by high byte of modification time instead of crc
+		check := byte(header.ModifiedTime >> 8)
+		archive.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+			enc, err := newZipCryptoWriter(w, []byte(password), check)
+			if err != nil {
+				return nil, err
+			}
+			return flate.NewWriter(enc, flate.DefaultCompression)
+		})
+	case ZipAES:
+		header.Method = zipMethodAES
+		// AE-1, AES-256, actual method deflate
+		header.Extra = []byte{byte(zipExtraAES & 0xff), byte(zipExtraAES >> 8), 7, 0, 1, 0, 'A', 'E', 3, byte(zip.Deflate), 0}
+		archive.RegisterCompressor(zipMethodAES, func(w io.Writer) (io.WriteCloser, error) {
+			return newZipAESWriter(w, []byte(password))
+		})
+	default:
+		return nil, errors.Errorf("unknown zip encryption %d", encryption)
+	}
+
+	return archive.CreateHeader(header)
+}
+
+// zipCryptoKeys is state of traditional PKWARE encryption
+type zipCryptoKeys [3]uint32
+
+func newZipCryptoKeys(password []byte) *zipCryptoKeys {
+	keys := &zipCryptoKeys{0x12345678, 0x23456789, 0x34567890}
+	for _, b := range password {
+		keys.update(b)
+	}
+	return keys
+}
+
+// crc32Byte is one step of crc32 (without pre and post inversion)
+func crc32Byte(crc uint32, b byte) uint32 {
+	return ^crc32.Update(^crc, crc32.IEEETable, []byte{b})
+}
+
+func (keys *zipCryptoKeys) update(b byte) {
+	keys[0] = crc32Byte(keys[0], b)
+	keys[1] = (keys[1]+keys[0]&0xff)*134775813 + 1
+	keys[2] = crc32Byte(keys[2], byte(keys[1]>>24))
+}
+
+func (keys *zipCryptoKeys) stream() byte {
+	temp := uint16(keys[2] | 2)
+	return byte((temp * (temp ^ 1)) >> 8)
+}
+
+func (keys *zipCryptoKeys) encrypt(b byte) byte {
+	encrypted := b ^ keys.stream()
+	keys.update(b)
+	return encrypted
+}
+
+// zipCryptoWriter encrypt content of zip member by traditional PKWARE encryption
+type zipCryptoWriter struct {
+	w    io.Writer
+	keys *zipCryptoKeys
+	buf  []byte
+	// header isn't written yet - compressor is created by archive/zip before local header of member
+	header []byte
+}
+
+// newZipCryptoWriter return writer of content, encryption header (random bytes and check byte)
+// is written before first content
+func newZipCryptoWriter(w io.Writer, password []byte, check byte) (*zipCryptoWriter, error) {
+	header := make([]byte, zipCryptoHeaderLn)
+	if _, err := rand.Read(header[:zipCryptoHeaderLn-1]); err != nil {
+		return nil, err
+	}
+	header[zipCryptoHeaderLn-1] = check
+
+	return &zipCryptoWriter{w: w, keys: newZipCryptoKeys(password), header: header}, nil
+}
+
+func (z *zipCryptoWriter) Write(p []byte) (int, error) {
+	if z.header != nil {
+		header := z.header
+		z.header = nil
+		if _, err := z.Write(header); err != nil {
+			return 0, err
+		}
+	}
+
+	if cap(z.buf) < len(p) {
+		z.buf = make([]byte, len(p))
+	}
+	buf := z.buf[:len(p)]
+	for i, b := range p {
+		buf[i] = z.keys.encrypt(b)
+	}
+
+	return z.w.Write(buf)
+}
+
+// zipAESKeys derive keys of WinZip AES encryption from password and salt
+func zipAESKeys(password, salt []byte) (encryption, authentication, verifier []byte) {
+	keys := pbkdf2.Key(password, salt, zipAESIterations, 2*zipAESKeyLen+2, sha1.New)
+	return keys[:zipAESKeyLen], keys[zipAESKeyLen : 2*zipAESKeyLen], keys[2*zipAESKeyLen:]
+}
+
+// zipAESCTR is AES in CTR mode with little endian counter starting at 1 (by WinZip AES)
+type zipAESCTR struct {
+	block   cipher.Block
+	counter [aes.BlockSize]byte
+	stream  [aes.BlockSize]byte
+	used    int
+}
+
+func newZipAESCTR(key []byte) (*zipAESCTR, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &zipAESCTR{block: block, used: aes.BlockSize}, nil
+}
+
+func (ctr *zipAESCTR) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if ctr.used == aes.BlockSize {
+			for j := range ctr.counter {
+				ctr.counter[j]++
+				if ctr.counter[j] != 0 {
+					break
+				}
+			}
+			ctr.block.Encrypt(ctr.stream[:], ctr.counter[:])
+			ctr.used = 0
+		}
+		dst[i] = src[i] ^ ctr.stream[ctr.used]
+		ctr.used++
+	}
+}
+
+// zipAESWriter deflate and encrypt content of zip member by WinZip AES,
+// authentication code is written on Close
+type zipAESWriter struct {
+	w       io.Writer
+	deflate *flate.Writer
+	ctr     *zipAESCTR
+	mac     hash.Hash
+	buf     []byte
+	// salt and password verifier aren't written yet (see zipCryptoWriter)
+	header []byte
+}
+
+// newZipAESWriter return writer of content, salt and password verifier are written before first content
+func newZipAESWriter(w io.Writer, password []byte) (*zipAESWriter, error) {
+	salt := make([]byte, zipAESSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	encryption, authentication, verifier := zipAESKeys(password, salt)
+	ctr, err := newZipAESCTR(encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	z := &zipAESWriter{w: w, ctr: ctr, mac: hmac.New(sha1.New, authentication), header: append(salt, verifier...)}
+	if z.deflate, err = flate.NewWriter(writerFunc(z.encrypt), flate.DefaultCompression); err != nil {
+		return nil, err
+	}
+
+	return z, nil
+}
+
+func (z *zipAESWriter) encrypt(p []byte) (int, error) {
+	if err := z.writeHeader(); err != nil {
+		return 0, err
+	}
+
+	if cap(z.buf) < len(p) {
+		z.buf = make([]byte, len(p))
+	}
+	buf := z.buf[:len(p)]
+	z.ctr.XORKeyStream(buf, p)
+	z.mac.Write(buf)
+
+	return z.w.Write(buf)
+}
+
+func (z *zipAESWriter) Write(p []byte) (int, error) {
+	return z.deflate.Write(p)
+}
+
+func (z *zipAESWriter) writeHeader() error {
+	if z.header == nil {
+		return nil
+	}
+
+	_, err := z.w.Write(z.header)
+	z.header = nil
+	return err
+}
+
+func (z *zipAESWriter) Close() error {
+	if err := z.deflate.Close(); err != nil {
+		return err
+	}
+	if err := z.writeHeader(); err != nil {
+		return err
+	}
+
+	_, err := z.w.Write(z.mac.Sum(nil)[:zipAESMacLen])
+	return err
+}
+
+// writerFunc is io.Writer of function
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
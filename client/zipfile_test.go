@@ -0,0 +1,162 @@
+package storclient
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZipPassword(t *testing.T) {
+	content := strings.Repeat("MZ sample ", 1000)
+	sha := contentHash(content)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	for _, encryption := range []ZipEncryption{ZipCrypto, ZipAES} {
+		tmpDir, err := ioutil.TempDir("", "zip")
+		assert.NoError(t, err)
+		defer os.RemoveAll(tmpDir)
+
+		client, err := New(*serverURL, tmpDir, StorClientOpts{RetryAttempts: 1, ZipPassword: DefaultZipPassword, ZipEncryption: encryption})
+		assert.NoError(t, err)
+
+		client.Start()
+		assert.NoError(t, client.Download(sha))
+		total := client.Wait()
+		assert.Equal(t, 1, total.Count)
+
+		raw, err := ioutil.ReadFile(filepath.Join(tmpDir, sha.String()+".zip"))
+		assert.NoError(t, err)
+		assert.NotContains(t, string(raw), "MZ sample", "plaintext isn't in zip")
+		assert.False(t, fileExists(filepath.Join(tmpDir, sha.String())))
+
+		name, unzipped, err := unzipEncrypted(raw, DefaultZipPassword)
+		assert.NoError(t, err, "encryption %d", encryption)
+		assert.Equal(t, sha.String(), name)
+		assert.Equal(t, content, unzipped)
+
+		_, _, err = unzipEncrypted(raw, "wrong")
+		assert.Error(t, err)
+	}
+
+	_, err := New(*serverURL, "", StorClientOpts{ZipPassword: DefaultZipPassword, EncryptionKey: make([]byte, 16)})
+	assert.Error(t, err)
+}
+
+// unzipEncrypted return name and content of only member of zip encrypted by password
+func unzipEncrypted(archive []byte, password string) (string, string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return "", "", err
+	}
+	if len(reader.File) != 1 {
+		return "", "", errors.Errorf("zip has %d members", len(reader.File))
+	}
+	member := reader.File[0]
+
+	var decryptErr error
+	reader.RegisterDecompressor(zip.Deflate, func(r io.Reader) io.ReadCloser {
+		keys := newZipCryptoKeys([]byte(password))
+		header := make([]byte, zipCryptoHeaderLn)
+		if _, decryptErr = io.ReadFull(r, header); decryptErr != nil {
+			return ioutil.NopCloser(r)
+		}
+		for i := range header {
+			header[i] = zipCryptoDecrypt(keys, header[i])
+		}
+		if header[zipCryptoHeaderLn-1] != byte(member.ModifiedTime>>8) {
+			decryptErr = errors.New("wrong password")
+		}
+		return flate.NewReader(readerFunc(func(p []byte) (int, error) {
+			n, err := r.Read(p)
+			for i := range p[:n] {
+				p[i] = zipCryptoDecrypt(keys, p[i])
+			}
+			return n, err
+		}))
+	})
+	reader.RegisterDecompressor(zipMethodAES, func(r io.Reader) io.ReadCloser {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			decryptErr = err
+			return ioutil.NopCloser(r)
+		}
+
+		salt, verifier := data[:zipAESSaltLen], data[zipAESSaltLen:zipAESSaltLen+2]
+		encrypted, code := data[zipAESSaltLen+2:len(data)-zipAESMacLen], data[len(data)-zipAESMacLen:]
+		encryption, authentication, expectedVerifier := zipAESKeys([]byte(password), salt)
+		mac := hmac.New(sha1.New, authentication)
+		mac.Write(encrypted)
+		if !bytes.Equal(verifier, expectedVerifier) || !hmac.Equal(code, mac.Sum(nil)[:zipAESMacLen]) {
+			decryptErr = errors.New("wrong password")
+		}
+
+		ctr, _ := newZipAESCTR(encryption)
+		ctr.XORKeyStream(encrypted, encrypted)
+		return flate.NewReader(bytes.NewReader(encrypted))
+	})
+
+	r, err := member.Open()
+	if err != nil {
+		return "", "", err
+	}
+	defer r.Close()
+
+	content, err := ioutil.ReadAll(r)
+	if decryptErr != nil {
+		return "", "", decryptErr
+	}
+	return member.Name, string(content), err
+}
+
+func zipCryptoDecrypt(keys *zipCryptoKeys, b byte) byte {
+	plain := b ^ keys.stream()
+	keys.update(plain)
+	return plain
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestZipAESCTR(t *testing.T) {
+	key := make([]byte, 32)
+	ctr, err := newZipAESCTR(key)
+	assert.NoError(t, err)
+
+	// first block is encrypted counter 1 (little endian)
+	stream := make([]byte, 2*aes.BlockSize)
+	ctr.XORKeyStream(stream[:5], stream[:5])
+	ctr.XORKeyStream(stream[5:], stream[5:])
+
+	block, _ := aes.NewCipher(key)
+	expected := make([]byte, 2*aes.BlockSize)
+	counter := make([]byte, aes.BlockSize)
+	counter[0] = 1
+	block.Encrypt(expected, counter)
+	counter[0] = 2
+	block.Encrypt(expected[aes.BlockSize:], counter)
+
+	assert.Equal(t, expected, stream)
+}
@@ -0,0 +1,176 @@
+/*
+storcli is small command line tool over storclient library
+
+hashes are read from arguments, from file (--file, `-` means STDIN) or from STDIN
+if there are no arguments nor file - one hash per line; unreadable input exits with
+ExitFatal and invalid hash with ExitPartialFailure (see storclient.ExitCode)
+
+	storcli --storage http://stor.domain.tld --dir /tmp/samples EE2BF0BFD365EBF829F8D07B197B7A15F39760CD14C6D3BFDFBAD2B145CB72B8
+	cat shas.txt | storcli --storage http://stor.domain.tld --dir /tmp/samples --max 16
+
+for all features (S3, systemd, authentication...) use stor-client (root of this repository)
+*/
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/avast/stor-client/client"
+	log "github.com/sirupsen/logrus"
+)
+
+var version = "master"
+
+// options is parsed command line
+type options struct {
+	storageUrl    *url.URL
+	dir           string
+	max           int
+	retryAttempts uint
+	retryDelay    time.Duration
+	devnull       bool
+	suffix        string
+	file          string
+	hashes        []string
+}
+
+func parseArgs(args []string) (options, error) {
+	opts := options{}
+
+	app := kingpin.New("storcli", "download files (objects) from stor by hash")
+	app.Version(version)
+	app.Flag("storage", "storage url").Short('u').Required().URLVar(&opts.storageUrl)
+	app.Flag("dir", "directory for downloaded files").Short('d').Default(".").StringVar(&opts.dir)
+	app.Flag("max", "max download process").Default(strconv.Itoa(storclient.DefaultMax)).IntVar(&opts.max)
+	app.Flag("attempts", "count of attempts of retry").Default(strconv.Itoa(storclient.DefaultRetryAttempts)).UintVar(&opts.retryAttempts)
+	app.Flag("delay", "exponential retry - start delay time").Default(storclient.DefaultRetryDelay.String()).DurationVar(&opts.retryDelay)
+	app.Flag("devnull", "download file to /dev/null").BoolVar(&opts.devnull)
+	app.Flag("suffix", "downloaded file suffix - like '.dat' => SHA.dat").StringVar(&opts.suffix)
+	app.Flag("file", "file with hashes (one per line), - means STDIN").Short('f').StringVar(&opts.file)
+	app.Arg("hash", "hashes to download (STDIN is read if there are no hashes nor --file)").StringsVar(&opts.hashes)
+
+	_, err := app.Parse(args)
+
+	return opts, err
+}
+
+// readHashes send trimmed non-empty lines of rd to hashes
+func readHashes(rd io.Reader, hashes chan<- string) error {
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			hashes <- line
+		}
+	}
+
+	return scanner.Err()
+}
+
+// hashSource return hashes of arguments, file or STDIN and channel with error of read of
+// file or STDIN (closed after hashes)
+func hashSource(opts options, stdin io.Reader) (<-chan string, <-chan error) {
+	hashes := make(chan string, 32)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(hashes)
+
+		for _, hash := range opts.hashes {
+			hashes <- hash
+		}
+
+		var rd io.Reader
+		switch {
+		case opts.file == "-":
+			rd = stdin
+		case opts.file != "":
+			file, err := os.Open(opts.file)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer file.Close()
+			rd = file
+		case len(opts.hashes) == 0:
+			rd = stdin
+		default:
+			return
+		}
+
+		if err := readHashes(rd, hashes); err != nil {
+			errs <- err
+		}
+	}()
+
+	return hashes, errs
+}
+
+// run download all hashes and return exit code - ExitFatal if input isn't readable,
+// ExitPartialFailure if some hash is invalid
+func run(opts options, stdin io.Reader) int {
+	client, err := storclient.New(*opts.storageUrl, opts.dir, storclient.StorClientOpts{
+		Max:           opts.max,
+		RetryAttempts: opts.retryAttempts,
+		RetryDelay:    opts.retryDelay,
+		Devnull:       opts.devnull,
+		Suffix:        opts.suffix,
+	})
+	if err != nil {
+		log.Error(err)
+		return storclient.ExitCode(storclient.TotalStat{}, err)
+	}
+
+	startTime := time.Now()
+	client.Start()
+
+	hashes, errs := hashSource(opts, stdin)
+
+	invalid := 0
+	for hashStr := range hashes {
+		hash, err := storclient.ParseHash(hashStr)
+		if err != nil {
+			log.Errorf("Invalid hash %q: %s", hashStr, err)
+			invalid++
+			continue
+		}
+
+		if err := client.Download(hash); err != nil {
+			log.Error(err)
+			invalid++
+		}
+	}
+	errRead := <-errs
+
+	total := client.Wait()
+	total.Print(startTime)
+
+	if errRead != nil {
+		log.Errorf("Read of hashes fail: %s", errRead)
+		return storclient.ExitFatal
+	}
+
+	code := storclient.ExitCode(total, nil)
+	if code == storclient.ExitOK && invalid > 0 {
+		return storclient.ExitPartialFailure
+	}
+
+	return code
+}
+
+func main() {
+	opts, err := parseArgs(os.Args[1:])
+	if err != nil {
+		log.Error(err)
+		os.Exit(storclient.ExitFatal)
+	}
+
+	os.Exit(run(opts, os.Stdin))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/avast/stor-client/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseArgs(t *testing.T) {
+	opts, err := parseArgs([]string{"-u", "http://stor", "--max", "8", "--devnull", "--suffix", ".dat", "a", "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "stor", opts.storageUrl.Host)
+	assert.Equal(t, 8, opts.max)
+	assert.True(t, opts.devnull)
+	assert.Equal(t, ".dat", opts.suffix)
+	assert.Equal(t, uint(storclient.DefaultRetryAttempts), opts.retryAttempts)
+	assert.Equal(t, []string{"a", "b"}, opts.hashes)
+
+	_, err = parseArgs([]string{"a"})
+	assert.Error(t, err, "storage is required")
+}
+
+func TestHashSource(t *testing.T) {
+	collect := func(opts options, stdin string) []string {
+		hashes, errs := hashSource(opts, strings.NewReader(stdin))
+		var got []string
+		for hash := range hashes {
+			got = append(got, hash)
+		}
+		assert.NoError(t, <-errs)
+		return got
+	}
+
+	assert.Equal(t, []string{"a", "b"}, collect(options{hashes: []string{"a", "b"}}, "c\n"), "stdin isn't read with arguments")
+	assert.Equal(t, []string{"c", "d"}, collect(options{}, "c\n\n d \n"), "stdin without arguments")
+	assert.Equal(t, []string{"a", "c"}, collect(options{hashes: []string{"a"}, file: "-"}, "c\n"), "arguments and stdin")
+
+	file, err := ioutil.TempFile("", "storcli")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("e\nf\n")
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	assert.Equal(t, []string{"e", "f"}, collect(options{file: file.Name()}, "c\n"), "file")
+
+	hashes, errs := hashSource(options{hashes: []string{"a"}, file: file.Name() + ".missing"}, strings.NewReader(""))
+	for range hashes {
+	}
+	assert.Error(t, <-errs, "unreadable file")
+}
+
+func TestRun(t *testing.T) {
+	content := "storcli"
+	sum := sha256.Sum256([]byte(content))
+	sha := hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "storcli")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	opts, err := parseArgs([]string{"-u", server.URL, "-d", dir, "--suffix", ".dat", sha})
+	assert.NoError(t, err)
+	assert.Equal(t, storclient.ExitOK, run(opts, strings.NewReader("")))
+
+	downloaded, err := ioutil.ReadFile(filepath.Join(dir, sha+".dat"))
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(downloaded))
+
+	opts, err = parseArgs([]string{"-u", server.URL, "-d", dir, "--suffix", ".dat", sha, "invalid"})
+	assert.NoError(t, err)
+	assert.Equal(t, storclient.ExitPartialFailure, run(opts, strings.NewReader("")), "invalid hash")
+
+	opts, err = parseArgs([]string{"-u", server.URL, "-d", dir, "--file", filepath.Join(dir, "missing"), sha})
+	assert.NoError(t, err)
+	assert.Equal(t, storclient.ExitFatal, run(opts, strings.NewReader("")), "unreadable file")
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/avast/stor-client/client"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -12,16 +16,20 @@ func TestReadShaFromReader(t *testing.T) {
 		"01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b",
 		"edeaaff3f1774ad2888673770c6d64097e391bc362d7d6fb34982ddf0efd18cb",
 		"15220D166C77DED74E948DA77BD628928E845A062BA9FE64A6EAA6B345EDA6FA",
+		"sha256:01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b",
+		"AbpHGcgLb+kRsJGnwFEktk7uzpZOCcBY74+YBdrKVGs=",
 	}
 	var x = `
 nosha
 01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b
 a/b/c/edeaaff3f1774ad2888673770c6d64097e391bc362d7d6fb34982ddf0efd18cb
 15220D166C77DED74E948DA77BD628928E845A062BA9FE64A6EAA6B345EDA6FA.dat
+sha256:01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b
+AbpHGcgLb+kRsJGnwFEktk7uzpZOCcBY74+YBdrKVGs=
 `
 	r := strings.NewReader(x)
 
-	shas := readShaFromReader(r)
+	shas := readShaFromReader(r, storclient.SHA256)
 
 	got := make([]string, 0)
 	for sha := range shas {
@@ -30,3 +38,79 @@ a/b/c/edeaaff3f1774ad2888673770c6d64097e391bc362d7d6fb34982ddf0efd18cb
 
 	assert.Equal(t, expected, got)
 }
+
+func TestPrintPresence(t *testing.T) {
+	exists, _ := storclient.ParseHash("01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b")
+	missing, _ := storclient.ParseHash("edeaaff3f1774ad2888673770c6d64097e391bc362d7d6fb34982ddf0efd18cb")
+
+	results := make(chan storclient.DownloadResult, 2)
+	results <- storclient.DownloadResult{Sha: exists, Exists: true}
+	results <- storclient.DownloadResult{Sha: missing}
+	close(results)
+
+	out := &strings.Builder{}
+	done := make(chan struct{})
+	printPresence(out, results, done)
+	<-done
+
+	assert.Equal(t, exists.String()+" exists\n"+missing.String()+" missing\n", out.String())
+}
+
+func TestReadEncryptionKey(t *testing.T) {
+	file, err := ioutil.TempFile("", "key")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString("000102030405060708090a0b0c0d0e0f\n")
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	key, err := readEncryptionKey(file.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}, key)
+
+	assert.NoError(t, ioutil.WriteFile(file.Name(), []byte("not hex"), 0600))
+	_, err = readEncryptionKey(file.Name())
+	assert.Error(t, err)
+}
+
+func TestParseFileAttrs(t *testing.T) {
+	mode, owner, err := parseFileAttrs("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0), mode)
+	assert.Nil(t, owner)
+
+	mode, owner, err = parseFileAttrs("0640", "1000:100")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), mode)
+	assert.Equal(t, &storclient.FileOwner{UID: 1000, GID: 100}, owner)
+
+	_, _, err = parseFileAttrs("rw-r--r--", "")
+	assert.Error(t, err)
+
+	_, _, err = parseFileAttrs("", "1000")
+	assert.Error(t, err)
+}
+
+func TestParseDeadline(t *testing.T) {
+	now := time.Date(2020, 1, 1, 22, 0, 0, 0, time.Local)
+
+	deadline, err := parseDeadline("", now)
+	assert.NoError(t, err)
+	assert.True(t, deadline.IsZero())
+
+	deadline, err = parseDeadline("23:30", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 1, 1, 23, 30, 0, 0, time.Local), deadline)
+
+	deadline, err = parseDeadline("06:00", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 1, 2, 6, 0, 0, 0, time.Local), deadline, "next morning")
+
+	deadline, err = parseDeadline("2020-01-02T06:00:00Z", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 1, 2, 6, 0, 0, 0, time.UTC), deadline)
+
+	_, err = parseDeadline("6 am", now)
+	assert.Error(t, err)
+}
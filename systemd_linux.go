@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// sdNotify send state to systemd (see sd_notify(3))
+//
+// it's noop if stor-client isn't running as systemd service (NOTIFY_SOCKET isn't set)
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// sdWatchdog ping systemd watchdog in half of WATCHDOG_USEC interval until stop is closed
+//
+// it's noop if watchdog isn't enabled for service
+func sdWatchdog(stop <-chan struct{}) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(usec) * time.Microsecond / 2)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := sdNotify("WATCHDOG=1"); err != nil {
+					log.Warningf("systemd watchdog ping fail: %s", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
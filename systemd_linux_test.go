@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// listenNotifySocket listen on NOTIFY_SOCKET in temp dir, returned dir must be removed by caller
+func listenNotifySocket(t *testing.T) (*net.UnixConn, string) {
+	dir, err := ioutil.TempDir("", "sdnotify")
+	assert.NoError(t, err)
+
+	socket := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	assert.NoError(t, err)
+
+	os.Setenv("NOTIFY_SOCKET", socket)
+
+	return conn, dir
+}
+
+func readNotify(t *testing.T, conn *net.UnixConn) string {
+	buf := make([]byte, 64)
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(buf)
+	assert.NoError(t, err)
+
+	return string(buf[:n])
+}
+
+func TestSdNotify(t *testing.T) {
+	os.Unsetenv("NOTIFY_SOCKET")
+	assert.NoError(t, sdNotify("READY=1"), "without NOTIFY_SOCKET is noop")
+
+	conn, dir := listenNotifySocket(t)
+	defer os.RemoveAll(dir)
+	defer conn.Close()
+	defer os.Unsetenv("NOTIFY_SOCKET")
+
+	assert.NoError(t, sdNotify("READY=1"))
+	assert.Equal(t, "READY=1", readNotify(t, conn))
+}
+
+func TestSdWatchdog(t *testing.T) {
+	conn, dir := listenNotifySocket(t)
+	defer os.RemoveAll(dir)
+	defer conn.Close()
+	defer os.Unsetenv("NOTIFY_SOCKET")
+
+	os.Setenv("WATCHDOG_USEC", "2000")
+	os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	defer os.Unsetenv("WATCHDOG_USEC")
+	defer os.Unsetenv("WATCHDOG_PID")
+
+	stop := make(chan struct{})
+	defer close(stop)
+	sdWatchdog(stop)
+
+	assert.Equal(t, "WATCHDOG=1", readNotify(t, conn))
+}
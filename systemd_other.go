@@ -0,0 +1,9 @@
+// +build !linux
+
+package main
+
+func sdNotify(state string) error {
+	return nil
+}
+
+func sdWatchdog(stop <-chan struct{}) {}